@@ -3,8 +3,15 @@ module github.com/TMateusz1/go-3rd-devs
 go 1.24
 
 require (
-	github.com/joho/godotenv v1.5.1 // indirect
-	github.com/openai/openai-go v0.1.0-beta.10 // indirect
+	github.com/joho/godotenv v1.5.1
+	github.com/openai/openai-go v0.1.0-beta.10
+	github.com/pkoukk/tiktoken-go v0.1.7
+	github.com/pkoukk/tiktoken-go-loader v0.0.2
+)
+
+require (
+	github.com/dlclark/regexp2 v1.10.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
 	github.com/tidwall/gjson v1.18.0 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect
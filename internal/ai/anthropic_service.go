@@ -0,0 +1,397 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"github.com/TMateusz1/go-3rd-devs/internal/ratelimit"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const anthropicEmptyToolInput = "{}"
+
+type anthropicService struct {
+	c       http.Client
+	config  *option.AnthropicConfig
+	limiter *ratelimit.Limiter
+}
+
+func NewAnthropicService(opts ...option.AnthropicOption) (Service, error) {
+	config, err := option.NewAnthropicConfig(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create anthropic service: %w", err)
+	}
+	limiter, err := newLimiter(ProviderAnthropic, config.BaseUrl, config.ApiKey, config.RateLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create anthropic service: %w", err)
+	}
+
+	return &anthropicService{
+		c: http.Client{
+			Timeout: 5 * time.Minute,
+		},
+		config:  config,
+		limiter: limiter,
+	}, nil
+}
+
+func (a *anthropicService) Chat(ctx context.Context, messages []Message, opts ...CallOption) (string, error) {
+	return a.ChatWithModel(ctx, messages, a.config.DefaultModel, opts...)
+}
+
+func (a *anthropicService) DefaultModels() []ServiceModel {
+	return []ServiceModel{{Provider: ProviderAnthropic, Model: a.config.DefaultModel}}
+}
+
+func (a *anthropicService) ChatWithModel(ctx context.Context, messages []Message, model string, opts ...CallOption) (string, error) {
+	return AnswerContent(a.Complete(ctx, Request{
+		Model:    model,
+		Messages: messages,
+		Params:   NewGenerationParams(opts...),
+	}))
+}
+
+func (a *anthropicService) Complete(ctx context.Context, req Request) (Response, error) {
+	body, err := a.newRequest(req)
+	if err != nil {
+		return Response{}, err
+	}
+	reservation, err := a.limiter.Acquire(ctx, reservedTokens(req, body.Model, body.MaxTokens))
+	if err != nil {
+		return Response{}, fmt.Errorf("waiting for rate limit: %w", err)
+	}
+	defer reservation.Release()
+
+	httpResp, err := a.send(ctx, body)
+	if err != nil {
+		return Response{}, err
+	}
+	defer httpResp.Body.Close()
+
+	var resp anthropicResponse
+	err = json.NewDecoder(httpResp.Body).Decode(&resp)
+	if err != nil {
+		return Response{}, fmt.Errorf("decode anthropic response: %w", err)
+	}
+
+	usage := resp.Usage.toUsage()
+	recordUsage(ctx, body.Model, usage)
+	reservation.Reconcile(int(usage.TotalTokens()))
+
+	message := AssistantMessage("")
+	text := strings.Builder{}
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			message.ToolCalls = append(message.ToolCalls, ToolCall{
+				ID:        block.ID,
+				Name:      block.Name,
+				Arguments: string(block.Input),
+			})
+		}
+	}
+	message.Content = text.String()
+
+	return Response{
+		Message:      message,
+		Model:        resp.Model,
+		FinishReason: mapAnthropicStopReason(resp.StopReason),
+		Usage:        usage,
+	}, nil
+}
+
+func (a *anthropicService) ChatStream(ctx context.Context, messages []Message, model string, opts ...CallOption) (<-chan StreamChunk, error) {
+	req := Request{
+		Model:    model,
+		Messages: messages,
+		Params:   NewGenerationParams(opts...),
+	}
+	body, err := a.newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStreaming(body.Model); err != nil {
+		return nil, err
+	}
+	body.Stream = true
+
+	reservation, err := a.limiter.Acquire(ctx, reservedTokens(req, body.Model, body.MaxTokens))
+	if err != nil {
+		return nil, fmt.Errorf("waiting for rate limit: %w", err)
+	}
+	httpResp, err := a.send(ctx, body)
+	if err != nil {
+		reservation.Release()
+		return nil, err
+	}
+
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(ch)
+		defer reservation.Release()
+		defer httpResp.Body.Close()
+
+		send := func(chunk StreamChunk) bool {
+			select {
+			case ch <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var usage anthropicUsage
+		var stopReason string
+		content := strings.Builder{}
+		defer func() {
+			recordUsage(ctx, body.Model, usage.toUsage())
+			// without message_start usage is unknown, so the whole reservation is kept
+			if usage != (anthropicUsage{}) {
+				reservation.Reconcile(int(usage.toUsage().TotalTokens()))
+			}
+		}()
+
+		scanner := bufio.NewScanner(httpResp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data:")
+			if !ok {
+				continue
+			}
+
+			var event anthropicStreamEvent
+			err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event)
+			if err != nil {
+				send(StreamChunk{Err: fmt.Errorf("decode anthropic stream event: %w", err)})
+				return
+			}
+
+			switch event.Type {
+			case "message_start":
+				usage = event.Message.Usage
+			case "message_delta":
+				usage.OutputTokens = event.Usage.OutputTokens
+				if event.Delta.StopReason != "" {
+					stopReason = event.Delta.StopReason
+				}
+			case "content_block_delta":
+				if event.Delta.Type != "text_delta" || event.Delta.Text == "" {
+					continue
+				}
+				content.WriteString(event.Delta.Text)
+				if !send(StreamChunk{Content: event.Delta.Text}) {
+					return
+				}
+			case "error":
+				send(StreamChunk{Err: fmt.Errorf("anthropic stream error %s: %s", event.Error.Type, event.Error.Message)})
+				return
+			case "message_stop":
+				if err := checkFinishReason(Response{
+					Message:      AssistantMessage(content.String()),
+					Model:        body.Model,
+					FinishReason: mapAnthropicStopReason(stopReason),
+				}); err != nil {
+					send(StreamChunk{Err: err})
+				}
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			send(StreamChunk{Err: fmt.Errorf("failed to stream message from AI: %w", err)})
+			return
+		}
+		// the connection was closed before message_stop, the answer may be cut anywhere
+		send(StreamChunk{Err: fmt.Errorf("anthropic stream ended without message_stop: %w: %w", ErrProviderUnavailable, io.ErrUnexpectedEOF)})
+	}()
+
+	return ch, nil
+}
+
+func (a *anthropicService) send(ctx context.Context, body anthropicRequest) (*http.Response, error) {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling anthropic request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/v1/messages", strings.TrimSuffix(a.config.BaseUrl, "/")), bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("x-api-key", a.config.ApiKey)
+	req.Header.Set("anthropic-version", a.config.Version)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send message to AI: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, newAnthropicError(resp)
+	}
+	return resp, nil
+}
+
+func newAnthropicError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var errResp anthropicErrorResponse
+	if json.Unmarshal(body, &errResp) == nil && errResp.Error.Message != "" {
+		statusErr := newStatusError(resp, fmt.Errorf("anthropic response code %d %s: %s", resp.StatusCode, errResp.Error.Type, errResp.Error.Message))
+		statusErr.Code = errResp.Error.Type
+		return statusErr
+	}
+	return newStatusError(resp, fmt.Errorf("anthropic response code %d: %s", resp.StatusCode, strings.TrimSpace(string(body))))
+}
+
+func (a *anthropicService) newRequest(req Request) (anthropicRequest, error) {
+	model := req.Model
+	if model == "" {
+		model = a.config.DefaultModel
+	}
+	if err := checkCapabilities(model, req); err != nil {
+		return anthropicRequest{}, err
+	}
+
+	system, messages, err := mapMessagesToAnthropicMessages(req.Messages)
+	if err != nil {
+		return anthropicRequest{}, err
+	}
+
+	// Messages API doesn't support seed, it's skipped
+	body := anthropicRequest{
+		Model:         model,
+		MaxTokens:     a.config.MaxTokens,
+		System:        system,
+		Messages:      messages,
+		Temperature:   req.Params.Temperature,
+		TopP:          req.Params.TopP,
+		StopSequences: req.Params.Stop,
+	}
+	if req.Params.MaxTokens != nil {
+		body.MaxTokens = *req.Params.MaxTokens
+	}
+	for _, tool := range req.Tools {
+		schema := tool.Parameters
+		if schema == nil {
+			schema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		body.Tools = append(body.Tools, anthropicTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: schema,
+		})
+	}
+	return body, nil
+}
+
+// mapMessagesToAnthropicMessages moves system messages to the top-level system field and merges
+// consecutive messages of one role, because Messages API requires user and assistant turns to alternate.
+func mapMessagesToAnthropicMessages(messages []Message) (string, []anthropicMessage, error) {
+	var system []string
+	var result []anthropicMessage
+
+	for _, message := range messages {
+		var role string
+		var blocks []anthropicContentBlock
+
+		switch message.Role {
+		case System:
+			system = append(system, message.Content)
+			continue
+		case User:
+			role = "user"
+			if len(message.Parts) == 0 {
+				blocks = append(blocks, anthropicContentBlock{Type: "text", Text: message.Content})
+				break
+			}
+			partBlocks, err := mapPartsToAnthropicBlocks(message.contentParts())
+			if err != nil {
+				return "", nil, err
+			}
+			blocks = append(blocks, partBlocks...)
+		case Assistant:
+			role = "assistant"
+			if message.Content != "" {
+				blocks = append(blocks, anthropicContentBlock{Type: "text", Text: message.Content})
+			}
+			for _, toolCall := range message.ToolCalls {
+				input := toolCall.Arguments
+				if strings.TrimSpace(input) == "" {
+					input = anthropicEmptyToolInput
+				}
+				blocks = append(blocks, anthropicContentBlock{
+					Type:  "tool_use",
+					ID:    toolCall.ID,
+					Name:  toolCall.Name,
+					Input: json.RawMessage(input),
+				})
+			}
+		case Tool:
+			if message.ToolCallID == "" {
+				return "", nil, fmt.Errorf("tool message without tool call id")
+			}
+			role = "user"
+			blocks = append(blocks, anthropicContentBlock{
+				Type:      "tool_result",
+				ToolUseID: message.ToolCallID,
+				Content:   message.Content,
+			})
+		default:
+			return "", nil, fmt.Errorf("unknown role: %s", message.Role)
+		}
+
+		if len(result) > 0 && result[len(result)-1].Role == role {
+			result[len(result)-1].Content = append(result[len(result)-1].Content, blocks...)
+			continue
+		}
+		result = append(result, anthropicMessage{Role: role, Content: blocks})
+	}
+
+	return strings.Join(system, "\n\n"), result, nil
+}
+
+func mapPartsToAnthropicBlocks(parts []ContentPart) ([]anthropicContentBlock, error) {
+	var blocks []anthropicContentBlock
+	for _, part := range parts {
+		switch part.Type {
+		case ContentPartText:
+			blocks = append(blocks, anthropicContentBlock{Type: "text", Text: part.Text})
+		case ContentPartImageURL:
+			blocks = append(blocks, anthropicContentBlock{
+				Type:   "image",
+				Source: &anthropicImageSource{Type: "url", URL: part.ImageURL},
+			})
+		case ContentPartImageBase64:
+			blocks = append(blocks, anthropicContentBlock{
+				Type:   "image",
+				Source: &anthropicImageSource{Type: "base64", MediaType: part.MediaType, Data: part.Data},
+			})
+		default:
+			return nil, fmt.Errorf("unknown content part type: %s", part.Type)
+		}
+	}
+	return blocks, nil
+}
+
+func mapAnthropicStopReason(stopReason string) FinishReason {
+	switch stopReason {
+	case "max_tokens":
+		return FinishReasonLength
+	case "tool_use":
+		return FinishReasonToolCalls
+	case "refusal":
+		return FinishReasonContentFilter
+	default:
+		return FinishReasonStop
+	}
+}
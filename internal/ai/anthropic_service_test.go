@@ -0,0 +1,260 @@
+package ai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// anthropicServer stands in for Messages API, it records request bodies and answers with the given status and body.
+func anthropicServer(t *testing.T, status int, body string, requests *[]map[string]any) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %s, want /v1/messages", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "key" || r.Header.Get("anthropic-version") == "" {
+			t.Errorf("missing auth headers: %v", r.Header)
+		}
+		var request map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if requests != nil {
+			*requests = append(*requests, request)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnthropicComplete(t *testing.T) {
+	var requests []map[string]any
+	server := anthropicServer(t, http.StatusOK, `{
+		"model": "claude-3-5-haiku-latest",
+		"content": [
+			{"type": "text", "text": "Let me check."},
+			{"type": "tool_use", "id": "call_1", "name": "weather", "input": {"city": "Kraków"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 10, "output_tokens": 5, "cache_read_input_tokens": 4}
+	}`, &requests)
+
+	as, err := ai.NewAnthropicService(
+		option.WithAnthropicApiKey("key"),
+		option.WithAnthropicBaseUrl(server.URL),
+		option.WithAnthropicBaseModel("claude-3-5-haiku-latest"),
+	)
+	if err != nil {
+		t.Fatalf("creating service: %v", err)
+	}
+
+	resp, err := as.Complete(context.Background(), ai.Request{
+		Messages: []ai.Message{
+			ai.SystemMessage("be brief"),
+			ai.UserMessage("weather?"),
+			ai.UserMessage("in Kraków"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(requests))
+	}
+	if requests[0]["system"] != "be brief" {
+		t.Errorf("system = %v, want top-level system prompt", requests[0]["system"])
+	}
+	if requests[0]["model"] != "claude-3-5-haiku-latest" {
+		t.Errorf("model = %v, want default model", requests[0]["model"])
+	}
+	if messages := requests[0]["messages"].([]any); len(messages) != 1 {
+		t.Errorf("messages = %d, want consecutive user messages merged into 1", len(messages))
+	}
+
+	if resp.Message.Content != "Let me check." {
+		t.Errorf("content = %q", resp.Message.Content)
+	}
+	if len(resp.Message.ToolCalls) != 1 || resp.Message.ToolCalls[0].Name != "weather" || resp.Message.ToolCalls[0].Arguments != `{"city": "Kraków"}` {
+		t.Errorf("tool calls = %+v", resp.Message.ToolCalls)
+	}
+	if resp.Usage.PromptTokens != 14 || resp.Usage.CachedTokens != 4 || resp.Usage.CompletionTokens != 5 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestAnthropicErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{name: "rate limited", status: http.StatusTooManyRequests, body: `{"error": {"type": "rate_limit_error", "message": "slow down"}}`, want: ai.ErrRateLimited},
+		{name: "auth", status: http.StatusUnauthorized, body: `{"error": {"type": "authentication_error", "message": "bad key"}}`, want: ai.ErrAuth},
+		{name: "overloaded", status: 529, body: `{"error": {"type": "overloaded_error", "message": "overloaded"}}`, want: ai.ErrProviderUnavailable},
+		{name: "truncated", status: http.StatusOK, body: `{"content": [{"type": "text", "text": "Once"}], "stop_reason": "max_tokens"}`, want: ai.ErrTruncated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := anthropicServer(t, tt.status, tt.body, nil)
+			as, err := ai.NewAnthropicService(option.WithAnthropicApiKey("key"), option.WithAnthropicBaseUrl(server.URL))
+			if err != nil {
+				t.Fatalf("creating service: %v", err)
+			}
+
+			_, err = as.Chat(context.Background(), []ai.Message{ai.UserMessage("hi")})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			var statusErr *ai.StatusError
+			if tt.status == http.StatusTooManyRequests && (!errors.As(err, &statusErr) || statusErr.RetryAfter.Seconds() != 7) {
+				t.Errorf("err = %v, want retry after 7s", err)
+			}
+		})
+	}
+}
+
+// anthropicEvents formats events of Messages API stream.
+func anthropicEvents(events ...string) string {
+	var builder strings.Builder
+	for _, event := range events {
+		builder.WriteString("event: message\ndata: " + event + "\n\n")
+	}
+	return builder.String()
+}
+
+func TestAnthropicStream(t *testing.T) {
+	start := `{"type": "message_start", "message": {"usage": {"input_tokens": 5}}}`
+	delta := func(text string) string {
+		return `{"type": "content_block_delta", "delta": {"type": "text_delta", "text": "` + text + `"}}`
+	}
+	stop := func(reason string) string {
+		return `{"type": "message_delta", "delta": {"stop_reason": "` + reason + `"}, "usage": {"output_tokens": 2}}`
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantDeltas string
+		wantErr    string
+		wantIs     []error
+	}{
+		{
+			name:       "complete",
+			body:       anthropicEvents(start, delta("Hello "), delta("there"), stop("end_turn"), `{"type": "message_stop"}`),
+			wantDeltas: "Hello there",
+		},
+		{
+			name:       "truncated connection",
+			body:       anthropicEvents(start, delta("Hello ")),
+			wantDeltas: "Hello ",
+			wantErr:    "ended without message_stop",
+			wantIs:     []error{ai.ErrProviderUnavailable, io.ErrUnexpectedEOF},
+		},
+		{
+			name:       "max tokens",
+			body:       anthropicEvents(start, delta("Once"), stop("max_tokens"), `{"type": "message_stop"}`),
+			wantDeltas: "Once",
+			wantErr:    "truncated",
+			wantIs:     []error{ai.ErrTruncated},
+		},
+		{
+			name:    "error event",
+			body:    anthropicEvents(start, `{"type": "error", "error": {"type": "overloaded_error", "message": "overloaded"}}`),
+			wantErr: "overloaded_error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := anthropicServer(t, http.StatusOK, tt.body, nil)
+			as, err := ai.NewAnthropicService(option.WithAnthropicApiKey("key"), option.WithAnthropicBaseUrl(server.URL))
+			if err != nil {
+				t.Fatalf("creating service: %v", err)
+			}
+
+			stream, err := as.ChatStream(context.Background(), []ai.Message{ai.UserMessage("hi")}, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			deltas := strings.Builder{}
+			var streamErr error
+			for chunk := range stream {
+				if chunk.Err != nil {
+					streamErr = chunk.Err
+					continue
+				}
+				deltas.WriteString(chunk.Content)
+			}
+
+			if deltas.String() != tt.wantDeltas {
+				t.Errorf("deltas = %q, want %q", deltas.String(), tt.wantDeltas)
+			}
+			if tt.wantErr == "" {
+				if streamErr != nil {
+					t.Fatalf("err = %v, want none", streamErr)
+				}
+				return
+			}
+			if streamErr == nil || !strings.Contains(streamErr.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", streamErr, tt.wantErr)
+			}
+			for _, want := range tt.wantIs {
+				if !errors.Is(streamErr, want) {
+					t.Errorf("err = %v, want %v", streamErr, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewServiceFromEnvForwardsOptionsToAnthropic(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseModel string
+		wantModel string
+	}{
+		{name: "anthropic model", baseModel: "claude-3-5-haiku-latest", wantModel: "claude-3-5-haiku-latest"},
+		{name: "model of another provider", baseModel: ai.OpenRouterModelGPT4oMini, wantModel: "claude-sonnet-4-20250514"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []map[string]any
+			server := anthropicServer(t, http.StatusOK, `{"content": [{"type": "text", "text": "hi"}], "stop_reason": "end_turn"}`, &requests)
+			t.Setenv("AI_PROVIDER", ai.ProviderAnthropic)
+			t.Setenv("ANTHROPIC_API_KEY", "key")
+			t.Setenv("ANTHROPIC_BASE_URL", server.URL)
+			t.Setenv("ANTHROPIC_MODEL", "claude-sonnet-4-20250514")
+
+			as, err := ai.NewServiceFromEnv(option.WithBaseModel(tt.baseModel), option.WithDefaultMaxTokens(300))
+			if err != nil {
+				t.Fatalf("creating service: %v", err)
+			}
+			if _, err := as.Chat(context.Background(), []ai.Message{ai.UserMessage("hi")}); err != nil {
+				t.Fatalf("chat: %v", err)
+			}
+
+			if requests[0]["model"] != tt.wantModel {
+				t.Errorf("model = %v, want %s", requests[0]["model"], tt.wantModel)
+			}
+			if requests[0]["max_tokens"] != float64(300) {
+				t.Errorf("max_tokens = %v, want forwarded 300", requests[0]["max_tokens"])
+			}
+		})
+	}
+}
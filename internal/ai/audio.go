@@ -0,0 +1,48 @@
+package ai
+
+import (
+	"context"
+	"io"
+)
+
+// TranscriptionService turns speech into text (Whisper compatible /audio/transcriptions).
+type TranscriptionService interface {
+	Transcribe(ctx context.Context, req TranscriptionRequest) (Transcription, error)
+}
+
+// SpeechService synthesizes speech from text (/audio/speech).
+type SpeechService interface {
+	Speak(ctx context.Context, req SpeechRequest) (Speech, error)
+}
+
+type AudioService interface {
+	TranscriptionService
+	SpeechService
+}
+
+type TranscriptionRequest struct {
+	Audio io.Reader
+	// Filename extension tells the provider audio format, e.g. question.webm.
+	Filename string
+	// Language (ISO-639-1) and Prompt are optional hints improving accuracy.
+	Language string
+	Prompt   string
+}
+
+type Transcription struct {
+	Text  string
+	Model string
+}
+
+// SpeechRequest with empty Voice or Format uses the configured defaults.
+type SpeechRequest struct {
+	Input  string
+	Voice  string
+	Format string
+}
+
+type Speech struct {
+	Audio       []byte
+	ContentType string
+	Model       string
+}
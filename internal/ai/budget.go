@@ -0,0 +1,158 @@
+package ai
+
+import (
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/models"
+	"slices"
+)
+
+const (
+	// defaultContextWindow is assumed for models missing in the registry.
+	defaultContextWindow = 8_192
+	truncatedMarker      = "\n[truncated]"
+)
+
+// Budget fits outgoing messages and documents into the context window of a model, leaving room for the answer.
+type Budget struct {
+	ContextWindow  int
+	ReservedOutput int
+	tokenizer      *Tokenizer
+}
+
+func NewBudget(model string, reservedOutput int) (*Budget, error) {
+	tokenizer, err := TokenizerForModel(model)
+	if err != nil {
+		return nil, fmt.Errorf("creating budget: %w", err)
+	}
+	contextWindow := defaultContextWindow
+	info, ok := models.Lookup(model)
+	if ok && info.ContextWindow > 0 {
+		contextWindow = int(info.ContextWindow)
+	}
+	if reservedOutput < 0 || reservedOutput >= contextWindow {
+		return nil, fmt.Errorf("creating budget: reserved output %d doesn't fit context window %d of %s", reservedOutput, contextWindow, model)
+	}
+
+	return &Budget{
+		ContextWindow:  contextWindow,
+		ReservedOutput: reservedOutput,
+		tokenizer:      tokenizer,
+	}, nil
+}
+
+// NewBudgetForService fits the smallest context window of default models of the service, e.g. of the fallback chain.
+// Services which don't tell their models get the budget of the default context window.
+func NewBudgetForService(as Service, reservedOutput int) (*Budget, error) {
+	defaults := DefaultModels(as)
+	if len(defaults) == 0 {
+		return NewBudget("", reservedOutput)
+	}
+	var smallest *Budget
+	for _, model := range defaults {
+		budget, err := NewBudget(model.Model, reservedOutput)
+		if err != nil {
+			return nil, err
+		}
+		if smallest == nil || budget.ContextWindow < smallest.ContextWindow {
+			smallest = budget
+		}
+	}
+	return smallest, nil
+}
+
+func (b *Budget) Tokenizer() *Tokenizer {
+	return b.tokenizer
+}
+
+// Available is the number of prompt tokens.
+func (b *Budget) Available() int {
+	return b.ContextWindow - b.ReservedOutput
+}
+
+// FitMessages drops the oldest messages which are neither system nor the last one,
+// when it's not enough the longest remaining messages are truncated. Messages which can't fit fail with ErrContextLengthExceeded.
+func (b *Budget) FitMessages(messages []Message) ([]Message, error) {
+	result := slices.Clone(messages)
+	for b.tokenizer.CountMessages(result) > b.Available() {
+		dropped := false
+		for i := 0; i < len(result)-1; i++ {
+			if result[i].Role != System {
+				result = slices.Delete(result, i, i+1)
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			break
+		}
+	}
+
+	over := b.tokenizer.CountMessages(result) - b.Available()
+	for over > 0 {
+		longest := -1
+		longestTokens := 0
+		for i, message := range result {
+			if tokens := b.tokenizer.Count(message.Content); tokens > longestTokens {
+				longest, longestTokens = i, tokens
+			}
+		}
+		if longest < 0 || longestTokens <= over {
+			return nil, fmt.Errorf("messages don't fit into %d tokens: %w", b.Available(), ErrContextLengthExceeded)
+		}
+		result[longest].Content = b.truncate(result[longest].Content, longestTokens-over)
+		previous := over
+		over = b.tokenizer.CountMessages(result) - b.Available()
+		if over >= previous {
+			return nil, fmt.Errorf("messages don't fit into %d tokens: %w", b.Available(), ErrContextLengthExceeded)
+		}
+	}
+	return result, nil
+}
+
+// FitDocuments truncates documents to share tokens left after the fixed messages (e.g. the prompt without documents).
+// Short documents stay whole and the rest of the budget is split evenly among the longer ones.
+func (b *Budget) FitDocuments(fixed []Message, documents []string) []string {
+	available := b.Available() - b.tokenizer.CountMessages(fixed)
+	result := slices.Clone(documents)
+	if len(documents) == 0 {
+		return result
+	}
+
+	counts := make([]int, len(documents))
+	total := 0
+	for i, document := range documents {
+		counts[i] = b.tokenizer.Count(document)
+		total += counts[i]
+	}
+	if total <= available {
+		return result
+	}
+
+	order := make([]int, len(documents))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(i, j int) int {
+		return counts[i] - counts[j]
+	})
+
+	remaining := max(available, 0)
+	for n, i := range order {
+		share := remaining / (len(order) - n)
+		if counts[i] <= share {
+			remaining -= counts[i]
+			continue
+		}
+		result[i] = b.truncate(documents[i], share)
+		remaining -= share
+	}
+	return result
+}
+
+func (b *Budget) truncate(text string, maxTokens int) string {
+	markerTokens := b.tokenizer.Count(truncatedMarker)
+	if maxTokens <= markerTokens {
+		return b.tokenizer.Truncate(text, maxTokens)
+	}
+	return b.tokenizer.Truncate(text, maxTokens-markerTokens) + truncatedMarker
+}
@@ -0,0 +1,109 @@
+package ai_test
+
+import (
+	"errors"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"strings"
+	"testing"
+)
+
+func TestNewBudgetForService(t *testing.T) {
+	tests := []struct {
+		name           string
+		models         []ai.ServiceModel
+		reservedOutput int
+		wantWindow     int
+		wantEncoding   ai.Encoding
+		wantErr        string
+	}{
+		{
+			name:         "configured model",
+			models:       []ai.ServiceModel{{Provider: ai.ProviderAnthropic, Model: "claude-sonnet-4-20250514"}},
+			wantWindow:   200_000,
+			wantEncoding: ai.EncodingCL100K,
+		},
+		{
+			name: "smallest window of fallback chain",
+			models: []ai.ServiceModel{
+				{Provider: ai.ProviderAnthropic, Model: "claude-sonnet-4-20250514"},
+				{Provider: ai.ProviderOpenai, Model: "gpt-3.5-turbo"},
+				{Provider: ai.ProviderOpenai, Model: "gpt-4o-mini"},
+			},
+			wantWindow:   16_385,
+			wantEncoding: ai.EncodingCL100K,
+		},
+		{
+			name:         "tokenizer of the model",
+			models:       []ai.ServiceModel{{Provider: ai.ProviderOpenai, Model: "gpt-4o-mini"}},
+			wantWindow:   128_000,
+			wantEncoding: ai.EncodingO200K,
+		},
+		{
+			name:         "unknown models",
+			wantWindow:   8_192,
+			wantEncoding: ai.EncodingCL100K,
+		},
+		{
+			name:           "reserved output doesn't fit smallest window",
+			models:         []ai.ServiceModel{{Provider: ai.ProviderOpenai, Model: "gpt-4o-mini"}, {Provider: ai.ProviderOpenai, Model: "gpt-3.5-turbo"}},
+			reservedOutput: 20_000,
+			wantErr:        "doesn't fit context window 16385 of gpt-3.5-turbo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			as := modelService{FakeService: testsupport.NewFakeService(), models: tt.models}
+
+			budget, err := ai.NewBudgetForService(as, tt.reservedOutput)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if budget.ContextWindow != tt.wantWindow || budget.Tokenizer().Encoding() != tt.wantEncoding {
+				t.Errorf("budget = %d tokens of %s, want %d of %s", budget.ContextWindow, budget.Tokenizer().Encoding(), tt.wantWindow, tt.wantEncoding)
+			}
+		})
+	}
+}
+
+func TestBudgetFitMessages(t *testing.T) {
+	budget, err := ai.NewBudget("", 8_000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := strings.Repeat("word ", 500)
+
+	tests := []struct {
+		name         string
+		messages     []ai.Message
+		wantMessages int
+		wantErr      error
+	}{
+		{name: "fits", messages: []ai.Message{ai.SystemMessage("be brief"), ai.UserMessage("hi")}, wantMessages: 2},
+		{name: "drops oldest", messages: []ai.Message{ai.SystemMessage("be brief"), ai.UserMessage(long), ai.AssistantMessage("ok"), ai.UserMessage("hi")}, wantMessages: 3},
+		{name: "truncates longest", messages: []ai.Message{ai.SystemMessage("be brief"), ai.UserMessage(long)}, wantMessages: 2},
+		{name: "doesn't fit", messages: []ai.Message{ai.SystemMessage(long), ai.UserMessage(long)}, wantErr: ai.ErrContextLengthExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fitted, err := budget.FitMessages(tt.messages)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if len(fitted) != tt.wantMessages {
+				t.Errorf("messages = %d, want %d", len(fitted), tt.wantMessages)
+			}
+			if err == nil && budget.Tokenizer().CountMessages(fitted) > budget.Available() {
+				t.Errorf("fitted messages have %d tokens, want at most %d", budget.Tokenizer().CountMessages(fitted), budget.Available())
+			}
+		})
+	}
+}
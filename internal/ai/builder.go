@@ -0,0 +1,48 @@
+package ai
+
+import (
+	"fmt"
+)
+
+// ServiceBuilder composes the service: decorators wrap it in the order they are added
+// and interceptors run around the whole stack, the first added is the outermost.
+type ServiceBuilder struct {
+	service      Service
+	interceptors []Interceptor
+	err          error
+}
+
+func NewServiceBuilder(service Service) *ServiceBuilder {
+	return &ServiceBuilder{
+		service: service,
+	}
+}
+
+// Wrap adds a decorator like NewRetryService, e.g. b.Wrap(func(s ai.Service) (ai.Service, error) { return ai.NewRetryService(s) }).
+func (b *ServiceBuilder) Wrap(decorator func(Service) (Service, error)) *ServiceBuilder {
+	if b.err != nil {
+		return b
+	}
+	service, err := decorator(b.service)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.service = service
+	return b
+}
+
+func (b *ServiceBuilder) Use(interceptors ...Interceptor) *ServiceBuilder {
+	b.interceptors = append(b.interceptors, interceptors...)
+	return b
+}
+
+func (b *ServiceBuilder) Build() (Service, error) {
+	if b.err != nil {
+		return nil, fmt.Errorf("building ai service: %w", b.err)
+	}
+	if len(b.interceptors) == 0 {
+		return b.service, nil
+	}
+	return NewInterceptedService(b.service, b.interceptors...), nil
+}
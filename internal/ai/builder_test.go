@@ -0,0 +1,93 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"reflect"
+	"testing"
+)
+
+// labelService adds its label to answers of the wrapped service.
+type labelService struct {
+	ai.Service
+	label string
+}
+
+func (l labelService) Complete(ctx context.Context, req ai.Request) (ai.Response, error) {
+	resp, err := l.Service.Complete(ctx, req)
+	resp.Message.Content = l.label + "(" + resp.Message.Content + ")"
+	return resp, err
+}
+
+func label(name string) func(ai.Service) (ai.Service, error) {
+	return func(s ai.Service) (ai.Service, error) {
+		return labelService{Service: s, label: name}, nil
+	}
+}
+
+func TestServiceBuilder(t *testing.T) {
+	failed := errors.New("invalid config")
+
+	tests := []struct {
+		name    string
+		build   func(b *ai.ServiceBuilder) *ai.ServiceBuilder
+		want    string
+		wantErr error
+	}{
+		{name: "no decorators", build: func(b *ai.ServiceBuilder) *ai.ServiceBuilder { return b }, want: "answer"},
+		{name: "first decorator is innermost", build: func(b *ai.ServiceBuilder) *ai.ServiceBuilder { return b.Wrap(label("retry")).Wrap(label("cache")) }, want: "cache(retry(answer))"},
+		{
+			name: "failed decorator stops building",
+			build: func(b *ai.ServiceBuilder) *ai.ServiceBuilder {
+				return b.Wrap(func(ai.Service) (ai.Service, error) { return nil, failed }).Wrap(label("cache"))
+			},
+			wantErr: failed,
+		},
+		{
+			name: "interceptors around decorators",
+			build: func(b *ai.ServiceBuilder) *ai.ServiceBuilder {
+				return b.Use(func(ctx context.Context, req ai.Request, next ai.Invoker) (ai.Response, error) {
+					resp, err := next(ctx, req)
+					resp.Message.Content = "logging(" + resp.Message.Content + ")"
+					return resp, err
+				}).Wrap(label("retry"))
+			},
+			want: "logging(retry(answer))",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := testsupport.NewFakeService()
+			fake.On(ai.User, "hi").Reply("answer")
+
+			as, err := tt.build(ai.NewServiceBuilder(fake)).Build()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || as != nil {
+					t.Fatalf("build = %v, %v, want %v", as, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := as.Complete(context.Background(), ai.Request{Messages: []ai.Message{ai.UserMessage("hi")}})
+			if err != nil || resp.Message.Content != tt.want {
+				t.Errorf("answer = %q, %v, want %q", resp.Message.Content, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceBuilderWithoutInterceptorsReturnsService(t *testing.T) {
+	fake := testsupport.NewFakeService()
+	as, err := ai.NewServiceBuilder(fake).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(as, ai.Service(fake)) {
+		t.Errorf("service = %T, want the fake itself", as)
+	}
+}
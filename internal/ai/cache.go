@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultMemoryCacheEntries = 1000
+
+// NewCacheBackendFromEnv uses file cache when AI_CACHE_DIR is set, otherwise memory LRU of AI_CACHE_SIZE entries.
+func NewCacheBackendFromEnv() (CacheBackend, error) {
+	dir, ok := os.LookupEnv("AI_CACHE_DIR")
+	if ok && dir != "" {
+		return NewFileCache(dir)
+	}
+
+	size := defaultMemoryCacheEntries
+	value, ok := os.LookupEnv("AI_CACHE_SIZE")
+	if ok {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("parsing AI_CACHE_SIZE: %w", err)
+		}
+		size = parsed
+	}
+	return NewMemoryCache(size), nil
+}
+
+type CacheBackend interface {
+	Get(ctx context.Context, key string) (Response, bool, error)
+	Set(ctx context.Context, key string, resp Response, ttl time.Duration) error
+}
+
+// CacheKey is canonical hash of everything which changes the answer: model, messages, tools, format and params.
+// Models are the effective models of the service, so answers of other providers or default models aren't shared.
+func CacheKey(req Request, models ...ServiceModel) (string, error) {
+	var key any = req
+	if len(models) > 0 {
+		key = struct {
+			Request Request        `json:"request"`
+			Models  []ServiceModel `json:"models"`
+		}{req, models}
+	}
+	payload, err := json.Marshal(key)
+	if err != nil {
+		return "", fmt.Errorf("marshalling request for cache key: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+type memoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+}
+
+type memoryCacheEntry struct {
+	key       string
+	resp      Response
+	expiresAt time.Time
+}
+
+// NewMemoryCache is LRU cache which evicts the least recently used entry above maxEntries.
+func NewMemoryCache(maxEntries int) CacheBackend {
+	return &memoryCache{
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		order:      list.New(),
+	}
+}
+
+func (m *memoryCache) Get(_ context.Context, key string) (Response, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		return Response{}, false, nil
+	}
+	entry := element.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		m.order.Remove(element)
+		delete(m.entries, key)
+		return Response{}, false, nil
+	}
+	m.order.MoveToFront(element)
+	return entry.resp, true, nil
+}
+
+func (m *memoryCache) Set(_ context.Context, key string, resp Response, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := &memoryCacheEntry{key: key, resp: resp, expiresAt: time.Now().Add(ttl)}
+	if element, ok := m.entries[key]; ok {
+		element.Value = entry
+		m.order.MoveToFront(element)
+		return nil
+	}
+
+	m.entries[key] = m.order.PushFront(entry)
+	for m.maxEntries > 0 && m.order.Len() > m.maxEntries {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+	return nil
+}
+
+type fileCache struct {
+	dir string
+}
+
+type fileCacheEntry struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	Response  Response  `json:"response"`
+}
+
+// NewFileCache keeps one JSON file per key in dir, so cached answers survive restarts.
+func NewFileCache(dir string) (CacheBackend, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("creating cache dir: %w", err)
+	}
+	return &fileCache{dir: dir}, nil
+}
+
+func (f *fileCache) Get(_ context.Context, key string) (Response, bool, error) {
+	data, err := os.ReadFile(f.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return Response{}, false, nil
+	}
+	if err != nil {
+		return Response{}, false, fmt.Errorf("reading cache entry: %w", err)
+	}
+
+	var entry fileCacheEntry
+	err = json.Unmarshal(data, &entry)
+	if err != nil {
+		return Response{}, false, fmt.Errorf("decoding cache entry: %w", err)
+	}
+	if time.Now().After(entry.ExpiresAt) {
+		_ = os.Remove(f.path(key))
+		return Response{}, false, nil
+	}
+	return entry.Response, true, nil
+}
+
+func (f *fileCache) Set(_ context.Context, key string, resp Response, ttl time.Duration) error {
+	data, err := json.Marshal(fileCacheEntry{
+		ExpiresAt: time.Now().Add(ttl),
+		Response:  resp,
+	})
+	if err != nil {
+		return fmt.Errorf("encoding cache entry: %w", err)
+	}
+
+	// write and rename, so concurrent reader never sees half written file
+	tmp, err := os.CreateTemp(f.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating cache entry: %w", err)
+	}
+	_, err = tmp.Write(data)
+	closeErr := tmp.Close()
+	if err != nil || closeErr != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("writing cache entry: %w", errors.Join(err, closeErr))
+	}
+	return os.Rename(tmp.Name(), f.path(key))
+}
+
+func (f *fileCache) path(key string) string {
+	return filepath.Join(f.dir, key+".json")
+}
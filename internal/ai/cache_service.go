@@ -0,0 +1,167 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"log"
+	"strings"
+)
+
+type cacheService struct {
+	next    Service
+	backend CacheBackend
+	config  *option.CacheConfig
+}
+
+type cacheBypassKey struct{}
+
+// WithoutCache makes calls with the context skip cache lookup, the fresh answer is still stored.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func isCacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// NewCacheService caches complete answers (finish reason stop) of the wrapped service.
+func NewCacheService(next Service, backend CacheBackend, opts ...option.CacheOption) (Service, error) {
+	config, err := option.NewCacheConfig(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache service: %w", err)
+	}
+
+	return &cacheService{
+		next:    next,
+		backend: backend,
+		config:  config,
+	}, nil
+}
+
+func (c *cacheService) Chat(ctx context.Context, messages []Message, opts ...CallOption) (string, error) {
+	return c.ChatWithModel(ctx, messages, "", opts...)
+}
+
+func (c *cacheService) DefaultModels() []ServiceModel {
+	return DefaultModels(c.next)
+}
+
+func (c *cacheService) ChatWithModel(ctx context.Context, messages []Message, model string, opts ...CallOption) (string, error) {
+	return AnswerContent(c.Complete(ctx, Request{
+		Model:    model,
+		Messages: messages,
+		Params:   NewGenerationParams(opts...),
+	}))
+}
+
+func (c *cacheService) Complete(ctx context.Context, req Request) (Response, error) {
+	key, cached, ok := c.lookup(ctx, req)
+	if ok {
+		return cached, nil
+	}
+
+	resp, err := c.next.Complete(ctx, req)
+	if err != nil {
+		return Response{}, err
+	}
+	// truncated, filtered and tool call answers would be replayed to callers which expect a complete one
+	if resp.FinishReason == FinishReasonStop {
+		c.store(ctx, key, resp)
+	}
+	return resp, nil
+}
+
+// ChatStream sends cached answer as a single delta, a fresh answer is stored once the stream ends without error.
+func (c *cacheService) ChatStream(ctx context.Context, messages []Message, model string, opts ...CallOption) (<-chan StreamChunk, error) {
+	req := Request{
+		Model:    model,
+		Messages: messages,
+		Params:   NewGenerationParams(opts...),
+	}
+	key, cached, ok := c.lookup(ctx, req)
+	if ok {
+		ch := make(chan StreamChunk, 1)
+		ch <- StreamChunk{Content: cached.Message.Content}
+		close(ch)
+		return ch, nil
+	}
+
+	stream, err := c.next.ChatStream(ctx, messages, model, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(ch)
+		builder := strings.Builder{}
+		for chunk := range stream {
+			if chunk.Err != nil {
+				key = ""
+			}
+			builder.WriteString(chunk.Content)
+			select {
+			case ch <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+		c.store(ctx, key, Response{
+			Message:      AssistantMessage(builder.String()),
+			Model:        model,
+			FinishReason: FinishReasonStop,
+		})
+	}()
+	return ch, nil
+}
+
+// lookup returns empty key when the request can't be cached.
+func (c *cacheService) lookup(ctx context.Context, req Request) (string, Response, bool) {
+	key, err := c.key(req)
+	if err != nil {
+		log.Printf("skipping AI cache: %v", err)
+		return "", Response{}, false
+	}
+	if isCacheBypassed(ctx) {
+		return key, Response{}, false
+	}
+
+	resp, ok, err := c.backend.Get(ctx, key)
+	if err != nil {
+		log.Printf("AI cache lookup failed: %v", err)
+		return key, Response{}, false
+	}
+	if c.config.Observer != nil {
+		c.config.Observer(key, ok)
+	}
+	if ok {
+		resp.Cached = true
+	}
+	return key, resp, ok
+}
+
+// key resolves the default model before hashing, the explicit model replaces the first default one like fallback does.
+func (c *cacheService) key(req Request) (string, error) {
+	models := DefaultModels(c.next)
+	if len(models) == 0 {
+		return CacheKey(req)
+	}
+	models = append([]ServiceModel{}, models...)
+	if req.Model == "" {
+		req.Model = models[0].Model
+	}
+	models[0].Model = req.Model
+	return CacheKey(req, models...)
+}
+
+func (c *cacheService) store(ctx context.Context, key string, resp Response) {
+	if key == "" {
+		return
+	}
+	err := c.backend.Set(ctx, key, resp, c.config.TTL)
+	if err != nil {
+		log.Printf("AI cache store failed: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package ai_test
+
+import (
+	"context"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"testing"
+	"time"
+)
+
+// modelService is a fake which tells its default models like providers do.
+type modelService struct {
+	*testsupport.FakeService
+	models []ai.ServiceModel
+}
+
+func (m modelService) DefaultModels() []ai.ServiceModel {
+	return m.models
+}
+
+func TestCacheKey(t *testing.T) {
+	base := ai.Request{Model: "gpt-4o-mini", Messages: []ai.Message{ai.UserMessage("hi")}}
+	baseKey, err := ai.CacheKey(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		req      ai.Request
+		models   []ai.ServiceModel
+		wantSame bool
+	}{
+		{name: "same request", req: ai.Request{Model: "gpt-4o-mini", Messages: []ai.Message{ai.UserMessage("hi")}}, wantSame: true},
+		{name: "other model", req: ai.Request{Model: "gpt-4o", Messages: []ai.Message{ai.UserMessage("hi")}}},
+		{name: "other message", req: ai.Request{Model: "gpt-4o-mini", Messages: []ai.Message{ai.UserMessage("hello")}}},
+		{name: "other params", req: ai.Request{Model: "gpt-4o-mini", Messages: []ai.Message{ai.UserMessage("hi")}, Params: ai.NewGenerationParams(ai.Deterministic())}},
+		{name: "tools", req: ai.Request{Model: "gpt-4o-mini", Messages: []ai.Message{ai.UserMessage("hi")}, Tools: []ai.ToolDefinition{{Name: "weather"}}}},
+		{name: "response format", req: ai.Request{Model: "gpt-4o-mini", Messages: []ai.Message{ai.UserMessage("hi")}, ResponseFormat: &ai.ResponseFormat{Name: "answer"}}},
+		{name: "provider", req: base, models: []ai.ServiceModel{{Provider: ai.ProviderOpenai, Model: "gpt-4o-mini"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ai.CacheKey(tt.req, tt.models...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (key == baseKey) != tt.wantSame {
+				t.Errorf("same key = %v, want %v", key == baseKey, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestCacheServiceResolvesDefaultModel(t *testing.T) {
+	openai := []ai.ServiceModel{{Provider: ai.ProviderOpenai, Model: "gpt-4o-mini"}}
+
+	tests := []struct {
+		name string
+		// first is answered by the provider and stored, second is checked
+		firstModel   string
+		secondModel  string
+		secondModels []ai.ServiceModel
+		wantHit      bool
+	}{
+		{name: "default and explicit default model", firstModel: "", secondModel: "gpt-4o-mini", secondModels: openai, wantHit: true},
+		{name: "default model twice", firstModel: "", secondModel: "", secondModels: openai, wantHit: true},
+		{name: "default model changed", firstModel: "", secondModel: "", secondModels: []ai.ServiceModel{{Provider: ai.ProviderOpenai, Model: "gpt-4o"}}},
+		{name: "other provider with the same model name", firstModel: "", secondModel: "", secondModels: []ai.ServiceModel{{Provider: ai.ProviderOllama, Model: "gpt-4o-mini"}}},
+		{name: "other fallback chain", firstModel: "", secondModel: "", secondModels: append(openai, ai.ServiceModel{Provider: ai.ProviderAnthropic, Model: "claude-3-5-haiku-latest"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := ai.NewMemoryCache(10)
+			messages := []ai.Message{ai.UserMessage("hi")}
+
+			first := modelService{FakeService: testsupport.NewFakeService(), models: openai}
+			first.On(ai.User, "hi").Reply("first")
+			cached, err := ai.NewCacheService(first, backend)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := cached.ChatWithModel(context.Background(), messages, tt.firstModel); err != nil {
+				t.Fatal(err)
+			}
+
+			second := modelService{FakeService: testsupport.NewFakeService(), models: tt.secondModels}
+			second.On(ai.User, "hi").Reply("second")
+			cached, err = ai.NewCacheService(second, backend)
+			if err != nil {
+				t.Fatal(err)
+			}
+			answer, err := cached.ChatWithModel(context.Background(), messages, tt.secondModel)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if hit := answer == "first"; hit != tt.wantHit {
+				t.Errorf("cache hit = %v, want %v", hit, tt.wantHit)
+			}
+		})
+	}
+}
+
+func TestCacheServiceBypassAndTTL(t *testing.T) {
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		wait      time.Duration
+		bypass    bool
+		wantCalls int
+	}{
+		{name: "hit", ttl: time.Hour, wantCalls: 1},
+		{name: "bypass", ttl: time.Hour, bypass: true, wantCalls: 2},
+		{name: "expired", ttl: 5 * time.Millisecond, wait: 10 * time.Millisecond, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := testsupport.NewFakeService()
+			fake.On(ai.User, ".").Reply("answer")
+			cached, err := ai.NewCacheService(fake, ai.NewMemoryCache(10), option.WithCacheTTL(tt.ttl))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			messages := []ai.Message{ai.UserMessage("hi")}
+			if _, err := cached.Chat(context.Background(), messages); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(tt.wait)
+			ctx := context.Background()
+			if tt.bypass {
+				ctx = ai.WithoutCache(ctx)
+			}
+			if _, err := cached.Chat(ctx, messages); err != nil {
+				t.Fatal(err)
+			}
+
+			if calls := len(fake.Calls()); calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCacheServiceStoresOnlyCompleteAnswers(t *testing.T) {
+	tests := []struct {
+		name      string
+		reason    ai.FinishReason
+		wantCalls int
+	}{
+		{name: "stop", reason: ai.FinishReasonStop, wantCalls: 1},
+		{name: "length", reason: ai.FinishReasonLength, wantCalls: 2},
+		{name: "content filter", reason: ai.FinishReasonContentFilter, wantCalls: 2},
+		{name: "tool calls", reason: ai.FinishReasonToolCalls, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := testsupport.NewFakeService()
+			fake.On(ai.User, ".").ReplyResponse(ai.Response{Message: ai.AssistantMessage("answer"), FinishReason: tt.reason})
+			cached, err := ai.NewCacheService(fake, ai.NewMemoryCache(10))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := ai.Request{Messages: []ai.Message{ai.UserMessage("hi")}}
+			for range 2 {
+				resp, err := cached.Complete(context.Background(), req)
+				if err != nil || resp.FinishReason != tt.reason {
+					t.Fatalf("response = %+v, %v, want finish reason %s", resp, err, tt.reason)
+				}
+			}
+
+			if calls := len(fake.Calls()); calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package ai
+
+import (
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/models"
+)
+
+// checkCapabilities rejects requests which the model can't handle before they are sent, unknown models are not checked.
+func checkCapabilities(model string, req Request) error {
+	info, ok := models.Lookup(model)
+	if !ok {
+		return nil
+	}
+
+	if len(req.Tools) > 0 && !info.Tools {
+		return fmt.Errorf("model %s doesn't support tools", model)
+	}
+	if !info.Vision && hasImages(req.Messages) {
+		return fmt.Errorf("model %s doesn't support images", model)
+	}
+	if req.Params.MaxTokens != nil && info.MaxOutput > 0 && *req.Params.MaxTokens > info.MaxOutput {
+		return fmt.Errorf("max tokens %d exceeds max output %d of model %s", *req.Params.MaxTokens, info.MaxOutput, model)
+	}
+	// the rough estimate is confirmed by the tokenizer, counting every request would be too slow
+	if info.ContextWindow > 0 && int64(estimateRequestTokens(req)) > info.ContextWindow {
+		tokenizer, err := TokenizerForModel(model)
+		if err != nil {
+			return err
+		}
+		if tokens := tokenizer.CountMessages(req.Messages); int64(tokens) > info.ContextWindow {
+			return fmt.Errorf("request has %d tokens, context window of model %s is %d: %w", tokens, model, info.ContextWindow, ErrContextLengthExceeded)
+		}
+	}
+	return nil
+}
+
+func checkStreaming(model string) error {
+	info, ok := models.Lookup(model)
+	if ok && !info.Streaming {
+		return fmt.Errorf("model %s doesn't support streaming", model)
+	}
+	return nil
+}
+
+// supportsJSONSchema is true for unknown models, providers without support ignore the schema anyway.
+func supportsJSONSchema(model string) bool {
+	info, ok := models.Lookup(model)
+	return !ok || info.JSONSchema
+}
+
+func hasImages(messages []Message) bool {
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if part.Type == ContentPartImageURL || part.Type == ContentPartImageBase64 {
+				return true
+			}
+		}
+	}
+	return false
+}
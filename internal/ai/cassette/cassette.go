@@ -0,0 +1,77 @@
+package cassette
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"os"
+	"path/filepath"
+)
+
+// ErrNoInteraction is returned by Replayer for requests which weren't recorded.
+var ErrNoInteraction = errors.New("cassette: no recorded interaction for request")
+
+// Interaction is one recorded call, Stream holds deltas when it was ChatStream call.
+type Interaction struct {
+	Key      string      `json:"key"`
+	Request  ai.Request  `json:"request"`
+	Response ai.Response `json:"response"`
+	Stream   []string    `json:"stream,omitempty"`
+	IsStream bool        `json:"is_stream,omitempty"`
+}
+
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading cassette: %w", err)
+	}
+
+	var c Cassette
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding cassette: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("creating cassette dir: %w", err)
+	}
+	err = os.WriteFile(path, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("writing cassette: %w", err)
+	}
+	return nil
+}
+
+// key differs for streamed and completed calls, because they are replayed differently.
+func key(req ai.Request, stream bool) (string, error) {
+	k, err := ai.CacheKey(req)
+	if err != nil {
+		return "", err
+	}
+	if stream {
+		return "stream:" + k, nil
+	}
+	return k, nil
+}
+
+func streamRequest(messages []ai.Message, model string, opts []ai.CallOption) ai.Request {
+	return ai.Request{
+		Model:    model,
+		Messages: messages,
+		Params:   ai.NewGenerationParams(opts...),
+	}
+}
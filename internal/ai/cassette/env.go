@@ -0,0 +1,39 @@
+package cassette
+
+import (
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"os"
+)
+
+const (
+	ModeRecord = "record"
+	ModeReplay = "replay"
+)
+
+// FromEnv wraps the service by AI_CASSETTE_MODE (record or replay) with AI_CASSETTE file.
+// In replay mode newService isn't called at all, so no api key or network is needed.
+func FromEnv(newService func() (ai.Service, error)) (ai.Service, error) {
+	mode := os.Getenv("AI_CASSETTE_MODE")
+	if mode == "" {
+		return newService()
+	}
+
+	path, ok := os.LookupEnv("AI_CASSETTE")
+	if !ok || path == "" {
+		return nil, fmt.Errorf("AI_CASSETTE is required with AI_CASSETTE_MODE=%s", mode)
+	}
+
+	switch mode {
+	case ModeReplay:
+		return NewReplayer(path)
+	case ModeRecord:
+		as, err := newService()
+		if err != nil {
+			return nil, err
+		}
+		return NewRecorder(as, path), nil
+	default:
+		return nil, fmt.Errorf("unknown AI_CASSETTE_MODE: %s", mode)
+	}
+}
@@ -0,0 +1,96 @@
+package cassette
+
+import (
+	"context"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"log"
+	"sync"
+)
+
+// Recorder passes calls to the real service and writes every successful call to the cassette file.
+type Recorder struct {
+	next ai.Service
+	path string
+
+	mu       sync.Mutex
+	cassette *Cassette
+}
+
+func NewRecorder(next ai.Service, path string) *Recorder {
+	return &Recorder{
+		next:     next,
+		path:     path,
+		cassette: &Cassette{},
+	}
+}
+
+func (r *Recorder) Chat(ctx context.Context, messages []ai.Message, opts ...ai.CallOption) (string, error) {
+	return r.ChatWithModel(ctx, messages, "", opts...)
+}
+
+func (r *Recorder) DefaultModels() []ai.ServiceModel {
+	return ai.DefaultModels(r.next)
+}
+
+func (r *Recorder) ChatWithModel(ctx context.Context, messages []ai.Message, model string, opts ...ai.CallOption) (string, error) {
+	return ai.AnswerContent(r.Complete(ctx, streamRequest(messages, model, opts)))
+}
+
+func (r *Recorder) Complete(ctx context.Context, req ai.Request) (ai.Response, error) {
+	resp, err := r.next.Complete(ctx, req)
+	if err != nil {
+		return ai.Response{}, err
+	}
+	r.record(req, Interaction{Request: req, Response: resp}, false)
+	return resp, nil
+}
+
+func (r *Recorder) ChatStream(ctx context.Context, messages []ai.Message, model string, opts ...ai.CallOption) (<-chan ai.StreamChunk, error) {
+	stream, err := r.next.ChatStream(ctx, messages, model, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan ai.StreamChunk)
+	go func() {
+		defer close(ch)
+		var deltas []string
+		failed := false
+		for chunk := range stream {
+			if chunk.Err != nil {
+				failed = true
+			} else {
+				deltas = append(deltas, chunk.Content)
+			}
+			select {
+			case ch <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if failed {
+			return
+		}
+		req := streamRequest(messages, model, opts)
+		r.record(req, Interaction{Request: req, Stream: deltas, IsStream: true}, true)
+	}()
+	return ch, nil
+}
+
+func (r *Recorder) record(req ai.Request, interaction Interaction, stream bool) {
+	k, err := key(req, stream)
+	if err != nil {
+		log.Printf("cassette: skipping record: %v", err)
+		return
+	}
+	interaction.Key = k
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	// saved after every call, so the cassette is complete even when the process is killed
+	err = r.cassette.Save(r.path)
+	if err != nil {
+		log.Printf("cassette: %v", err)
+	}
+}
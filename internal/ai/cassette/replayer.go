@@ -0,0 +1,114 @@
+package cassette
+
+import (
+	"context"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"sync"
+)
+
+// Replayer serves recorded interactions without network. The same request recorded many times is replayed
+// in recorded order and the last one is repeated afterwards.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions map[string][]Interaction
+	served       map[string]int
+}
+
+func NewReplayer(path string) (*Replayer, error) {
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayerFromCassette(c), nil
+}
+
+func NewReplayerFromCassette(c *Cassette) *Replayer {
+	interactions := map[string][]Interaction{}
+	for _, interaction := range c.Interactions {
+		interactions[interaction.Key] = append(interactions[interaction.Key], interaction)
+	}
+	return &Replayer{
+		interactions: interactions,
+		served:       map[string]int{},
+	}
+}
+
+func (r *Replayer) Chat(ctx context.Context, messages []ai.Message, opts ...ai.CallOption) (string, error) {
+	return r.ChatWithModel(ctx, messages, "", opts...)
+}
+
+func (r *Replayer) ChatWithModel(ctx context.Context, messages []ai.Message, model string, opts ...ai.CallOption) (string, error) {
+	return ai.AnswerContent(r.Complete(ctx, streamRequest(messages, model, opts)))
+}
+
+func (r *Replayer) Complete(_ context.Context, req ai.Request) (ai.Response, error) {
+	interaction, err := r.next(req, false)
+	if err != nil {
+		return ai.Response{}, err
+	}
+	return interaction.Response, nil
+}
+
+func (r *Replayer) ChatStream(ctx context.Context, messages []ai.Message, model string, opts ...ai.CallOption) (<-chan ai.StreamChunk, error) {
+	interaction, err := r.next(streamRequest(messages, model, opts), true)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan ai.StreamChunk)
+	go func() {
+		defer close(ch)
+		for _, delta := range interaction.Stream {
+			select {
+			case ch <- ai.StreamChunk{Content: delta}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// Unused returns keys of recorded interactions which were never replayed, useful to find stale fixtures.
+func (r *Replayer) Unused() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unused []string
+	for k := range r.interactions {
+		if r.served[k] == 0 {
+			unused = append(unused, k)
+		}
+	}
+	return unused
+}
+
+func (r *Replayer) next(req ai.Request, stream bool) (Interaction, error) {
+	k, err := key(req, stream)
+	if err != nil {
+		return Interaction{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recorded, ok := r.interactions[k]
+	if !ok {
+		return Interaction{}, fmt.Errorf("%w: key %s, model %q, last message %q", ErrNoInteraction, k, req.Model, lastMessage(req))
+	}
+	i := min(r.served[k], len(recorded)-1)
+	r.served[k]++
+	return recorded[i], nil
+}
+
+func lastMessage(req ai.Request) string {
+	if len(req.Messages) == 0 {
+		return ""
+	}
+	content := req.Messages[len(req.Messages)-1].Content
+	if len(content) > 200 {
+		return content[:200] + "..."
+	}
+	return content
+}
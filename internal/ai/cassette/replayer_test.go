@@ -0,0 +1,100 @@
+package cassette_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/cassette"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	tests := []struct {
+		name     string
+		recorded []string
+		asked    []string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "recorded question", recorded: []string{"hi"}, asked: []string{"hi"}, want: []string{"answer 1"}},
+		{name: "repeated question in recorded order", recorded: []string{"hi", "hi"}, asked: []string{"hi", "hi", "hi"}, want: []string{"answer 1", "answer 2", "answer 2"}},
+		{name: "unrecorded question", recorded: []string{"hi"}, asked: []string{"bye"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cassette.json")
+			fake := testsupport.NewFakeService()
+			for i := range tt.recorded {
+				fake.On(ai.User, ".").Reply(fmt.Sprintf("answer %d", i+1)).Times(1)
+			}
+			recorder := cassette.NewRecorder(fake, path)
+			for _, question := range tt.recorded {
+				if _, err := recorder.Chat(context.Background(), []ai.Message{ai.UserMessage(question)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			replayer, err := cassette.NewReplayer(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, question := range tt.asked {
+				answer, err := replayer.Chat(context.Background(), []ai.Message{ai.UserMessage(question)})
+				if tt.wantErr {
+					if !errors.Is(err, cassette.ErrNoInteraction) || !strings.Contains(err.Error(), question) {
+						t.Fatalf("err = %v, want no interaction naming the question", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, answer)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("answers = %v, want %v", got, tt.want)
+			}
+			if unused := replayer.Unused(); len(unused) != 0 {
+				t.Errorf("unused = %v", unused)
+			}
+		})
+	}
+}
+
+func TestReplayStreamIsNotComplete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	fake := testsupport.NewFakeService()
+	fake.On(ai.User, ".").Reply("streamed answer")
+	recorder := cassette.NewRecorder(fake, path)
+	stream, err := recorder.ChatStream(context.Background(), []ai.Message{ai.UserMessage("hi")}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range stream {
+	}
+
+	replayer, err := cassette.NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replayer.Chat(context.Background(), []ai.Message{ai.UserMessage("hi")}); !errors.Is(err, cassette.ErrNoInteraction) {
+		t.Errorf("complete of recorded stream: err = %v, want no interaction", err)
+	}
+	stream, err = replayer.ChatStream(context.Background(), []ai.Message{ai.UserMessage("hi")}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Builder{}
+	for chunk := range stream {
+		content.WriteString(chunk.Content)
+	}
+	if content.String() != "streamed answer" {
+		t.Errorf("replayed stream = %q", content.String())
+	}
+}
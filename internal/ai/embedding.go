@@ -0,0 +1,21 @@
+package ai
+
+import (
+	"context"
+)
+
+type EmbeddingService interface {
+	// Embed returns one vector per input in the same order.
+	Embed(ctx context.Context, inputs []string) (Embeddings, error)
+}
+
+type Embeddings struct {
+	Vectors [][]float64
+	Model   string
+	Usage   EmbeddingUsage
+}
+
+type EmbeddingUsage struct {
+	PromptTokens int64
+	TotalTokens  int64
+}
@@ -0,0 +1,159 @@
+package ai
+
+import (
+	"errors"
+	"github.com/openai/openai-go"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors of AI calls, check them with errors.Is, e.g. to map them to HTTP status codes.
+var (
+	ErrRateLimited           = errors.New("rate limited by AI provider")
+	ErrAuth                  = errors.New("AI provider rejected credentials")
+	ErrContextLengthExceeded = errors.New("context length of the model exceeded")
+	ErrContentFiltered       = errors.New("answer refused by content filter")
+	ErrTruncated             = errors.New("answer truncated by max tokens")
+	ErrProviderUnavailable   = errors.New("AI provider unavailable")
+	ErrInvalidRequest        = errors.New("invalid request to AI provider")
+)
+
+// StatusError is returned by providers when the API responds with error status code.
+type StatusError struct {
+	StatusCode int
+	// Code is the error code of the provider, e.g. context_length_exceeded of OpenAI.
+	Code string
+	// RetryAfter is parsed from Retry-After headers, zero when the provider didn't send it.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// Is classifies the status code, 529 is "overloaded" of Anthropic.
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrAuth:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrProviderUnavailable:
+		return e.StatusCode >= http.StatusInternalServerError
+	case ErrContextLengthExceeded:
+		return e.StatusCode == http.StatusRequestEntityTooLarge || isContextLengthMessage(e.Code) || isContextLengthMessage(e.Err.Error())
+	}
+	return false
+}
+
+func isContextLengthMessage(message string) bool {
+	message = strings.ToLower(message)
+	for _, phrase := range []string{"context_length_exceeded", "maximum context length", "prompt is too long", "context window"} {
+		if strings.Contains(message, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// IncompleteError is returned when the answer ended because of max tokens or content filter, Response has the partial answer.
+type IncompleteError struct {
+	Reason   FinishReason
+	Response Response
+}
+
+func (e *IncompleteError) Error() string {
+	if e.Reason == FinishReasonContentFilter {
+		return ErrContentFiltered.Error()
+	}
+	return ErrTruncated.Error()
+}
+
+func (e *IncompleteError) Is(target error) bool {
+	switch e.Reason {
+	case FinishReasonLength:
+		return target == ErrTruncated
+	case FinishReasonContentFilter:
+		return target == ErrContentFiltered
+	}
+	return false
+}
+
+// checkFinishReason is used where only the text of the answer is returned and an incomplete one would pass as complete.
+func checkFinishReason(resp Response) error {
+	if resp.FinishReason == FinishReasonLength || resp.FinishReason == FinishReasonContentFilter {
+		return &IncompleteError{Reason: resp.FinishReason, Response: resp}
+	}
+	return nil
+}
+
+// AnswerContent returns the text of a complete answer, it's used by ChatWithModel of services.
+func AnswerContent(resp Response, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	if err := checkFinishReason(resp); err != nil {
+		return "", err
+	}
+	return resp.Message.Content, nil
+}
+
+func newStatusError(resp *http.Response, err error) *StatusError {
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header),
+		Err:        err,
+	}
+}
+
+func wrapOpenaiError(err error) error {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	statusErr := &StatusError{
+		StatusCode: apiErr.StatusCode,
+		Code:       apiErr.Code,
+		Err:        err,
+	}
+	if apiErr.Response != nil {
+		statusErr.RetryAfter = parseRetryAfter(apiErr.Response.Header)
+	}
+	return statusErr
+}
+
+func parseRetryAfter(header http.Header) time.Duration {
+	if ms := header.Get("Retry-After-Ms"); ms != "" {
+		parsed, err := strconv.ParseFloat(ms, 64)
+		if err == nil && parsed > 0 {
+			return time.Duration(parsed * float64(time.Millisecond))
+		}
+	}
+
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	date, err := http.ParseTime(value)
+	if err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
@@ -0,0 +1,296 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FallbackTarget is one (provider, model) pair of the chain, empty Model means the default one of the provider.
+type FallbackTarget struct {
+	Service Service
+	Model   string
+}
+
+type fallbackService struct {
+	targets []FallbackTarget
+	config  *option.FallbackConfig
+}
+
+// NewFallbackService asks targets in order and falls through on transient errors, timeouts and content filter refusals,
+// other errors (e.g. context length exceeded or invalid request) would fail on every target and are returned at once.
+// Explicit model of the request replaces the model of the first target.
+func NewFallbackService(targets []FallbackTarget, opts ...option.FallbackOption) (Service, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("failed to create fallback service: no targets")
+	}
+	config, err := option.NewFallbackConfig(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fallback service: %w", err)
+	}
+
+	return &fallbackService{
+		targets: targets,
+		config:  config,
+	}, nil
+}
+
+// NewFallbackTargetsFromEnv reads the chain from AI_FALLBACK_MODELS, e.g. "openai:google/gemini-2.5-flash,anthropic:claude-sonnet-4-20250514".
+// Every provider is created once, given options are used only by the openai provider.
+func NewFallbackTargetsFromEnv(opts ...option.Option) ([]FallbackTarget, error) {
+	chain, ok := os.LookupEnv("AI_FALLBACK_MODELS")
+	if !ok || strings.TrimSpace(chain) == "" {
+		return nil, fmt.Errorf("missing AI_FALLBACK_MODELS env")
+	}
+
+	services := map[string]Service{}
+	var targets []FallbackTarget
+	for _, item := range strings.Split(chain, ",") {
+		provider, model, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok || model == "" {
+			return nil, fmt.Errorf("invalid AI_FALLBACK_MODELS item %q, expected provider:model", item)
+		}
+
+		service, ok := services[provider]
+		if !ok {
+			var err error
+			switch provider {
+			case ProviderOpenai:
+				service, err = NewOpenaiService(opts...)
+			case ProviderAnthropic:
+				var anthropicOpts []option.AnthropicOption
+				anthropicOpts, err = option.AnthropicOptions(opts...)
+				if err == nil {
+					service, err = NewAnthropicService(anthropicOpts...)
+				}
+			case ProviderOllama:
+				var ollamaOpts []option.OllamaOption
+				ollamaOpts, err = option.OllamaOptions(opts...)
+				if err == nil {
+					service, err = NewOllamaService(ollamaOpts...)
+				}
+			default:
+				err = fmt.Errorf("unknown provider: %s", provider)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("creating fallback provider %s: %w", provider, err)
+			}
+			services[provider] = service
+		}
+		targets = append(targets, FallbackTarget{Service: service, Model: model})
+	}
+	return targets, nil
+}
+
+func (f *fallbackService) Chat(ctx context.Context, messages []Message, opts ...CallOption) (string, error) {
+	return f.ChatWithModel(ctx, messages, "", opts...)
+}
+
+// DefaultModels returns models of all targets, empty model of a target is resolved by its service.
+func (f *fallbackService) DefaultModels() []ServiceModel {
+	var result []ServiceModel
+	for _, target := range f.targets {
+		defaults := DefaultModels(target.Service)
+		model := ServiceModel{Model: target.Model}
+		if len(defaults) > 0 {
+			model.Provider = defaults[0].Provider
+			if model.Model == "" {
+				model.Model = defaults[0].Model
+			}
+		}
+		result = append(result, model)
+	}
+	return result
+}
+
+func (f *fallbackService) ChatWithModel(ctx context.Context, messages []Message, model string, opts ...CallOption) (string, error) {
+	return AnswerContent(f.Complete(ctx, Request{
+		Model:    model,
+		Messages: messages,
+		Params:   NewGenerationParams(opts...),
+	}))
+}
+
+func (f *fallbackService) Complete(ctx context.Context, req Request) (Response, error) {
+	var errs []error
+	for i, target := range f.targets {
+		model := f.modelFor(i, req.Model)
+		attemptReq := req
+		attemptReq.Model = model
+
+		attemptCtx, cancel := context.WithTimeout(ctx, f.config.AttemptTimeout)
+		resp, err := target.Service.Complete(attemptCtx, attemptReq)
+		cancel()
+		if err == nil && resp.FinishReason == FinishReasonContentFilter {
+			err = ErrContentFiltered
+		}
+		if err == nil {
+			if resp.Model == "" {
+				resp.Model = model
+			}
+			recordAnsweredBy(ctx, resp.Model)
+			return resp, nil
+		}
+
+		if ctx.Err() != nil {
+			return Response{}, errors.Join(ctx.Err(), err)
+		}
+		if !fallsThrough(err) {
+			recordFallback(ctx, model, err)
+			return Response{}, fmt.Errorf("fallback model %s failed: %w", displayModel(model), err)
+		}
+		errs = append(errs, fmt.Errorf("model %s: %w", displayModel(model), err))
+		f.fallingThrough(ctx, model, err, i)
+	}
+	return Response{}, fmt.Errorf("all fallback models failed: %w", errors.Join(errs...))
+}
+
+// ChatStream falls through only until the first delta is received, later errors are passed to the caller.
+// AttemptTimeout limits only the wait for the first delta, a long answer which is already streamed isn't cut.
+// Streams carry no finish reason, so content filter refusals are detected only by Complete.
+func (f *fallbackService) ChatStream(ctx context.Context, messages []Message, model string, opts ...CallOption) (<-chan StreamChunk, error) {
+	var errs []error
+	for i, target := range f.targets {
+		targetModel := f.modelFor(i, model)
+
+		attemptCtx, cancel := context.WithCancel(ctx)
+		timer := time.AfterFunc(f.config.AttemptTimeout, cancel)
+		first, stream, opened, err := openStream(attemptCtx, target.Service, messages, targetModel, opts)
+		timer.Stop()
+		if err == nil && attemptCtx.Err() != nil {
+			err = attemptCtx.Err()
+		}
+		if err == nil {
+			recordAnsweredBy(ctx, targetModel)
+			ch := make(chan StreamChunk)
+			go func() {
+				defer close(ch)
+				defer cancel()
+				if !opened {
+					return
+				}
+				forwardStream(ctx, first, stream, ch)
+			}()
+			return ch, nil
+		}
+		cancel()
+
+		if ctx.Err() != nil {
+			return nil, errors.Join(ctx.Err(), err)
+		}
+		if !fallsThrough(err) {
+			recordFallback(ctx, targetModel, err)
+			return nil, fmt.Errorf("fallback model %s failed: %w", displayModel(targetModel), err)
+		}
+		errs = append(errs, fmt.Errorf("model %s: %w", displayModel(targetModel), err))
+		f.fallingThrough(ctx, targetModel, err, i)
+	}
+	return nil, fmt.Errorf("all fallback models failed: %w", errors.Join(errs...))
+}
+
+func openStream(ctx context.Context, service Service, messages []Message, model string, opts []CallOption) (StreamChunk, <-chan StreamChunk, bool, error) {
+	stream, err := service.ChatStream(ctx, messages, model, opts...)
+	if err != nil {
+		return StreamChunk{}, nil, false, err
+	}
+	first, opened := <-stream
+	if opened && first.Err != nil {
+		return StreamChunk{}, nil, false, first.Err
+	}
+	return first, stream, opened, nil
+}
+
+// fallsThrough reports errors which the next target may not have: transient ones, attempt timeouts and content filter refusals.
+func fallsThrough(err error) bool {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, ErrContentFiltered),
+		errors.Is(err, ErrRateLimited), errors.Is(err, ErrProviderUnavailable):
+		return true
+	}
+	return IsRetryable(err)
+}
+
+func (f *fallbackService) modelFor(i int, requested string) string {
+	if i == 0 && requested != "" {
+		return requested
+	}
+	return f.targets[i].Model
+}
+
+func (f *fallbackService) fallingThrough(ctx context.Context, model string, err error, i int) {
+	recordFallback(ctx, model, err)
+	if i == len(f.targets)-1 {
+		log.Printf("AI model %s failed, no fallback left: %v", displayModel(model), err)
+		return
+	}
+	log.Printf("AI model %s failed, falling back to %s: %v", displayModel(model), displayModel(f.modelFor(i+1, "")), err)
+	if f.config.OnFallback != nil {
+		f.config.OnFallback(model, err)
+	}
+}
+
+func displayModel(model string) string {
+	if model == "" {
+		return "default"
+	}
+	return model
+}
+
+// FallbackReport collects which models failed and which one answered during calls made with its context.
+type FallbackReport struct {
+	mu         sync.Mutex
+	answeredBy string
+	failed     []FailedModel
+}
+
+// FailedModel has the raw error of the provider, it's logged and mustn't be sent to clients as is.
+type FailedModel struct {
+	Model string
+	Err   error
+}
+
+type fallbackReportKey struct{}
+
+func WithFallbackReport(ctx context.Context) (context.Context, *FallbackReport) {
+	report := &FallbackReport{}
+	return context.WithValue(ctx, fallbackReportKey{}, report), report
+}
+
+// AnsweredBy returns the model of the last successful call, empty when no fallback chain was used.
+func (r *FallbackReport) AnsweredBy() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.answeredBy
+}
+
+func (r *FallbackReport) Failed() []FailedModel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]FailedModel{}, r.failed...)
+}
+
+func recordAnsweredBy(ctx context.Context, model string) {
+	report, ok := ctx.Value(fallbackReportKey{}).(*FallbackReport)
+	if !ok {
+		return
+	}
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	report.answeredBy = model
+}
+
+func recordFallback(ctx context.Context, model string, err error) {
+	report, ok := ctx.Value(fallbackReportKey{}).(*FallbackReport)
+	if !ok {
+		return
+	}
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	report.failed = append(report.failed, FailedModel{Model: model, Err: err})
+}
@@ -0,0 +1,314 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// target answers with the reply, or fails with err when it's set.
+type target struct {
+	model string
+	reply ai.Response
+	err   error
+	delay time.Duration
+}
+
+// slowService answers after the delay unless the attempt times out first.
+type slowService struct {
+	*testsupport.FakeService
+	delay time.Duration
+}
+
+func (s slowService) Complete(ctx context.Context, req ai.Request) (ai.Response, error) {
+	resp, err := s.FakeService.Complete(ctx, req)
+	select {
+	case <-time.After(s.delay):
+		return resp, err
+	case <-ctx.Done():
+		return ai.Response{}, ctx.Err()
+	}
+}
+
+func fallbackChain(t *testing.T, targets []target) (ai.Service, []*testsupport.FakeService) {
+	t.Helper()
+	var chain []ai.FallbackTarget
+	var fakes []*testsupport.FakeService
+	for _, tg := range targets {
+		fake := testsupport.NewFakeService()
+		if tg.err != nil {
+			fake.On(ai.User, ".").ReplyError(tg.err)
+		} else {
+			fake.On(ai.User, ".").ReplyResponse(tg.reply)
+		}
+		chain = append(chain, ai.FallbackTarget{Service: slowService{FakeService: fake, delay: tg.delay}, Model: tg.model})
+		fakes = append(fakes, fake)
+	}
+	fs, err := ai.NewFallbackService(chain, option.WithAttemptTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fs, fakes
+}
+
+func answer(content string) ai.Response {
+	return ai.Response{Message: ai.AssistantMessage(content), FinishReason: ai.FinishReasonStop}
+}
+
+func TestFallbackService(t *testing.T) {
+	unavailable := &ai.StatusError{StatusCode: http.StatusServiceUnavailable, Err: errors.New("overloaded")}
+	badRequest := &ai.StatusError{StatusCode: http.StatusBadRequest, Err: errors.New("invalid schema")}
+
+	tests := []struct {
+		name         string
+		targets      []target
+		model        string
+		want         string
+		wantErr      error
+		wantAllErr   bool
+		wantAnswered string
+		wantFailed   []string
+		wantModels   []string
+	}{
+		{
+			name:         "first answers",
+			targets:      []target{{model: "a", reply: answer("from a")}, {model: "b", reply: answer("from b")}},
+			want:         "from a",
+			wantAnswered: "a",
+			wantModels:   []string{"a", ""},
+		},
+		{
+			name:         "falls through errors",
+			targets:      []target{{model: "a", err: unavailable}, {model: "b", err: ai.ErrRateLimited}, {model: "c", reply: answer("from c")}},
+			want:         "from c",
+			wantAnswered: "c",
+			wantFailed:   []string{"a", "b"},
+			wantModels:   []string{"a", "b", "c"},
+		},
+		{
+			name:         "falls through content filter",
+			targets:      []target{{model: "a", reply: ai.Response{FinishReason: ai.FinishReasonContentFilter}}, {model: "b", reply: answer("from b")}},
+			want:         "from b",
+			wantAnswered: "b",
+			wantFailed:   []string{"a"},
+			wantModels:   []string{"a", "b"},
+		},
+		{
+			name:         "falls through attempt timeout",
+			targets:      []target{{model: "a", reply: answer("late"), delay: 100 * time.Millisecond}, {model: "b", reply: answer("from b")}},
+			want:         "from b",
+			wantAnswered: "b",
+			wantFailed:   []string{"a"},
+			wantModels:   []string{"a", "b"},
+		},
+		{
+			name:         "explicit model replaces the first target",
+			targets:      []target{{model: "a", reply: answer("from x")}, {model: "b", reply: answer("from b")}},
+			model:        "x",
+			want:         "from x",
+			wantAnswered: "x",
+			wantModels:   []string{"x", ""},
+		},
+		{
+			name:       "context length exceeded fails at once",
+			targets:    []target{{model: "a", err: ai.ErrContextLengthExceeded}, {model: "b", reply: answer("from b")}},
+			wantErr:    ai.ErrContextLengthExceeded,
+			wantFailed: []string{"a"},
+			wantModels: []string{"a", ""},
+		},
+		{
+			name:       "bad request fails at once",
+			targets:    []target{{model: "a", err: badRequest}, {model: "b", reply: answer("from b")}},
+			wantErr:    badRequest,
+			wantFailed: []string{"a"},
+			wantModels: []string{"a", ""},
+		},
+		{
+			name:       "canceled call fails at once",
+			targets:    []target{{model: "a", err: context.Canceled}, {model: "b", reply: answer("from b")}},
+			wantErr:    context.Canceled,
+			wantFailed: []string{"a"},
+			wantModels: []string{"a", ""},
+		},
+		{
+			name:       "all fail",
+			targets:    []target{{model: "a", err: unavailable}, {model: "b", err: unavailable}},
+			wantErr:    ai.ErrProviderUnavailable,
+			wantAllErr: true,
+			wantFailed: []string{"a", "b"},
+			wantModels: []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, fakes := fallbackChain(t, tt.targets)
+			ctx, report := ai.WithFallbackReport(context.Background())
+
+			got, err := fs.ChatWithModel(ctx, []ai.Message{ai.UserMessage("hi")}, tt.model)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || strings.Contains(err.Error(), "all fallback models failed") != tt.wantAllErr {
+					t.Fatalf("err = %v, want %v, of every model %t", err, tt.wantErr, tt.wantAllErr)
+				}
+			} else if err != nil || got != tt.want {
+				t.Fatalf("answer = %q, err = %v, want %q", got, err, tt.want)
+			}
+
+			if report.AnsweredBy() != tt.wantAnswered {
+				t.Errorf("answered by = %q, want %q", report.AnsweredBy(), tt.wantAnswered)
+			}
+			var failed []string
+			for _, f := range report.Failed() {
+				if f.Err == nil {
+					t.Errorf("failed model %s without error", f.Model)
+				}
+				failed = append(failed, f.Model)
+			}
+			if !reflect.DeepEqual(failed, tt.wantFailed) {
+				t.Errorf("failed = %v, want %v", failed, tt.wantFailed)
+			}
+			var models []string
+			for _, fake := range fakes {
+				model := ""
+				if calls := fake.Calls(); len(calls) > 0 {
+					model = calls[0].Model
+				}
+				models = append(models, model)
+			}
+			if !reflect.DeepEqual(models, tt.wantModels) {
+				t.Errorf("asked models = %v, want %v", models, tt.wantModels)
+			}
+		})
+	}
+}
+
+func TestFallbackStream(t *testing.T) {
+	fs, _ := fallbackChain(t, []target{{model: "a", err: ai.ErrRateLimited}, {model: "b", reply: answer("streamed from b")}})
+	ctx, report := ai.WithFallbackReport(context.Background())
+
+	stream, err := fs.ChatStream(ctx, []ai.Message{ai.UserMessage("hi")}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Builder{}
+	for chunk := range stream {
+		if chunk.Err != nil {
+			t.Fatal(chunk.Err)
+		}
+		content.WriteString(chunk.Content)
+	}
+
+	if content.String() != "streamed from b" || report.AnsweredBy() != "b" {
+		t.Errorf("stream = %q answered by %q, want b", content.String(), report.AnsweredBy())
+	}
+}
+
+// slowStream sends the first delta at once and the rest after the delay.
+type slowStream struct {
+	*testsupport.FakeService
+	delay time.Duration
+}
+
+func (s slowStream) ChatStream(ctx context.Context, messages []ai.Message, model string, opts ...ai.CallOption) (<-chan ai.StreamChunk, error) {
+	ch := make(chan ai.StreamChunk)
+	go func() {
+		defer close(ch)
+		ch <- ai.StreamChunk{Content: "long "}
+		select {
+		case <-time.After(s.delay):
+			ch <- ai.StreamChunk{Content: "answer"}
+		case <-ctx.Done():
+			ch <- ai.StreamChunk{Err: ctx.Err()}
+		}
+	}()
+	return ch, nil
+}
+
+func TestFallbackStreamAttemptTimeoutEndsWithFirstDelta(t *testing.T) {
+	fs, err := ai.NewFallbackService([]ai.FallbackTarget{{Service: slowStream{FakeService: testsupport.NewFakeService(), delay: 100 * time.Millisecond}}}, option.WithAttemptTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := fs.ChatStream(context.Background(), []ai.Message{ai.UserMessage("hi")}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ai.CollectStream(stream); err != nil || got != "long answer" {
+		t.Errorf("stream = %q, %v, want the whole answer", got, err)
+	}
+}
+
+func TestFallbackStreamFailsAtOnceOnContextLength(t *testing.T) {
+	fs, fakes := fallbackChain(t, []target{{model: "a", err: ai.ErrContextLengthExceeded}, {model: "b", reply: answer("from b")}})
+
+	if _, err := fs.ChatStream(context.Background(), []ai.Message{ai.UserMessage("hi")}, ""); !errors.Is(err, ai.ErrContextLengthExceeded) {
+		t.Errorf("err = %v, want %v", err, ai.ErrContextLengthExceeded)
+	}
+	if calls := len(fakes[1].Calls()); calls != 0 {
+		t.Errorf("calls of the next model = %d, want 0", calls)
+	}
+}
+
+func TestFallbackDefaultModels(t *testing.T) {
+	openai := modelService{FakeService: testsupport.NewFakeService(), models: []ai.ServiceModel{{Provider: ai.ProviderOpenai, Model: "gpt-4o-mini"}}}
+	anthropic := modelService{FakeService: testsupport.NewFakeService(), models: []ai.ServiceModel{{Provider: ai.ProviderAnthropic, Model: "claude-sonnet-4-20250514"}}}
+	fs, err := ai.NewFallbackService([]ai.FallbackTarget{{Service: openai, Model: "gpt-4o"}, {Service: anthropic}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ai.ServiceModel{{Provider: ai.ProviderOpenai, Model: "gpt-4o"}, {Provider: ai.ProviderAnthropic, Model: "claude-sonnet-4-20250514"}}
+	if got := ai.DefaultModels(fs); !reflect.DeepEqual(got, want) {
+		t.Errorf("default models = %+v, want %+v", got, want)
+	}
+}
+
+func TestNewFallbackTargetsFromEnv(t *testing.T) {
+	tests := []struct {
+		name       string
+		chain      string
+		wantModels []string
+		wantErr    string
+	}{
+		{name: "chain", chain: "anthropic:claude-sonnet-4-20250514, anthropic:claude-3-5-haiku-latest", wantModels: []string{"claude-sonnet-4-20250514", "claude-3-5-haiku-latest"}},
+		{name: "missing", chain: " ", wantErr: "missing AI_FALLBACK_MODELS"},
+		{name: "missing model", chain: "anthropic", wantErr: "expected provider:model"},
+		{name: "unknown provider", chain: "gemini:pro", wantErr: "unknown provider"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AI_FALLBACK_MODELS", tt.chain)
+			t.Setenv("ANTHROPIC_API_KEY", "key")
+
+			targets, err := ai.NewFallbackTargetsFromEnv()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var models []string
+			for _, target := range targets {
+				models = append(models, target.Model)
+			}
+			if !reflect.DeepEqual(models, tt.wantModels) {
+				t.Errorf("models = %v, want %v", models, tt.wantModels)
+			}
+			if targets[0].Service != targets[1].Service {
+				t.Error("targets of one provider should share its service")
+			}
+		})
+	}
+}
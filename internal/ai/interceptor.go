@@ -0,0 +1,137 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// Invoker performs the call, it's the next interceptor or the wrapped service.
+type Invoker func(ctx context.Context, req Request) (Response, error)
+
+// Interceptor runs around every call, it can modify the request before calling next and the response after it.
+// For streams the response is assembled after the last delta, so its modifications don't reach the caller.
+type Interceptor func(ctx context.Context, req Request, next Invoker) (Response, error)
+
+type interceptedService struct {
+	next         Service
+	interceptors []Interceptor
+}
+
+type streamCallKey struct{}
+
+// IsStream tells interceptors the call is ChatStream.
+func IsStream(ctx context.Context) bool {
+	stream, _ := ctx.Value(streamCallKey{}).(bool)
+	return stream
+}
+
+// NewInterceptedService runs interceptors in the given order, the first one is the outermost.
+func NewInterceptedService(next Service, interceptors ...Interceptor) Service {
+	return &interceptedService{
+		next:         next,
+		interceptors: interceptors,
+	}
+}
+
+func (s *interceptedService) Chat(ctx context.Context, messages []Message, opts ...CallOption) (string, error) {
+	return s.ChatWithModel(ctx, messages, "", opts...)
+}
+
+func (s *interceptedService) DefaultModels() []ServiceModel {
+	return DefaultModels(s.next)
+}
+
+func (s *interceptedService) ChatWithModel(ctx context.Context, messages []Message, model string, opts ...CallOption) (string, error) {
+	return AnswerContent(s.Complete(ctx, Request{
+		Model:    model,
+		Messages: messages,
+		Params:   NewGenerationParams(opts...),
+	}))
+}
+
+func (s *interceptedService) Complete(ctx context.Context, req Request) (Response, error) {
+	return s.invoke(ctx, req, s.next.Complete)
+}
+
+func (s *interceptedService) ChatStream(ctx context.Context, messages []Message, model string, opts ...CallOption) (<-chan StreamChunk, error) {
+	ctx = context.WithValue(ctx, streamCallKey{}, true)
+	req := Request{
+		Model:    model,
+		Messages: messages,
+		Params:   NewGenerationParams(opts...),
+	}
+
+	out := make(chan StreamChunk)
+	opened := make(chan error, 1)
+	// only the first open is signalled, interceptors like retries may call next again
+	var openedOnce sync.Once
+	signalOpened := func(err error) {
+		openedOnce.Do(func() { opened <- err })
+	}
+	go func() {
+		defer close(out)
+		streamOpened := false
+		resp, err := s.invoke(ctx, req, func(ctx context.Context, req Request) (Response, error) {
+			stream, err := s.next.ChatStream(ctx, req.Messages, req.Model, WithParams(req.Params))
+			if err != nil {
+				return Response{}, err
+			}
+			streamOpened = true
+			signalOpened(nil)
+
+			builder := strings.Builder{}
+			for chunk := range stream {
+				if chunk.Err != nil {
+					return Response{}, chunk.Err
+				}
+				builder.WriteString(chunk.Content)
+				select {
+				case out <- chunk:
+				case <-ctx.Done():
+					return Response{}, ctx.Err()
+				}
+			}
+			return Response{
+				Message:      AssistantMessage(builder.String()),
+				Model:        req.Model,
+				FinishReason: FinishReasonStop,
+			}, nil
+		})
+
+		var last StreamChunk
+		switch {
+		case !streamOpened:
+			signalOpened(err)
+			if err != nil {
+				return
+			}
+			// an interceptor answered without calling the service
+			last = StreamChunk{Content: resp.Message.Content}
+		case err != nil:
+			last = StreamChunk{Err: err}
+		default:
+			return
+		}
+		select {
+		case out <- last:
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := <-opened; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *interceptedService) invoke(ctx context.Context, req Request, last Invoker) (Response, error) {
+	invoker := last
+	for i := len(s.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := s.interceptors[i], invoker
+		invoker = func(ctx context.Context, req Request) (Response, error) {
+			return interceptor(ctx, req, next)
+		}
+	}
+	return invoker(ctx, req)
+}
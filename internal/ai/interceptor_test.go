@@ -0,0 +1,212 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyStream streams "partial" and fails the first failures streams, the next ones stream the whole answer.
+type flakyStream struct {
+	*testsupport.FakeService
+	failures int
+
+	mu    sync.Mutex
+	opens int
+}
+
+func (f *flakyStream) ChatStream(ctx context.Context, messages []ai.Message, model string, opts ...ai.CallOption) (<-chan ai.StreamChunk, error) {
+	f.mu.Lock()
+	f.opens++
+	failed := f.opens <= f.failures
+	f.mu.Unlock()
+
+	ch := make(chan ai.StreamChunk, 2)
+	if failed {
+		ch <- ai.StreamChunk{Content: "partial "}
+		ch <- ai.StreamChunk{Err: ai.ErrProviderUnavailable}
+	} else {
+		ch <- ai.StreamChunk{Content: "whole answer"}
+	}
+	close(ch)
+	return ch, nil
+}
+
+// tracing records when it runs around next.
+func tracing(name string, trace *[]string) ai.Interceptor {
+	return func(ctx context.Context, req ai.Request, next ai.Invoker) (ai.Response, error) {
+		*trace = append(*trace, name+" before")
+		resp, err := next(ctx, req)
+		*trace = append(*trace, name+" after")
+		return resp, err
+	}
+}
+
+func collect(t *testing.T, as ai.Service) (string, error) {
+	t.Helper()
+	stream, err := as.ChatStream(context.Background(), []ai.Message{ai.UserMessage("hi")}, "")
+	if err != nil {
+		return "", err
+	}
+	return ai.CollectStream(stream)
+}
+
+func TestInterceptorOrder(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		var trace []string
+		fake := testsupport.NewFakeService()
+		fake.On(ai.User, "hi").ReplyFunc(func(ai.Request) (ai.Response, error) {
+			trace = append(trace, "service")
+			return ai.Response{Message: ai.AssistantMessage("hello"), FinishReason: ai.FinishReasonStop}, nil
+		})
+		as := ai.NewInterceptedService(fake, tracing("outer", &trace), tracing("inner", &trace))
+
+		var err error
+		if stream {
+			_, err = collect(t, as)
+		} else {
+			_, err = as.Chat(context.Background(), []ai.Message{ai.UserMessage("hi")})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{"outer before", "inner before", "service", "inner after", "outer after"}
+		if !reflect.DeepEqual(trace, want) {
+			t.Errorf("stream %t: trace = %v, want %v", stream, trace, want)
+		}
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	shortCircuit := func(ctx context.Context, req ai.Request, next ai.Invoker) (ai.Response, error) {
+		return ai.Response{Message: ai.AssistantMessage("from cache"), FinishReason: ai.FinishReasonStop}, nil
+	}
+	rewriteRequest := func(ctx context.Context, req ai.Request, next ai.Invoker) (ai.Response, error) {
+		req.Messages = append([]ai.Message{ai.SystemMessage("Be brief.")}, req.Messages...)
+		req.Model = "gpt-4o"
+		return next(ctx, req)
+	}
+	rewriteResponse := func(ctx context.Context, req ai.Request, next ai.Invoker) (ai.Response, error) {
+		resp, err := next(ctx, req)
+		resp.Message.Content = strings.ToUpper(resp.Message.Content)
+		return resp, err
+	}
+	failing := func(ctx context.Context, req ai.Request, next ai.Invoker) (ai.Response, error) {
+		return ai.Response{}, ai.ErrRateLimited
+	}
+
+	tests := []struct {
+		name        string
+		interceptor ai.Interceptor
+		want        string
+		wantStream  string
+		wantErr     error
+		wantCalls   int
+		wantModel   string
+		wantSystem  bool
+	}{
+		{name: "short circuit skips service", interceptor: shortCircuit, want: "from cache", wantStream: "from cache"},
+		{name: "rewritten request", interceptor: rewriteRequest, want: "hello there", wantStream: "hello there", wantCalls: 1, wantModel: "gpt-4o", wantSystem: true},
+		// streamed deltas reach the caller before the response is assembled
+		{name: "rewritten response", interceptor: rewriteResponse, want: "HELLO THERE", wantStream: "hello there", wantCalls: 1},
+		{name: "error", interceptor: failing, wantErr: ai.ErrRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := testsupport.NewFakeService()
+			fake.On(ai.User, "hi").Reply("hello there")
+			as := ai.NewInterceptedService(fake, tt.interceptor)
+
+			got, err := as.Chat(context.Background(), []ai.Message{ai.UserMessage("hi")})
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("chat = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+			streamed, err := collect(t, as)
+			if !errors.Is(err, tt.wantErr) || streamed != tt.wantStream {
+				t.Errorf("stream = %q, %v, want %q, %v", streamed, err, tt.wantStream, tt.wantErr)
+			}
+
+			calls := fake.Calls()
+			if len(calls) != 2*tt.wantCalls {
+				t.Fatalf("service calls = %d, want %d", len(calls), 2*tt.wantCalls)
+			}
+			for _, call := range calls {
+				if call.Model != tt.wantModel || (call.Messages[0].Role == ai.System) != tt.wantSystem {
+					t.Errorf("service request = %+v, want model %q with system message %t", call, tt.wantModel, tt.wantSystem)
+				}
+			}
+		})
+	}
+}
+
+func TestInterceptedStreamErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		retries     int
+		want        string
+		wantErr     error
+		wantSeenErr bool
+	}{
+		{name: "error chunk reaches caller and interceptor", failures: 1, wantErr: ai.ErrProviderUnavailable, wantSeenErr: true},
+		{name: "interceptor calling next again", failures: 3, retries: 3, want: "partial partial partial whole answer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seenErr error
+			retrying := func(ctx context.Context, req ai.Request, next ai.Invoker) (ai.Response, error) {
+				resp, err := next(ctx, req)
+				for attempt := 0; err != nil && attempt < tt.retries; attempt++ {
+					resp, err = next(ctx, req)
+				}
+				seenErr = err
+				return resp, err
+			}
+			as := ai.NewInterceptedService(&flakyStream{FakeService: testsupport.NewFakeService(), failures: tt.failures}, retrying)
+
+			type result struct {
+				answer string
+				err    error
+			}
+			done := make(chan result)
+			go func() {
+				answer, err := collect(t, as)
+				done <- result{answer, err}
+			}()
+			select {
+			case got := <-done:
+				if got.answer != tt.want || !errors.Is(got.err, tt.wantErr) {
+					t.Errorf("stream = %q, %v, want %q, %v", got.answer, got.err, tt.want, tt.wantErr)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("stream is blocked")
+			}
+			if (seenErr != nil) != tt.wantSeenErr {
+				t.Errorf("interceptor saw %v", seenErr)
+			}
+		})
+	}
+}
+
+func TestInterceptedStreamFailsToOpen(t *testing.T) {
+	fake := testsupport.NewFakeService()
+	fake.On(ai.User, "hi").ReplyError(ai.ErrAuth)
+	var trace []string
+	as := ai.NewInterceptedService(fake, tracing("outer", &trace))
+
+	if _, err := as.ChatStream(context.Background(), []ai.Message{ai.UserMessage("hi")}, ""); !errors.Is(err, ai.ErrAuth) {
+		t.Errorf("err = %v, want %v", err, ai.ErrAuth)
+	}
+	if len(trace) != 2 {
+		t.Errorf("trace = %v, want interceptor around the failed open", trace)
+	}
+}
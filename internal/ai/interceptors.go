@@ -0,0 +1,110 @@
+package ai
+
+import (
+	"context"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID tags AI calls made with the context, e.g. in logs of LoggingInterceptor.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func RequestIDFrom(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// LoggingInterceptor logs model, duration and usage of every call, prompts and answers only when logPrompts is set (dev).
+func LoggingInterceptor(logPrompts bool) Interceptor {
+	return func(ctx context.Context, req Request, next Invoker) (Response, error) {
+		requestID := RequestIDFrom(ctx)
+		if logPrompts {
+			for _, message := range req.Messages {
+				log.Printf("[%s] AI prompt %s: %s", requestID, message.Role, message.Content)
+			}
+		}
+
+		start := time.Now()
+		resp, err := next(ctx, req)
+		if err != nil {
+			log.Printf("[%s] AI call to %s failed after %s: %v", requestID, displayModel(req.Model), time.Since(start), err)
+			return resp, err
+		}
+
+		log.Printf("[%s] AI call to %s took %s, tokens: %d prompt, %d completion, stream: %t",
+			requestID, displayModel(resp.Model), time.Since(start), resp.Usage.PromptTokens, resp.Usage.CompletionTokens, IsStream(ctx))
+		if logPrompts {
+			log.Printf("[%s] AI answer: %s", requestID, resp.Message.Content)
+		}
+		return resp, nil
+	}
+}
+
+// TimingInterceptor reports duration of every call, e.g. to export latency metrics.
+func TimingInterceptor(observe func(ctx context.Context, model string, duration time.Duration, err error)) Interceptor {
+	return func(ctx context.Context, req Request, next Invoker) (Response, error) {
+		start := time.Now()
+		resp, err := next(ctx, req)
+		observe(ctx, req.Model, time.Since(start), err)
+		return resp, err
+	}
+}
+
+// DefaultSecretPatterns match common API keys and bearer tokens.
+var DefaultSecretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`sk-(?:ant-|or-|proj-)?[A-Za-z0-9_\-]{16,}`),
+	regexp.MustCompile(`fc-[a-f0-9]{32}`),
+	regexp.MustCompile(`AKIA[0-9A-Z]{16}`),
+	regexp.MustCompile(`(?i)bearer\s+[A-Za-z0-9._\-]{16,}`),
+}
+
+const redacted = "[REDACTED]"
+
+// RedactingInterceptor replaces secrets in outgoing messages, so they don't leave to the provider nor logs of later interceptors.
+func RedactingInterceptor(patterns ...*regexp.Regexp) Interceptor {
+	if len(patterns) == 0 {
+		patterns = DefaultSecretPatterns
+	}
+	redact := func(text string) string {
+		for _, pattern := range patterns {
+			text = pattern.ReplaceAllString(text, redacted)
+		}
+		return text
+	}
+
+	return func(ctx context.Context, req Request, next Invoker) (Response, error) {
+		messages := make([]Message, len(req.Messages))
+		for i, message := range req.Messages {
+			message.Content = redact(message.Content)
+			if len(message.Parts) > 0 {
+				parts := make([]ContentPart, len(message.Parts))
+				for j, part := range message.Parts {
+					if part.Type == ContentPartText {
+						part.Text = redact(part.Text)
+					}
+					parts[j] = part
+				}
+				message.Parts = parts
+			}
+			messages[i] = message
+		}
+		req.Messages = messages
+		return next(ctx, req)
+	}
+}
+
+// InterceptorsFromEnv redacts secrets and logs calls, AI_LOG_PROMPTS=true logs prompts and answers too.
+func InterceptorsFromEnv() []Interceptor {
+	logPrompts, _ := strconv.ParseBool(os.Getenv("AI_LOG_PROMPTS"))
+	return []Interceptor{
+		RedactingInterceptor(),
+		LoggingInterceptor(logPrompts),
+	}
+}
@@ -0,0 +1,64 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/models"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"github.com/TMateusz1/go-3rd-devs/internal/ratelimit"
+)
+
+// newLimiter shares limits of the provider account (base url and api key) between chat, embedding and audio clients.
+func newLimiter(provider, baseUrl, apiKey string, config option.RateLimitConfig) (*ratelimit.Limiter, error) {
+	key := fmt.Sprintf("%s|%s|%x", provider, baseUrl, sha256.Sum256([]byte(apiKey)))
+	limiter, err := ratelimit.Shared(key, config.MaxInFlight, config.RequestsPerMinute, config.TokensPerMinute)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit of %s account at %s: %w", provider, baseUrl, err)
+	}
+	return limiter, nil
+}
+
+// reservedTokens is the prompt and the longest answer of the request, the reservation is reconciled with its usage.
+// maxTokens is 0 when the request doesn't limit the answer, max output of the model is used then.
+func reservedTokens(req Request, model string, maxTokens int64) int {
+	if maxTokens == 0 {
+		if info, ok := models.Lookup(model); ok {
+			maxTokens = info.MaxOutput
+		}
+	}
+	return estimateRequestTokens(req) + int(maxTokens)
+}
+
+// estimateTokens is rough estimation (~4 characters per token) good enough for limits.
+func estimateTokens(text string) int {
+	return len(text)/4 + 1
+}
+
+func estimateRequestTokens(req Request) int {
+	tokens := 0
+	for _, message := range req.Messages {
+		tokens += estimateTokens(message.Content)
+		for _, part := range message.Parts {
+			tokens += estimatePartTokens(part)
+		}
+		for _, toolCall := range message.ToolCalls {
+			tokens += estimateTokens(toolCall.Arguments)
+		}
+	}
+	return tokens
+}
+
+// estimatePartTokens counts images as OpenAI does for one 512px tile (low) or a typical 1024px image.
+func estimatePartTokens(part ContentPart) int {
+	switch part.Type {
+	case ContentPartText:
+		return estimateTokens(part.Text)
+	case ContentPartImageURL, ContentPartImageBase64:
+		if part.Detail == ImageDetailLow {
+			return 85
+		}
+		return 765
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,93 @@
+package ai
+
+import (
+	"context"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenaiClientsShareLimiter(t *testing.T) {
+	account := []option.Option{option.WithApiKey("key"), option.WithBaseUrl("http://limit.test/v1"), option.WithRateLimit(2, 60, 0)}
+
+	chat, err := NewOpenaiService(account...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedding, err := NewOpenaiEmbeddingService(account...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audio, err := NewOpenaiAudioService(account...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewOpenaiService(option.WithApiKey("other-key"), option.WithBaseUrl("http://limit.test/v1"), option.WithRateLimit(2, 60, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limiter := chat.(*openaiService).limiter
+	if embedding.(*openaiEmbeddingService).limiter != limiter || audio.(*openaiAudioService).limiter != limiter {
+		t.Error("clients of one account have separate limiters")
+	}
+	if other.(*openaiService).limiter == limiter {
+		t.Error("another account shares the limiter")
+	}
+}
+
+func TestOpenaiClientsOfAccountNeedSameLimits(t *testing.T) {
+	if _, err := NewOpenaiService(option.WithApiKey("key"), option.WithBaseUrl("http://limits.test/v1"), option.WithRateLimit(2, 60, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewOpenaiEmbeddingService(option.WithApiKey("key"), option.WithBaseUrl("http://limits.test/v1"), option.WithRateLimit(4, 60, 0)); err == nil {
+		t.Error("client of the account with other limits: want error")
+	}
+}
+
+func TestReservedTokens(t *testing.T) {
+	req := Request{Messages: []Message{UserMessage(strings.Repeat("a", 400))}}
+	tests := []struct {
+		name      string
+		model     string
+		maxTokens int64
+		want      int
+	}{
+		{name: "max tokens of request", model: "gpt-4o-mini", maxTokens: 100, want: 201},
+		{name: "max output of model", model: "gpt-4o-mini", want: 101 + 16_384},
+		{name: "unknown model", model: "mistral", want: 101},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reservedTokens(req, tt.model, tt.maxTokens); got != tt.want {
+				t.Errorf("reserved tokens = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenaiReconcilesReservedTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices": [{"index": 0, "message": {"role": "assistant", "content": "ok"}, "finish_reason": "stop"}], "usage": {"prompt_tokens": 10, "completion_tokens": 10, "total_tokens": 20}}`))
+	}))
+	defer server.Close()
+	as, err := NewOpenaiService(option.WithApiKey("key"), option.WithBaseUrl(server.URL), option.WithRateLimit(0, 0, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every call reserves most of the minute limit, so the next one only fits when unused tokens are given back
+	for i := range 5 {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		_, err := as.Chat(ctx, []Message{UserMessage("hi")}, WithMaxTokens(900))
+		cancel()
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+}
@@ -1,16 +1,90 @@
 package ai
 
+import (
+	"encoding/base64"
+	"fmt"
+)
+
 type Role string
 
 const (
 	System    Role = "system"
 	User      Role = "user"
 	Assistant Role = "assistant"
+	Tool      Role = "tool"
 )
 
 type Message struct {
 	Role    Role
 	Content string
+	// Parts carry images of multimodal user messages, Content is sent as the first text part.
+	Parts []ContentPart
+	// ToolCalls are requested by the assistant, ToolCallID points which call the tool message answers.
+	ToolCalls  []ToolCall
+	ToolCallID string
+}
+
+type ContentPartType string
+
+const (
+	ContentPartText        ContentPartType = "text"
+	ContentPartImageURL    ContentPartType = "image_url"
+	ContentPartImageBase64 ContentPartType = "image_base64"
+)
+
+type ImageDetail string
+
+const (
+	ImageDetailAuto ImageDetail = "auto"
+	ImageDetailLow  ImageDetail = "low"
+	ImageDetailHigh ImageDetail = "high"
+)
+
+type ContentPart struct {
+	Type ContentPartType
+	Text string
+	// ImageURL is set for image_url parts.
+	ImageURL string
+	// MediaType and Data (base64 encoded) are set for image_base64 parts.
+	MediaType string
+	Data      string
+	// Detail is used only by OpenAI, empty means auto.
+	Detail ImageDetail
+}
+
+func TextPart(text string) ContentPart {
+	return ContentPart{
+		Type: ContentPartText,
+		Text: text,
+	}
+}
+
+func ImageURLPart(url string, detail ImageDetail) ContentPart {
+	return ContentPart{
+		Type:     ContentPartImageURL,
+		ImageURL: url,
+		Detail:   detail,
+	}
+}
+
+// ImageBase64Part encodes the image, mediaType is e.g. image/png.
+func ImageBase64Part(mediaType string, image []byte, detail ImageDetail) ContentPart {
+	return ContentPart{
+		Type:      ContentPartImageBase64,
+		MediaType: mediaType,
+		Data:      base64.StdEncoding.EncodeToString(image),
+		Detail:    detail,
+	}
+}
+
+func (p ContentPart) dataURL() string {
+	return fmt.Sprintf("data:%s;base64,%s", p.MediaType, p.Data)
+}
+
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
 }
 
 func UserMessage(content string) Message {
@@ -20,6 +94,14 @@ func UserMessage(content string) Message {
 	}
 }
 
+// UserPartsMessage is a multimodal user message, e.g. a question with screenshots.
+func UserPartsMessage(parts ...ContentPart) Message {
+	return Message{
+		Role:  User,
+		Parts: parts,
+	}
+}
+
 func SystemMessage(content string) Message {
 	return Message{
 		Role:    System,
@@ -33,3 +115,27 @@ func AssistantMessage(content string) Message {
 		Content: content,
 	}
 }
+
+func AssistantToolCallsMessage(content string, toolCalls []ToolCall) Message {
+	return Message{
+		Role:      Assistant,
+		Content:   content,
+		ToolCalls: toolCalls,
+	}
+}
+
+func ToolMessage(toolCallID string, content string) Message {
+	return Message{
+		Role:       Tool,
+		Content:    content,
+		ToolCallID: toolCallID,
+	}
+}
+
+// contentParts returns Content followed by Parts.
+func (m Message) contentParts() []ContentPart {
+	if m.Content == "" {
+		return m.Parts
+	}
+	return append([]ContentPart{TextPart(m.Content)}, m.Parts...)
+}
@@ -0,0 +1,51 @@
+package models
+
+var builtin = []Model{
+	{
+		Name: "gpt-3.5-turbo", Provider: ProviderOpenai, ContextWindow: 16_385, MaxOutput: 4_096, Encoding: "cl100k_base",
+		Tools: true, Streaming: true,
+		Pricing: Pricing{Input: 0.50, CachedInput: 0.50, Output: 1.50},
+	},
+	{
+		Name: "gpt-4o-mini", Provider: ProviderOpenai, ContextWindow: 128_000, MaxOutput: 16_384, Encoding: "o200k_base",
+		Tools: true, JSONSchema: true, Vision: true, Streaming: true,
+		Pricing: Pricing{Input: 0.15, CachedInput: 0.075, Output: 0.60},
+	},
+	{
+		Name: "openai/gpt-4o-mini", Provider: ProviderOpenRouter, ContextWindow: 128_000, MaxOutput: 16_384, Encoding: "o200k_base",
+		Tools: true, JSONSchema: true, Vision: true, Streaming: true,
+		Pricing: Pricing{Input: 0.15, CachedInput: 0.075, Output: 0.60},
+	},
+	{
+		Name: "openai/gpt-4.1-nano", Provider: ProviderOpenRouter, ContextWindow: 1_047_576, MaxOutput: 32_768, Encoding: "o200k_base",
+		Tools: true, JSONSchema: true, Vision: true, Streaming: true,
+		Pricing: Pricing{Input: 0.10, CachedInput: 0.025, Output: 0.40},
+	},
+	{
+		Name: "google/gemini-2.5-flash-preview", Provider: ProviderOpenRouter, ContextWindow: 1_048_576, MaxOutput: 65_535,
+		Tools: true, JSONSchema: true, Vision: true, Streaming: true,
+		Pricing: Pricing{Input: 0.15, CachedInput: 0.0375, Output: 0.60},
+	},
+	{
+		Name: "claude-sonnet-4-20250514", Provider: ProviderAnthropic, ContextWindow: 200_000, MaxOutput: 64_000,
+		Tools: true, Vision: true, Streaming: true,
+		Pricing: Pricing{Input: 3, CachedInput: 0.30, Output: 15},
+	},
+	{
+		Name: "claude-3-5-haiku-latest", Provider: ProviderAnthropic, ContextWindow: 200_000, MaxOutput: 8_192,
+		Tools: true, Vision: true, Streaming: true,
+		Pricing: Pricing{Input: 0.80, CachedInput: 0.08, Output: 4},
+	},
+	{
+		Name: "llama3.2", Provider: ProviderOllama, ContextWindow: 131_072,
+		Tools: true, JSONSchema: true, Streaming: true,
+	},
+	{
+		Name: "text-embedding-3-small", Provider: ProviderOpenai, ContextWindow: 8_191, Embedding: true, Encoding: "cl100k_base",
+		Pricing: Pricing{Input: 0.02},
+	},
+	{
+		Name: "text-embedding-3-large", Provider: ProviderOpenai, ContextWindow: 8_191, Embedding: true, Encoding: "cl100k_base",
+		Pricing: Pricing{Input: 0.13},
+	},
+}
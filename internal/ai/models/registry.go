@@ -0,0 +1,231 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const (
+	ProviderOpenai     = "openai"
+	ProviderOpenRouter = "openrouter"
+	ProviderAnthropic  = "anthropic"
+	ProviderOllama     = "ollama"
+)
+
+// Pricing is in USD per million tokens.
+type Pricing struct {
+	Input       float64 `json:"input"`
+	CachedInput float64 `json:"cached_input"`
+	Output      float64 `json:"output"`
+}
+
+type Model struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	// ContextWindow and MaxOutput are in tokens, 0 means unknown.
+	ContextWindow int64 `json:"context_window"`
+	MaxOutput     int64 `json:"max_output"`
+	// Encoding is the BPE encoding of OpenAI models (cl100k_base, o200k_base), others are counted approximately with cl100k_base.
+	Encoding string `json:"encoding"`
+
+	Tools      bool `json:"tools"`
+	JSONSchema bool `json:"json_schema"`
+	Vision     bool `json:"vision"`
+	Streaming  bool `json:"streaming"`
+	Embedding  bool `json:"embedding"`
+
+	Pricing Pricing `json:"pricing"`
+}
+
+// Registry describes known models, models missing in it are allowed unless it's strict.
+type Registry struct {
+	mu     sync.RWMutex
+	models map[string]Model
+	strict bool
+}
+
+type registryFile struct {
+	Strict *bool   `json:"strict"`
+	Models []Model `json:"models"`
+}
+
+func NewRegistry(models ...Model) (*Registry, error) {
+	r := &Registry{
+		models: map[string]Model{},
+	}
+	for _, model := range models {
+		err := r.Register(model)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+var (
+	defaultRegistry    *Registry
+	defaultRegistryErr error
+	defaultOnce        sync.Once
+)
+
+// Default is the registry of builtin models extended by AI_MODELS_FILE, AI_MODELS_STRICT=true rejects unknown models.
+// On file error the registry with builtin models is returned together with the error.
+func Default() (*Registry, error) {
+	defaultOnce.Do(func() {
+		defaultRegistry, defaultRegistryErr = NewRegistry(builtin...)
+		if defaultRegistryErr != nil {
+			return
+		}
+
+		path, ok := os.LookupEnv("AI_MODELS_FILE")
+		if ok && path != "" {
+			defaultRegistryErr = defaultRegistry.LoadFile(path)
+		}
+
+		strict, ok := os.LookupEnv("AI_MODELS_STRICT")
+		if ok {
+			parsed, err := strconv.ParseBool(strict)
+			if err == nil {
+				defaultRegistry.SetStrict(parsed)
+			}
+		}
+	})
+	return defaultRegistry, defaultRegistryErr
+}
+
+// Lookup in the default registry.
+func Lookup(name string) (Model, bool) {
+	registry, _ := Default()
+	return registry.Lookup(name)
+}
+
+// Register adds the model or replaces the one with the same name.
+func (r *Registry) Register(model Model) error {
+	if model.Name == "" || model.Provider == "" {
+		return fmt.Errorf("model must have name and provider")
+	}
+	if model.ContextWindow < 0 || model.MaxOutput < 0 {
+		return fmt.Errorf("model %s: context window and max output can't be negative", model.Name)
+	}
+	if model.ContextWindow > 0 && model.MaxOutput > model.ContextWindow {
+		return fmt.Errorf("model %s: max output can't exceed context window", model.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.models[model.Name] = model
+	return nil
+}
+
+// LoadFile registers models of JSON file {"strict": false, "models": [{"name": "...", "provider": "openai", ...}]}.
+func (r *Registry) LoadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading models file: %w", err)
+	}
+
+	var file registryFile
+	err = json.Unmarshal(content, &file)
+	if err != nil {
+		return fmt.Errorf("decoding models file %s: %w", path, err)
+	}
+
+	for _, model := range file.Models {
+		err = r.Register(model)
+		if err != nil {
+			return fmt.Errorf("models file %s: %w", path, err)
+		}
+	}
+	if file.Strict != nil {
+		r.SetStrict(*file.Strict)
+	}
+	return nil
+}
+
+func (r *Registry) SetStrict(strict bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.strict = strict
+}
+
+func (r *Registry) Lookup(name string) (Model, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	model, ok := r.models[name]
+	return model, ok
+}
+
+// All returns models sorted by name.
+func (r *Registry) All() []Model {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]Model, 0, len(r.models))
+	for _, model := range r.models {
+		result = append(result, model)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Validate checks the model can be used as a chat model of the provider service.
+func (r *Registry) Validate(provider, name string) error {
+	model, ok := r.Lookup(name)
+	if !ok {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		if r.strict {
+			return fmt.Errorf("unknown model %s", name)
+		}
+		return nil
+	}
+	if model.Embedding {
+		return fmt.Errorf("model %s is an embedding model", name)
+	}
+	if !servedBy(provider, model.Provider) {
+		return fmt.Errorf("model %s is provided by %s, not %s", name, model.Provider, provider)
+	}
+	return nil
+}
+
+// ValidateEmbedding checks the model can be used by the embedding service of the provider.
+func (r *Registry) ValidateEmbedding(provider, name string) error {
+	model, ok := r.Lookup(name)
+	if !ok {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		if r.strict {
+			return fmt.Errorf("unknown embedding model %s", name)
+		}
+		return nil
+	}
+	if !model.Embedding {
+		return fmt.Errorf("model %s isn't an embedding model", name)
+	}
+	if !servedBy(provider, model.Provider) {
+		return fmt.Errorf("model %s is provided by %s, not %s", name, model.Provider, provider)
+	}
+	return nil
+}
+
+// ValidateMaxTokens checks the default max tokens of a config against the known max output.
+func (r *Registry) ValidateMaxTokens(name string, maxTokens int64) error {
+	model, ok := r.Lookup(name)
+	if !ok || model.MaxOutput == 0 || maxTokens <= model.MaxOutput {
+		return nil
+	}
+	return fmt.Errorf("max tokens %d exceeds max output %d of model %s", maxTokens, model.MaxOutput, name)
+}
+
+// servedBy tells if the service of the provider can call models of modelProvider, openai service talks to OpenRouter too.
+func servedBy(provider, modelProvider string) bool {
+	if provider == ProviderOpenai {
+		return modelProvider == ProviderOpenai || modelProvider == ProviderOpenRouter
+	}
+	return provider == modelProvider
+}
@@ -0,0 +1,172 @@
+package models_test
+
+import (
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// builtinRegistry returns a copy of builtin models, so tests can change it.
+func builtinRegistry(t *testing.T) *models.Registry {
+	t.Helper()
+	defaults, err := models.Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := models.NewRegistry(defaults.All()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+func writeModelsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "models.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRegistryValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		strict    bool
+		provider  string
+		model     string
+		embedding bool
+		wantErr   string
+	}{
+		{name: "known", provider: models.ProviderOpenai, model: "gpt-4o-mini"},
+		{name: "openrouter model of openai service", provider: models.ProviderOpenai, model: "openai/gpt-4o-mini"},
+		{name: "model of another provider", provider: models.ProviderAnthropic, model: "gpt-4o-mini", wantErr: "provided by openai, not anthropic"},
+		{name: "embedding model as chat model", provider: models.ProviderOpenai, model: "text-embedding-3-small", wantErr: "is an embedding model"},
+		{name: "unknown", provider: models.ProviderOllama, model: "mistral"},
+		{name: "unknown in strict mode", strict: true, provider: models.ProviderOllama, model: "mistral", wantErr: "unknown model mistral"},
+		{name: "known in strict mode", strict: true, provider: models.ProviderAnthropic, model: "claude-3-5-haiku-latest"},
+		{name: "embedding", embedding: true, provider: models.ProviderOpenai, model: "text-embedding-3-large"},
+		{name: "chat model as embedding model", embedding: true, provider: models.ProviderOpenai, model: "gpt-4o-mini", wantErr: "isn't an embedding model"},
+		{name: "embedding model of another provider", embedding: true, provider: models.ProviderOllama, model: "text-embedding-3-small", wantErr: "provided by openai, not ollama"},
+		{name: "unknown embedding", embedding: true, provider: models.ProviderOllama, model: "nomic-embed-text"},
+		{name: "unknown embedding in strict mode", strict: true, embedding: true, provider: models.ProviderOllama, model: "nomic-embed-text", wantErr: "unknown embedding model"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := builtinRegistry(t)
+			registry.SetStrict(tt.strict)
+
+			validate := registry.Validate
+			if tt.embedding {
+				validate = registry.ValidateEmbedding
+			}
+			err := validate(tt.provider, tt.model)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("err = %v, want none", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistryValidateMaxTokens(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     string
+		maxTokens int64
+		wantErr   bool
+	}{
+		{name: "within max output", model: "claude-3-5-haiku-latest", maxTokens: 8_192},
+		{name: "over max output", model: "claude-3-5-haiku-latest", maxTokens: 8_193, wantErr: true},
+		{name: "unknown max output", model: "llama3.2", maxTokens: 1_000_000},
+		{name: "unknown model", model: "mistral", maxTokens: 1_000_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := builtinRegistry(t).ValidateMaxTokens(tt.model, tt.maxTokens)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistryLoadFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantErr     string
+		wantModel   string
+		wantWindow  int64
+		wantStrict  bool
+		wantBuiltin bool
+	}{
+		{
+			name:        "new model",
+			content:     `{"models": [{"name": "mistral", "provider": "ollama", "context_window": 32000}]}`,
+			wantModel:   "mistral",
+			wantWindow:  32_000,
+			wantBuiltin: true,
+		},
+		{
+			name:        "overrides builtin",
+			content:     `{"models": [{"name": "gpt-4o-mini", "provider": "openai", "context_window": 64000, "pricing": {"input": 1}}]}`,
+			wantModel:   "gpt-4o-mini",
+			wantWindow:  64_000,
+			wantBuiltin: true,
+		},
+		{
+			name:        "strict",
+			content:     `{"strict": true, "models": []}`,
+			wantStrict:  true,
+			wantBuiltin: true,
+		},
+		{name: "malformed JSON", content: `{"models": [`, wantErr: "decoding models file"},
+		{name: "invalid model", content: `{"models": [{"name": "mistral"}]}`, wantErr: "must have name and provider"},
+		{name: "max output over context window", content: `{"models": [{"name": "mistral", "provider": "ollama", "context_window": 10, "max_output": 20}]}`, wantErr: "can't exceed context window"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := builtinRegistry(t)
+
+			err := registry.LoadFile(writeModelsFile(t, tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantModel != "" {
+				model, ok := registry.Lookup(tt.wantModel)
+				if !ok || model.ContextWindow != tt.wantWindow {
+					t.Errorf("model = %+v, %t, want context window %d", model, ok, tt.wantWindow)
+				}
+			}
+			if _, ok := registry.Lookup("claude-sonnet-4-20250514"); ok != tt.wantBuiltin {
+				t.Errorf("builtin model kept = %t", ok)
+			}
+			strictErr := registry.Validate(models.ProviderOllama, "unknown-model")
+			if (strictErr != nil) != tt.wantStrict {
+				t.Errorf("unknown model err = %v, want strict %t", strictErr, tt.wantStrict)
+			}
+		})
+	}
+}
+
+func TestRegistryLoadMissingFile(t *testing.T) {
+	err := builtinRegistry(t).LoadFile(filepath.Join(t.TempDir(), "missing.json"))
+	if err == nil || !strings.Contains(err.Error(), "reading models file") {
+		t.Errorf("err = %v, want reading error", err)
+	}
+}
@@ -0,0 +1,428 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"github.com/TMateusz1/go-3rd-devs/internal/ratelimit"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const ollamaStartupTimeout = 10 * time.Second
+
+type ollamaService struct {
+	c       http.Client
+	config  *option.OllamaConfig
+	limiter *ratelimit.Limiter
+
+	mu     sync.RWMutex
+	models []string
+}
+
+// NewOllamaService talks to Ollama native /api/chat, it fails when the server is down or doesn't have the default model.
+func NewOllamaService(opts ...option.OllamaOption) (Service, error) {
+	config, err := option.NewOllamaConfig(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ollama service: %w", err)
+	}
+	limiter, err := newLimiter(ProviderOllama, config.BaseUrl, "", config.RateLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ollama service: %w", err)
+	}
+
+	o := &ollamaService{
+		c: http.Client{
+			Timeout: 10 * time.Minute,
+		},
+		config:  config,
+		limiter: limiter,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ollamaStartupTimeout)
+	defer cancel()
+	err = o.checkModel(ctx, config.DefaultModel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ollama service: %w", err)
+	}
+
+	return o, nil
+}
+
+func (o *ollamaService) Chat(ctx context.Context, messages []Message, opts ...CallOption) (string, error) {
+	return o.ChatWithModel(ctx, messages, o.config.DefaultModel, opts...)
+}
+
+func (o *ollamaService) DefaultModels() []ServiceModel {
+	return []ServiceModel{{Provider: ProviderOllama, Model: o.config.DefaultModel}}
+}
+
+func (o *ollamaService) ChatWithModel(ctx context.Context, messages []Message, model string, opts ...CallOption) (string, error) {
+	return AnswerContent(o.Complete(ctx, Request{
+		Model:    model,
+		Messages: messages,
+		Params:   NewGenerationParams(opts...),
+	}))
+}
+
+func (o *ollamaService) Complete(ctx context.Context, req Request) (Response, error) {
+	body, err := o.newRequest(ctx, req)
+	if err != nil {
+		return Response{}, err
+	}
+	reservation, err := o.limiter.Acquire(ctx, reservedTokens(req, body.Model, ollamaMaxTokens(body)))
+	if err != nil {
+		return Response{}, fmt.Errorf("waiting for rate limit: %w", err)
+	}
+	defer reservation.Release()
+
+	httpResp, err := o.send(ctx, body)
+	if err != nil {
+		return Response{}, err
+	}
+	defer httpResp.Body.Close()
+
+	var resp ollamaChatResponse
+	err = json.NewDecoder(httpResp.Body).Decode(&resp)
+	if err != nil {
+		return Response{}, fmt.Errorf("decode ollama response: %w", err)
+	}
+	if resp.Error != "" {
+		return Response{}, fmt.Errorf("ollama error: %s", resp.Error)
+	}
+
+	usage := Usage{
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+	}
+	recordUsage(ctx, body.Model, usage)
+	reservation.Reconcile(int(usage.TotalTokens()))
+
+	message := AssistantMessage(resp.Message.Content)
+	for i, toolCall := range resp.Message.ToolCalls {
+		// ollama doesn't identify tool calls, so ids are only generated to keep Message contract
+		message.ToolCalls = append(message.ToolCalls, ToolCall{
+			ID:        fmt.Sprintf("call_%d", i),
+			Name:      toolCall.Function.Name,
+			Arguments: string(toolCall.Function.Arguments),
+		})
+	}
+
+	finishReason := mapOllamaDoneReason(resp.DoneReason)
+	if len(message.ToolCalls) > 0 {
+		finishReason = FinishReasonToolCalls
+	}
+
+	return Response{
+		Message:      message,
+		Model:        resp.Model,
+		FinishReason: finishReason,
+		Usage:        usage,
+	}, nil
+}
+
+func (o *ollamaService) ChatStream(ctx context.Context, messages []Message, model string, opts ...CallOption) (<-chan StreamChunk, error) {
+	req := Request{
+		Model:    model,
+		Messages: messages,
+		Params:   NewGenerationParams(opts...),
+	}
+	body, err := o.newRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStreaming(body.Model); err != nil {
+		return nil, err
+	}
+	body.Stream = true
+
+	reservation, err := o.limiter.Acquire(ctx, reservedTokens(req, body.Model, ollamaMaxTokens(body)))
+	if err != nil {
+		return nil, fmt.Errorf("waiting for rate limit: %w", err)
+	}
+	httpResp, err := o.send(ctx, body)
+	if err != nil {
+		reservation.Release()
+		return nil, err
+	}
+
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(ch)
+		defer reservation.Release()
+		defer httpResp.Body.Close()
+
+		send := func(chunk StreamChunk) bool {
+			select {
+			case ch <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		content := strings.Builder{}
+		decoder := json.NewDecoder(httpResp.Body)
+		for {
+			var resp ollamaChatResponse
+			err := decoder.Decode(&resp)
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				send(StreamChunk{Err: fmt.Errorf("failed to stream message from AI: %w", err)})
+				return
+			}
+			if resp.Error != "" {
+				send(StreamChunk{Err: fmt.Errorf("ollama error: %s", resp.Error)})
+				return
+			}
+			content.WriteString(resp.Message.Content)
+			if resp.Message.Content != "" && !send(StreamChunk{Content: resp.Message.Content}) {
+				return
+			}
+			if resp.Done {
+				usage := Usage{
+					PromptTokens:     resp.PromptEvalCount,
+					CompletionTokens: resp.EvalCount,
+				}
+				recordUsage(ctx, body.Model, usage)
+				reservation.Reconcile(int(usage.TotalTokens()))
+				if err := checkFinishReason(Response{
+					Message:      AssistantMessage(content.String()),
+					Model:        body.Model,
+					FinishReason: mapOllamaDoneReason(resp.DoneReason),
+				}); err != nil {
+					send(StreamChunk{Err: err})
+				}
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+func (o *ollamaService) listModels(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.url("/api/tags"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := o.c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("listing ollama models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("listing ollama models: response code %d", resp.StatusCode)
+	}
+
+	var tags ollamaTagsResponse
+	err = json.NewDecoder(resp.Body).Decode(&tags)
+	if err != nil {
+		return nil, fmt.Errorf("decode ollama models: %w", err)
+	}
+
+	var models []string
+	for _, model := range tags.Models {
+		models = append(models, model.Name)
+	}
+	return models, nil
+}
+
+// checkModel refreshes pulled models when the model isn't known yet, so models pulled after startup can be used.
+func (o *ollamaService) checkModel(ctx context.Context, model string) error {
+	if o.hasModel(model) {
+		return nil
+	}
+
+	models, err := o.listModels(ctx)
+	if err != nil {
+		return err
+	}
+	o.mu.Lock()
+	o.models = models
+	o.mu.Unlock()
+
+	if !o.hasModel(model) {
+		return fmt.Errorf("model %q is not available, pulled models: %s", model, strings.Join(models, ", "))
+	}
+	return nil
+}
+
+// hasModel accepts names without tag, e.g. "llama3.2" matches pulled "llama3.2:latest".
+func (o *ollamaService) hasModel(model string) bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	for _, available := range o.models {
+		if available == model || available == model+":latest" {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *ollamaService) send(ctx context.Context, body ollamaChatRequest) (*http.Response, error) {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling ollama request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url("/api/chat"), bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send message to AI: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return nil, newStatusError(resp, fmt.Errorf("ollama response code %d: %s", resp.StatusCode, strings.TrimSpace(string(body))))
+	}
+	return resp, nil
+}
+
+func (o *ollamaService) url(path string) string {
+	return strings.TrimSuffix(o.config.BaseUrl, "/") + path
+}
+
+func (o *ollamaService) newRequest(ctx context.Context, req Request) (ollamaChatRequest, error) {
+	model := req.Model
+	if model == "" {
+		model = o.config.DefaultModel
+	}
+	if err := o.checkModel(ctx, model); err != nil {
+		return ollamaChatRequest{}, err
+	}
+	if err := checkCapabilities(model, req); err != nil {
+		return ollamaChatRequest{}, err
+	}
+
+	messages, err := mapMessagesToOllamaMessages(req.Messages)
+	if err != nil {
+		return ollamaChatRequest{}, err
+	}
+
+	body := ollamaChatRequest{
+		Model:    model,
+		Messages: messages,
+		Options:  mapParamsToOllamaOptions(req.Params),
+	}
+	for _, definition := range req.Tools {
+		tool := ollamaTool{Type: "function"}
+		tool.Function.Name = definition.Name
+		tool.Function.Description = definition.Description
+		tool.Function.Parameters = definition.Parameters
+		body.Tools = append(body.Tools, tool)
+	}
+	if req.ResponseFormat != nil {
+		body.Format = req.ResponseFormat.Schema
+	}
+	return body, nil
+}
+
+func mapMessagesToOllamaMessages(messages []Message) ([]ollamaMessage, error) {
+	toolNames := map[string]string{}
+	var result []ollamaMessage
+
+	for _, message := range messages {
+		switch message.Role {
+		case System:
+			result = append(result, ollamaMessage{Role: string(message.Role), Content: message.Content})
+		case User:
+			ollamaMsg, err := mapUserMessageToOllamaMessage(message)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, ollamaMsg)
+		case Assistant:
+			ollamaMsg := ollamaMessage{Role: string(Assistant), Content: message.Content}
+			for _, toolCall := range message.ToolCalls {
+				toolNames[toolCall.ID] = toolCall.Name
+				arguments := toolCall.Arguments
+				if strings.TrimSpace(arguments) == "" {
+					arguments = "{}"
+				}
+				call := ollamaToolCall{}
+				call.Function.Name = toolCall.Name
+				call.Function.Arguments = json.RawMessage(arguments)
+				ollamaMsg.ToolCalls = append(ollamaMsg.ToolCalls, call)
+			}
+			result = append(result, ollamaMsg)
+		case Tool:
+			result = append(result, ollamaMessage{
+				Role:     string(Tool),
+				Content:  message.Content,
+				ToolName: toolNames[message.ToolCallID],
+			})
+		default:
+			return nil, fmt.Errorf("unknown role: %s", message.Role)
+		}
+	}
+
+	return result, nil
+}
+
+// mapUserMessageToOllamaMessage joins text parts, Ollama takes images only base64 encoded.
+func mapUserMessageToOllamaMessage(message Message) (ollamaMessage, error) {
+	ollamaMsg := ollamaMessage{Role: string(User), Content: message.Content}
+	if len(message.Parts) == 0 {
+		return ollamaMsg, nil
+	}
+
+	var texts []string
+	for _, part := range message.contentParts() {
+		switch part.Type {
+		case ContentPartText:
+			texts = append(texts, part.Text)
+		case ContentPartImageBase64:
+			ollamaMsg.Images = append(ollamaMsg.Images, part.Data)
+		case ContentPartImageURL:
+			return ollamaMessage{}, fmt.Errorf("ollama doesn't support image urls, send the image base64 encoded")
+		default:
+			return ollamaMessage{}, fmt.Errorf("unknown content part type: %s", part.Type)
+		}
+	}
+	ollamaMsg.Content = strings.Join(texts, "\n")
+	return ollamaMsg, nil
+}
+
+func mapParamsToOllamaOptions(params GenerationParams) *ollamaOptions {
+	if params.Temperature == nil && params.TopP == nil && params.Seed == nil && params.MaxTokens == nil && len(params.Stop) == 0 {
+		return nil
+	}
+	return &ollamaOptions{
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+		Seed:        params.Seed,
+		Stop:        params.Stop,
+		NumPredict:  params.MaxTokens,
+	}
+}
+
+// ollamaMaxTokens is num_predict of the request, 0 when it isn't limited.
+func ollamaMaxTokens(body ollamaChatRequest) int64 {
+	if body.Options == nil || body.Options.NumPredict == nil {
+		return 0
+	}
+	return *body.Options.NumPredict
+}
+
+func mapOllamaDoneReason(doneReason string) FinishReason {
+	switch doneReason {
+	case "length":
+		return FinishReasonLength
+	default:
+		return FinishReasonStop
+	}
+}
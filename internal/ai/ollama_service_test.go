@@ -0,0 +1,179 @@
+package ai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// ollamaServer stands in for Ollama API, pulled models can be changed while the service runs.
+type ollamaServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	pulled   []string
+	tagCalls int
+	requests []map[string]any
+	answer   string
+}
+
+func newOllamaServer(t *testing.T, answer string, pulled ...string) *ollamaServer {
+	t.Helper()
+	s := &ollamaServer{pulled: pulled, answer: answer}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/api/tags":
+			s.tagCalls++
+			var models []map[string]string
+			for _, name := range s.pulled {
+				models = append(models, map[string]string{"name": name, "model": name})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"models": models})
+		case "/api/chat":
+			var request map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				t.Errorf("decoding request: %v", err)
+			}
+			s.requests = append(s.requests, request)
+			_, _ = w.Write([]byte(s.answer))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *ollamaServer) pull(model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pulled = append(s.pulled, model)
+}
+
+func TestOllamaComplete(t *testing.T) {
+	tests := []struct {
+		name      string
+		answer    string
+		want      string
+		wantTools int
+		wantErr   error
+	}{
+		{
+			name:   "answer",
+			answer: `{"model": "llama3.2", "message": {"role": "assistant", "content": "Hello"}, "done": true, "done_reason": "stop", "prompt_eval_count": 8, "eval_count": 2}`,
+			want:   "Hello",
+		},
+		{
+			name:      "tool call",
+			answer:    `{"model": "llama3.2", "message": {"role": "assistant", "content": "", "tool_calls": [{"function": {"name": "weather", "arguments": {"city": "Gdańsk"}}}]}, "done": true}`,
+			wantTools: 1,
+		},
+		{
+			name:    "truncated",
+			answer:  `{"model": "llama3.2", "message": {"role": "assistant", "content": "Once"}, "done": true, "done_reason": "length"}`,
+			wantErr: ai.ErrTruncated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newOllamaServer(t, tt.answer, "llama3.2:latest")
+			as, err := ai.NewOllamaService(option.WithOllamaBaseUrl(server.URL), option.WithOllamaBaseModel("llama3.2"))
+			if err != nil {
+				t.Fatalf("creating service: %v", err)
+			}
+
+			resp, err := as.Complete(context.Background(), ai.Request{Messages: []ai.Message{ai.UserMessage("hi")}})
+			if err == nil {
+				_, err = ai.AnswerContent(resp, err)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("complete: %v", err)
+			}
+			if resp.Message.Content != tt.want || len(resp.Message.ToolCalls) != tt.wantTools {
+				t.Errorf("message = %+v, want content %q and %d tool calls", resp.Message, tt.want, tt.wantTools)
+			}
+			if server.requests[0]["model"] != "llama3.2" || server.requests[0]["stream"] != false {
+				t.Errorf("request = %v", server.requests[0])
+			}
+		})
+	}
+}
+
+func TestOllamaRefreshesModels(t *testing.T) {
+	server := newOllamaServer(t, `{"model": "qwen3", "message": {"role": "assistant", "content": "ok"}, "done": true}`, "llama3.2:latest")
+	as, err := ai.NewOllamaService(option.WithOllamaBaseUrl(server.URL), option.WithOllamaBaseModel("llama3.2"))
+	if err != nil {
+		t.Fatalf("creating service: %v", err)
+	}
+
+	_, err = as.ChatWithModel(context.Background(), []ai.Message{ai.UserMessage("hi")}, "qwen3")
+	if err == nil || !strings.Contains(err.Error(), `"qwen3" is not available`) {
+		t.Fatalf("err = %v, want model not available", err)
+	}
+
+	server.pull("qwen3:latest")
+	answer, err := as.ChatWithModel(context.Background(), []ai.Message{ai.UserMessage("hi")}, "qwen3")
+	if err != nil {
+		t.Fatalf("chat after pull: %v", err)
+	}
+	if answer != "ok" {
+		t.Errorf("answer = %q, want ok", answer)
+	}
+
+	tagCalls := server.tagCalls
+	if _, err := as.ChatWithModel(context.Background(), []ai.Message{ai.UserMessage("hi")}, "llama3.2"); err != nil {
+		t.Fatalf("chat with known model: %v", err)
+	}
+	if server.tagCalls != tagCalls {
+		t.Errorf("known model refreshed models, /api/tags calls = %d, want %d", server.tagCalls, tagCalls)
+	}
+}
+
+func TestNewServiceFromEnvForwardsOptionsToOllama(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseModel string
+		wantModel string
+	}{
+		{name: "ollama model", baseModel: "qwen3", wantModel: "qwen3"},
+		{name: "model of another provider", baseModel: ai.OpenRouterModelGPT4oMini, wantModel: "llama3.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newOllamaServer(t, `{"message": {"role": "assistant", "content": "ok"}, "done": true}`, "llama3.2:latest", "qwen3:latest")
+			t.Setenv("AI_PROVIDER", ai.ProviderOllama)
+			t.Setenv("OLLAMA_BASE_URL", server.URL)
+			t.Setenv("OLLAMA_MODEL", "llama3.2")
+
+			as, err := ai.NewServiceFromEnv(option.WithBaseModel(tt.baseModel))
+			if err != nil {
+				t.Fatalf("creating service: %v", err)
+			}
+			if _, err := as.Chat(context.Background(), []ai.Message{ai.UserMessage("hi")}); err != nil {
+				t.Fatalf("chat: %v", err)
+			}
+			if server.requests[0]["model"] != tt.wantModel {
+				t.Errorf("model = %v, want %s", server.requests[0]["model"], tt.wantModel)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"github.com/TMateusz1/go-3rd-devs/internal/ratelimit"
+	"github.com/openai/openai-go"
+	option2 "github.com/openai/openai-go/option"
+	"io"
+)
+
+type openaiAudioService struct {
+	client  *openai.Client
+	config  *option.OpenaiConfig
+	limiter *ratelimit.Limiter
+}
+
+// NewOpenaiAudioService uses OPENAI_AUDIO_BASE_URL when set, because chat providers like OpenRouter have no audio endpoints.
+func NewOpenaiAudioService(opts ...option.Option) (AudioService, error) {
+	config, err := option.NewOpenaiConfig(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create openai audio service: %w", err)
+	}
+	baseUrl := config.AudioBaseUrl
+	if baseUrl == "" {
+		baseUrl = config.BaseUrl
+	}
+	limiter, err := newLimiter(ProviderOpenai, baseUrl, config.ApiKey, config.RateLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create openai audio service: %w", err)
+	}
+	client := openai.NewClient(option2.WithAPIKey(config.ApiKey), option2.WithBaseURL(baseUrl), option2.WithMaxRetries(0))
+
+	return &openaiAudioService{
+		client:  &client,
+		config:  config,
+		limiter: limiter,
+	}, nil
+}
+
+func (o *openaiAudioService) Transcribe(ctx context.Context, req TranscriptionRequest) (Transcription, error) {
+	if req.Audio == nil {
+		return Transcription{}, fmt.Errorf("no audio to transcribe")
+	}
+	reservation, err := o.limiter.Acquire(ctx, 0)
+	if err != nil {
+		return Transcription{}, fmt.Errorf("waiting for rate limit: %w", err)
+	}
+	defer reservation.Release()
+
+	params := openai.AudioTranscriptionNewParams{
+		File:  openai.File(req.Audio, req.Filename, ""),
+		Model: o.config.TranscriptionModel,
+	}
+	if req.Language != "" {
+		params.Language = openai.String(req.Language)
+	}
+	if req.Prompt != "" {
+		params.Prompt = openai.String(req.Prompt)
+	}
+
+	resp, err := o.client.Audio.Transcriptions.New(ctx, params)
+	if err != nil {
+		return Transcription{}, fmt.Errorf("failed to transcribe audio: %w", wrapOpenaiError(err))
+	}
+
+	return Transcription{
+		Text:  resp.Text,
+		Model: o.config.TranscriptionModel,
+	}, nil
+}
+
+func (o *openaiAudioService) Speak(ctx context.Context, req SpeechRequest) (Speech, error) {
+	if req.Input == "" {
+		return Speech{}, fmt.Errorf("no text to speak")
+	}
+	voice := req.Voice
+	if voice == "" {
+		voice = o.config.SpeechVoice
+	}
+	format := req.Format
+	if format == "" {
+		format = o.config.SpeechFormat
+	}
+
+	reservation, err := o.limiter.Acquire(ctx, estimateTokens(req.Input))
+	if err != nil {
+		return Speech{}, fmt.Errorf("waiting for rate limit: %w", err)
+	}
+	defer reservation.Release()
+
+	resp, err := o.client.Audio.Speech.New(ctx, openai.AudioSpeechNewParams{
+		Input:          req.Input,
+		Model:          o.config.SpeechModel,
+		Voice:          openai.AudioSpeechNewParamsVoice(voice),
+		ResponseFormat: openai.AudioSpeechNewParamsResponseFormat(format),
+	})
+	if err != nil {
+		return Speech{}, fmt.Errorf("failed to synthesize speech: %w", wrapOpenaiError(err))
+	}
+	defer resp.Body.Close()
+
+	audio, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Speech{}, fmt.Errorf("reading synthesized speech: %w", err)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return Speech{
+		Audio:       audio,
+		ContentType: contentType,
+		Model:       o.config.SpeechModel,
+	}, nil
+}
@@ -0,0 +1,137 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/models"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"github.com/TMateusz1/go-3rd-devs/internal/ratelimit"
+	"github.com/openai/openai-go"
+	option2 "github.com/openai/openai-go/option"
+	"strings"
+)
+
+type openaiEmbeddingService struct {
+	client    *openai.Client
+	config    *option.OpenaiConfig
+	limiter   *ratelimit.Limiter
+	tokenizer *Tokenizer
+	// maxInputTokens is the context window of the embedding model, 0 when it's unknown.
+	maxInputTokens int
+}
+
+func NewOpenaiEmbeddingService(opts ...option.Option) (EmbeddingService, error) {
+	config, err := option.NewOpenaiConfig(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create openai embedding service: %w", err)
+	}
+	tokenizer, err := TokenizerForModel(config.EmbeddingModel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create openai embedding service: %w", err)
+	}
+	limiter, err := newLimiter(ProviderOpenai, config.BaseUrl, config.ApiKey, config.RateLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create openai embedding service: %w", err)
+	}
+	client := openai.NewClient(option2.WithAPIKey(config.ApiKey), option2.WithBaseURL(config.BaseUrl), option2.WithMaxRetries(0))
+
+	service := &openaiEmbeddingService{
+		client:    &client,
+		config:    config,
+		limiter:   limiter,
+		tokenizer: tokenizer,
+	}
+	if info, ok := models.Lookup(config.EmbeddingModel); ok {
+		service.maxInputTokens = int(info.ContextWindow)
+	}
+	return service, nil
+}
+
+// Embed rejects empty inputs with ErrInvalidRequest and inputs over the context window of the model with ErrContextLengthExceeded.
+func (o *openaiEmbeddingService) Embed(ctx context.Context, inputs []string) (Embeddings, error) {
+	tokens, err := o.countTokens(inputs)
+	if err != nil {
+		return Embeddings{}, err
+	}
+	result := Embeddings{
+		Vectors: make([][]float64, len(inputs)),
+		Model:   o.config.EmbeddingModel,
+	}
+
+	for _, batch := range o.batches(tokens) {
+		batchTokens := 0
+		for _, inputTokens := range tokens[batch.start:batch.end] {
+			batchTokens += inputTokens
+		}
+		reservation, err := o.limiter.Acquire(ctx, batchTokens)
+		if err != nil {
+			return Embeddings{}, fmt.Errorf("waiting for rate limit: %w", err)
+		}
+
+		params := openai.EmbeddingNewParams{
+			Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: inputs[batch.start:batch.end]},
+			Model: o.config.EmbeddingModel,
+		}
+		if o.config.EmbeddingDimensions > 0 {
+			params.Dimensions = openai.Int(o.config.EmbeddingDimensions)
+		}
+
+		resp, err := o.client.Embeddings.New(ctx, params)
+		reservation.Release()
+		if err != nil {
+			return Embeddings{}, fmt.Errorf("failed to create embeddings: %w", wrapOpenaiError(err))
+		}
+		if len(resp.Data) != batch.end-batch.start {
+			return Embeddings{}, fmt.Errorf("got %d embeddings for %d inputs", len(resp.Data), batch.end-batch.start)
+		}
+
+		for _, embedding := range resp.Data {
+			index := batch.start + int(embedding.Index)
+			if index < batch.start || index >= batch.end {
+				return Embeddings{}, fmt.Errorf("embedding index %d out of batch range", embedding.Index)
+			}
+			result.Vectors[index] = embedding.Embedding
+		}
+		result.Model = resp.Model
+		result.Usage.PromptTokens += resp.Usage.PromptTokens
+		result.Usage.TotalTokens += resp.Usage.TotalTokens
+		recordUsage(ctx, o.config.EmbeddingModel, Usage{PromptTokens: resp.Usage.PromptTokens})
+	}
+
+	return result, nil
+}
+
+type batchRange struct {
+	start, end int
+}
+
+func (o *openaiEmbeddingService) countTokens(inputs []string) ([]int, error) {
+	tokens := make([]int, len(inputs))
+	for i, input := range inputs {
+		if strings.TrimSpace(input) == "" {
+			return nil, fmt.Errorf("embedding input %d is empty: %w", i, ErrInvalidRequest)
+		}
+		tokens[i] = o.tokenizer.Count(input)
+		if o.maxInputTokens > 0 && tokens[i] > o.maxInputTokens {
+			return nil, fmt.Errorf("embedding input %d has %d tokens, %s accepts %d: %w", i, tokens[i], o.config.EmbeddingModel, o.maxInputTokens, ErrContextLengthExceeded)
+		}
+	}
+	return tokens, nil
+}
+
+// batches splits inputs by the count limit and the tokens limit of single request, tokens are counted per input.
+func (o *openaiEmbeddingService) batches(tokens []int) []batchRange {
+	var result []batchRange
+	start, batchTokens := 0, 0
+	for i, inputTokens := range tokens {
+		if i > start && (i-start >= o.config.EmbeddingBatchSize || batchTokens+inputTokens > o.config.EmbeddingBatchTokens) {
+			result = append(result, batchRange{start: start, end: i})
+			start, batchTokens = i, 0
+		}
+		batchTokens += inputTokens
+	}
+	if start < len(tokens) {
+		result = append(result, batchRange{start: start, end: len(tokens)})
+	}
+	return result
+}
@@ -0,0 +1,152 @@
+package ai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// embeddingServer stands in for Embeddings API, the vector of "input N" is [N] and data is sent in reversed order.
+func embeddingServer(t *testing.T) (*httptest.Server, func() [][]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var batches [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("path = %s, want /embeddings", r.URL.Path)
+		}
+		var request struct {
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		mu.Lock()
+		batches = append(batches, request.Input)
+		mu.Unlock()
+
+		var data []map[string]any
+		for i := len(request.Input) - 1; i >= 0; i-- {
+			var n float64
+			if _, err := fmt.Sscanf(request.Input[i], "input %g", &n); err != nil {
+				t.Errorf("unexpected input %q", request.Input[i])
+			}
+			data = append(data, map[string]any{"object": "embedding", "index": i, "embedding": []float64{n}})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"object": "list",
+			"data":   data,
+			"model":  "text-embedding-3-small",
+			"usage":  map[string]any{"prompt_tokens": len(request.Input), "total_tokens": len(request.Input)},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return batches
+	}
+}
+
+func inputs(n int) []string {
+	var result []string
+	for i := range n {
+		result = append(result, fmt.Sprintf("input %d", i))
+	}
+	return result
+}
+
+func TestOpenaiEmbed(t *testing.T) {
+	tokenizer, err := ai.NewTokenizer(ai.EncodingCL100K)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputTokens := tokenizer.Count("input 1")
+
+	tests := []struct {
+		name        string
+		inputs      []string
+		batchSize   int
+		batchTokens int
+		wantBatches []int
+	}{
+		{name: "one batch", inputs: inputs(3), batchSize: 10, batchTokens: 1000, wantBatches: []int{3}},
+		{name: "split by count", inputs: inputs(5), batchSize: 2, batchTokens: 1000, wantBatches: []int{2, 2, 1}},
+		{name: "split by tokens", inputs: inputs(5), batchSize: 10, batchTokens: 2 * inputTokens, wantBatches: []int{2, 2, 1}},
+		{name: "input over batch tokens is sent alone", inputs: inputs(2), batchSize: 10, batchTokens: 1, wantBatches: []int{1, 1}},
+		{name: "no inputs", batchSize: 10, batchTokens: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, batches := embeddingServer(t)
+			es, err := ai.NewOpenaiEmbeddingService(option.WithApiKey("key"), option.WithBaseUrl(server.URL), option.WithEmbeddingBatchLimits(tt.batchSize, tt.batchTokens))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			embeddings, err := es.Embed(context.Background(), tt.inputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var sizes []int
+			for _, batch := range batches() {
+				sizes = append(sizes, len(batch))
+			}
+			if !reflect.DeepEqual(sizes, tt.wantBatches) {
+				t.Errorf("batches = %v, want %v", sizes, tt.wantBatches)
+			}
+			if len(embeddings.Vectors) != len(tt.inputs) {
+				t.Fatalf("vectors = %d, want %d", len(embeddings.Vectors), len(tt.inputs))
+			}
+			for i, vector := range embeddings.Vectors {
+				if len(vector) != 1 || vector[0] != float64(i) {
+					t.Errorf("vector %d = %v, want the one of %q", i, vector, tt.inputs[i])
+				}
+			}
+			if embeddings.Usage.PromptTokens != int64(len(tt.inputs)) {
+				t.Errorf("prompt tokens = %d, want sum of batches %d", embeddings.Usage.PromptTokens, len(tt.inputs))
+			}
+		})
+	}
+}
+
+func TestOpenaiEmbedRejectsInvalidInputs(t *testing.T) {
+	tests := []struct {
+		name    string
+		inputs  []string
+		wantErr error
+	}{
+		{name: "empty", inputs: []string{"input 0", ""}, wantErr: ai.ErrInvalidRequest},
+		{name: "blank", inputs: []string{" \n"}, wantErr: ai.ErrInvalidRequest},
+		{name: "over context window", inputs: []string{"input 0", strings.Repeat("word ", 9000)}, wantErr: ai.ErrContextLengthExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, batches := embeddingServer(t)
+			es, err := ai.NewOpenaiEmbeddingService(option.WithApiKey("key"), option.WithBaseUrl(server.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := es.Embed(context.Background(), tt.inputs); !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if sent := len(batches()); sent != 0 {
+				t.Errorf("requests = %d, want none", sent)
+			}
+		})
+	}
+}
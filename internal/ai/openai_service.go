@@ -4,13 +4,17 @@ import (
 	"context"
 	"fmt"
 	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"github.com/TMateusz1/go-3rd-devs/internal/ratelimit"
 	"github.com/openai/openai-go"
 	option2 "github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/shared"
+	"strings"
 )
 
 type openaiService struct {
-	client *openai.Client
-	config *option.OpenaiConfig
+	client  *openai.Client
+	config  *option.OpenaiConfig
+	limiter *ratelimit.Limiter
 }
 
 func NewOpenaiService(opts ...option.Option) (Service, error) {
@@ -18,31 +22,204 @@ func NewOpenaiService(opts ...option.Option) (Service, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create openai service: %w", err)
 	}
-	client := openai.NewClient(option2.WithAPIKey(config.ApiKey), option2.WithBaseURL(config.BaseUrl))
+	limiter, err := newLimiter(ProviderOpenai, config.BaseUrl, config.ApiKey, config.RateLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create openai service: %w", err)
+	}
+	client := openai.NewClient(option2.WithAPIKey(config.ApiKey), option2.WithBaseURL(config.BaseUrl), option2.WithMaxRetries(0))
 
 	return &openaiService{
-		client: &client,
-		config: config,
+		client:  &client,
+		config:  config,
+		limiter: limiter,
 	}, nil
 }
 
-func (o *openaiService) Chat(ctx context.Context, messages []Message) (string, error) {
-	return o.ChatWithModel(ctx, messages, o.config.DefaultModel)
+func (o *openaiService) Chat(ctx context.Context, messages []Message, opts ...CallOption) (string, error) {
+	return o.ChatWithModel(ctx, messages, o.config.DefaultModel, opts...)
+}
+
+func (o *openaiService) DefaultModels() []ServiceModel {
+	return []ServiceModel{{Provider: ProviderOpenai, Model: o.config.DefaultModel}}
 }
 
-func (o *openaiService) ChatWithModel(ctx context.Context, messages []Message, model string) (string, error) {
-	openaiMessages, err := mapMessagesToOpenaiMessages(messages)
+func (o *openaiService) ChatWithModel(ctx context.Context, messages []Message, model string, opts ...CallOption) (string, error) {
+	return AnswerContent(o.Complete(ctx, Request{
+		Model:    model,
+		Messages: messages,
+		Params:   NewGenerationParams(opts...),
+	}))
+}
+
+func (o *openaiService) Complete(ctx context.Context, req Request) (Response, error) {
+	params, err := o.newParams(req)
+	if err != nil {
+		return Response{}, err
+	}
+	reservation, err := o.limiter.Acquire(ctx, reservedTokens(req, params.Model, params.MaxTokens.Or(0)))
 	if err != nil {
-		return "", err
+		return Response{}, fmt.Errorf("waiting for rate limit: %w", err)
 	}
-	resp, err := o.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+	defer reservation.Release()
+
+	resp, err := o.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to send message to AI: %w", wrapOpenaiError(err))
+	}
+	if len(resp.Choices) == 0 {
+		return Response{}, fmt.Errorf("AI response has no choices: %w", ErrProviderUnavailable)
+	}
+
+	usage := mapOpenaiUsage(resp.Usage)
+	recordUsage(ctx, params.Model, usage)
+	reservation.Reconcile(int(usage.TotalTokens()))
+
+	choice := resp.Choices[0]
+	message := AssistantMessage(choice.Message.Content)
+	for _, toolCall := range choice.Message.ToolCalls {
+		message.ToolCalls = append(message.ToolCalls, ToolCall{
+			ID:        toolCall.ID,
+			Name:      toolCall.Function.Name,
+			Arguments: toolCall.Function.Arguments,
+		})
+	}
+
+	return Response{
+		Message:      message,
+		Model:        resp.Model,
+		FinishReason: FinishReason(choice.FinishReason),
+		Usage:        usage,
+	}, nil
+}
+
+func (o *openaiService) ChatStream(ctx context.Context, messages []Message, model string, opts ...CallOption) (<-chan StreamChunk, error) {
+	req := Request{
 		Model:    model,
-		Messages: openaiMessages,
-	})
+		Messages: messages,
+		Params:   NewGenerationParams(opts...),
+	}
+	params, err := o.newParams(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStreaming(params.Model); err != nil {
+		return nil, err
+	}
+
+	reservation, err := o.limiter.Acquire(ctx, reservedTokens(req, params.Model, params.MaxTokens.Or(0)))
+	if err != nil {
+		return nil, fmt.Errorf("waiting for rate limit: %w", err)
+	}
+	params.StreamOptions.IncludeUsage = openai.Bool(true)
+	stream := o.client.Chat.Completions.NewStreaming(ctx, params)
+
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(ch)
+		defer reservation.Release()
+		defer stream.Close()
+
+		send := func(chunk StreamChunk) bool {
+			select {
+			case ch <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		content := strings.Builder{}
+		var finishReason FinishReason
+		for stream.Next() {
+			chunk := stream.Current()
+			// usage comes in the last chunk which doesn't have choices
+			if chunk.JSON.Usage.IsPresent() {
+				usage := mapOpenaiUsage(chunk.Usage)
+				recordUsage(ctx, params.Model, usage)
+				reservation.Reconcile(int(usage.TotalTokens()))
+			}
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+			if chunk.Choices[0].FinishReason != "" {
+				finishReason = FinishReason(chunk.Choices[0].FinishReason)
+			}
+			if chunk.Choices[0].Delta.Content == "" {
+				continue
+			}
+			content.WriteString(chunk.Choices[0].Delta.Content)
+			if !send(StreamChunk{Content: chunk.Choices[0].Delta.Content}) {
+				return
+			}
+		}
+
+		if err := stream.Err(); err != nil {
+			send(StreamChunk{Err: fmt.Errorf("failed to stream message from AI: %w", wrapOpenaiError(err))})
+			return
+		}
+		if err := checkFinishReason(Response{Message: AssistantMessage(content.String()), Model: params.Model, FinishReason: finishReason}); err != nil {
+			send(StreamChunk{Err: err})
+		}
+	}()
+
+	return ch, nil
+}
+
+func (o *openaiService) newParams(req Request) (openai.ChatCompletionNewParams, error) {
+	openaiMessages, err := mapMessagesToOpenaiMessages(req.Messages)
 	if err != nil {
-		return "", fmt.Errorf("failed to send message to AI: %w", err)
+		return openai.ChatCompletionNewParams{}, err
+	}
+	model := req.Model
+	if model == "" {
+		model = o.config.DefaultModel
+	}
+	if err := checkCapabilities(model, req); err != nil {
+		return openai.ChatCompletionNewParams{}, err
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Model:    model,
+		Messages: openaiMessages,
+	}
+	setOpenaiGenerationParams(&params, req.Params.withDefaults(GenerationParams{
+		Temperature: o.config.Temperature,
+		MaxTokens:   o.config.MaxTokens,
+		TopP:        o.config.TopP,
+	}))
+	for _, tool := range req.Tools {
+		params.Tools = append(params.Tools, mapToolToOpenaiTool(tool))
+	}
+	if req.ResponseFormat != nil && o.config.StructuredOutput && supportsJSONSchema(model) {
+		params.ResponseFormat = mapResponseFormatToOpenai(*req.ResponseFormat)
+	}
+	return params, nil
+}
+
+func setOpenaiGenerationParams(params *openai.ChatCompletionNewParams, generation GenerationParams) {
+	if generation.Temperature != nil {
+		params.Temperature = openai.Float(*generation.Temperature)
+	}
+	if generation.MaxTokens != nil {
+		params.MaxTokens = openai.Int(*generation.MaxTokens)
+	}
+	if generation.TopP != nil {
+		params.TopP = openai.Float(*generation.TopP)
+	}
+	if generation.Seed != nil {
+		params.Seed = openai.Int(*generation.Seed)
+	}
+	if len(generation.Stop) > 0 {
+		params.Stop.OfChatCompletionNewsStopArray = generation.Stop
+	}
+}
+
+func mapOpenaiUsage(usage openai.CompletionUsage) Usage {
+	return Usage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CachedTokens:     usage.PromptTokensDetails.CachedTokens,
 	}
-	return resp.Choices[0].Message.Content, nil
 }
 
 func mapMessagesToOpenaiMessages(messages []Message) ([]openai.ChatCompletionMessageParamUnion, error) {
@@ -64,10 +241,84 @@ func mapMessageToOpenaiMessage(message Message) (openai.ChatCompletionMessagePar
 	case System:
 		return openai.SystemMessage(message.Content), nil
 	case User:
+		if len(message.Parts) > 0 {
+			return mapPartsToOpenaiUserMessage(message.contentParts())
+		}
 		return openai.UserMessage(message.Content), nil
 	case Assistant:
-		return openai.AssistantMessage(message.Content), nil
+		if len(message.ToolCalls) == 0 {
+			return openai.AssistantMessage(message.Content), nil
+		}
+		return mapToolCallsToOpenaiMessage(message), nil
+	case Tool:
+		if message.ToolCallID == "" {
+			return openai.ChatCompletionMessageParamUnion{}, fmt.Errorf("tool message without tool call id")
+		}
+		return openai.ToolMessage(message.Content, message.ToolCallID), nil
 	default:
 		return openai.ChatCompletionMessageParamUnion{}, fmt.Errorf("unknown role: %s", message.Role)
 	}
 }
+
+func mapPartsToOpenaiUserMessage(parts []ContentPart) (openai.ChatCompletionMessageParamUnion, error) {
+	var result []openai.ChatCompletionContentPartUnionParam
+	for _, part := range parts {
+		switch part.Type {
+		case ContentPartText:
+			result = append(result, openai.TextContentPart(part.Text))
+		case ContentPartImageURL:
+			result = append(result, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+				URL:    part.ImageURL,
+				Detail: string(part.Detail),
+			}))
+		case ContentPartImageBase64:
+			result = append(result, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+				URL:    part.dataURL(),
+				Detail: string(part.Detail),
+			}))
+		default:
+			return openai.ChatCompletionMessageParamUnion{}, fmt.Errorf("unknown content part type: %s", part.Type)
+		}
+	}
+	return openai.UserMessage(result), nil
+}
+
+func mapToolCallsToOpenaiMessage(message Message) openai.ChatCompletionMessageParamUnion {
+	assistant := openai.ChatCompletionAssistantMessageParam{}
+	if message.Content != "" {
+		assistant.Content.OfString = openai.String(message.Content)
+	}
+	for _, toolCall := range message.ToolCalls {
+		assistant.ToolCalls = append(assistant.ToolCalls, openai.ChatCompletionMessageToolCallParam{
+			ID: toolCall.ID,
+			Function: openai.ChatCompletionMessageToolCallFunctionParam{
+				Name:      toolCall.Name,
+				Arguments: toolCall.Arguments,
+			},
+		})
+	}
+	return openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant}
+}
+
+func mapToolToOpenaiTool(tool ToolDefinition) openai.ChatCompletionToolParam {
+	function := openai.FunctionDefinitionParam{
+		Name:       tool.Name,
+		Parameters: tool.Parameters,
+	}
+	if tool.Description != "" {
+		function.Description = openai.String(tool.Description)
+	}
+	return openai.ChatCompletionToolParam{Function: function}
+}
+
+func mapResponseFormatToOpenai(format ResponseFormat) openai.ChatCompletionNewParamsResponseFormatUnion {
+	return openai.ChatCompletionNewParamsResponseFormatUnion{
+		OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+			JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   format.Name,
+				Strict: openai.Bool(format.Strict),
+				Schema: format.Schema,
+			},
+		},
+	}
+}
@@ -0,0 +1,152 @@
+package option
+
+import (
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/models"
+	"os"
+	"strconv"
+)
+
+const (
+	defaultAnthropicModel     = "claude-sonnet-4-20250514"
+	defaultAnthropicBaseUrl   = "https://api.anthropic.com"
+	defaultAnthropicVersion   = "2023-06-01"
+	defaultAnthropicMaxTokens = 4096
+)
+
+type AnthropicConfig struct {
+	ApiKey       string
+	DefaultModel string
+	BaseUrl      string
+	// Version is sent as anthropic-version header.
+	Version string
+	// MaxTokens is required by Messages API, it's used when the call doesn't set its own.
+	MaxTokens int64
+	RateLimit RateLimitConfig
+}
+
+type AnthropicOption func(*AnthropicConfig)
+
+func NewAnthropicConfig(opts ...AnthropicOption) (*AnthropicConfig, error) {
+	config := &AnthropicConfig{
+		DefaultModel: defaultAnthropicModel,
+		BaseUrl:      defaultAnthropicBaseUrl,
+		Version:      defaultAnthropicVersion,
+		MaxTokens:    defaultAnthropicMaxTokens,
+	}
+
+	loadAnthropicFromEnv(config)
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	err := validateAnthropic(config)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic config is invalid: %w", err)
+	}
+
+	return config, nil
+}
+
+func validateAnthropic(config *AnthropicConfig) error {
+	if config.ApiKey == "" {
+		return fmt.Errorf("apikey is required")
+	}
+	if config.DefaultModel == "" {
+		return fmt.Errorf("model is empty")
+	}
+	if config.BaseUrl == "" {
+		return fmt.Errorf("baseurl is empty")
+	}
+	if config.Version == "" {
+		return fmt.Errorf("version is empty")
+	}
+	if config.MaxTokens <= 0 {
+		return fmt.Errorf("max tokens must be positive")
+	}
+
+	registry, err := models.Default()
+	if err != nil {
+		return fmt.Errorf("loading model registry: %w", err)
+	}
+	if err := registry.Validate(models.ProviderAnthropic, config.DefaultModel); err != nil {
+		return err
+	}
+	if err := registry.ValidateMaxTokens(config.DefaultModel, config.MaxTokens); err != nil {
+		return err
+	}
+	return validateRateLimit(config.RateLimit)
+}
+
+func loadAnthropicFromEnv(config *AnthropicConfig) {
+	apikey, ok := os.LookupEnv("ANTHROPIC_API_KEY")
+	if ok {
+		config.ApiKey = apikey
+	}
+
+	model, ok := os.LookupEnv("ANTHROPIC_MODEL")
+	if ok {
+		config.DefaultModel = model
+	}
+
+	baseUrl, ok := os.LookupEnv("ANTHROPIC_BASE_URL")
+	if ok {
+		config.BaseUrl = baseUrl
+	}
+
+	version, ok := os.LookupEnv("ANTHROPIC_VERSION")
+	if ok {
+		config.Version = version
+	}
+
+	loadRateLimitFromEnv("ANTHROPIC", &config.RateLimit)
+
+	maxTokens, ok := os.LookupEnv("ANTHROPIC_MAX_TOKENS")
+	if ok {
+		parsed, err := strconv.ParseInt(maxTokens, 10, 64)
+		if err == nil {
+			config.MaxTokens = parsed
+		}
+	}
+}
+
+func WithAnthropicApiKey(apiKey string) AnthropicOption {
+	return func(config *AnthropicConfig) {
+		config.ApiKey = apiKey
+	}
+}
+
+func WithAnthropicBaseModel(model string) AnthropicOption {
+	return func(config *AnthropicConfig) {
+		config.DefaultModel = model
+	}
+}
+
+func WithAnthropicBaseUrl(baseUrl string) AnthropicOption {
+	return func(config *AnthropicConfig) {
+		config.BaseUrl = baseUrl
+	}
+}
+
+func WithAnthropicVersion(version string) AnthropicOption {
+	return func(config *AnthropicConfig) {
+		config.Version = version
+	}
+}
+
+func WithAnthropicMaxTokens(maxTokens int64) AnthropicOption {
+	return func(config *AnthropicConfig) {
+		config.MaxTokens = maxTokens
+	}
+}
+
+func WithAnthropicRateLimit(maxInFlight, requestsPerMinute, tokensPerMinute int) AnthropicOption {
+	return func(config *AnthropicConfig) {
+		config.RateLimit = RateLimitConfig{
+			MaxInFlight:       maxInFlight,
+			RequestsPerMinute: requestsPerMinute,
+			TokensPerMinute:   tokensPerMinute,
+		}
+	}
+}
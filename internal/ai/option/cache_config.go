@@ -0,0 +1,56 @@
+package option
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const defaultCacheTTL = 24 * time.Hour
+
+type CacheConfig struct {
+	TTL time.Duration
+	// Observer is called on every cache lookup, e.g. to export hit/miss metrics.
+	Observer func(key string, hit bool)
+}
+
+type CacheOption func(*CacheConfig)
+
+func NewCacheConfig(opts ...CacheOption) (*CacheConfig, error) {
+	config := &CacheConfig{
+		TTL: defaultCacheTTL,
+	}
+
+	loadCacheFromEnv(config)
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if config.TTL <= 0 {
+		return nil, fmt.Errorf("cache config is invalid: ttl must be positive")
+	}
+	return config, nil
+}
+
+func loadCacheFromEnv(config *CacheConfig) {
+	ttl, ok := os.LookupEnv("AI_CACHE_TTL")
+	if ok {
+		parsed, err := time.ParseDuration(ttl)
+		if err == nil {
+			config.TTL = parsed
+		}
+	}
+}
+
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(config *CacheConfig) {
+		config.TTL = ttl
+	}
+}
+
+func WithCacheObserver(observer func(key string, hit bool)) CacheOption {
+	return func(config *CacheConfig) {
+		config.Observer = observer
+	}
+}
@@ -0,0 +1,57 @@
+package option
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const defaultFallbackAttemptTimeout = 60 * time.Second
+
+type FallbackConfig struct {
+	// AttemptTimeout limits one target, for streams only the wait for the first delta so long answers aren't cut.
+	AttemptTimeout time.Duration
+	// OnFallback is called when a target failed and the next one is going to be asked.
+	OnFallback func(model string, err error)
+}
+
+type FallbackOption func(*FallbackConfig)
+
+func NewFallbackConfig(opts ...FallbackOption) (*FallbackConfig, error) {
+	config := &FallbackConfig{
+		AttemptTimeout: defaultFallbackAttemptTimeout,
+	}
+
+	loadFallbackFromEnv(config)
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	if config.AttemptTimeout <= 0 {
+		return nil, fmt.Errorf("fallback config is invalid: attempt timeout must be positive")
+	}
+	return config, nil
+}
+
+func loadFallbackFromEnv(config *FallbackConfig) {
+	timeout, ok := os.LookupEnv("AI_FALLBACK_ATTEMPT_TIMEOUT")
+	if ok {
+		parsed, err := time.ParseDuration(timeout)
+		if err == nil {
+			config.AttemptTimeout = parsed
+		}
+	}
+}
+
+func WithAttemptTimeout(timeout time.Duration) FallbackOption {
+	return func(config *FallbackConfig) {
+		config.AttemptTimeout = timeout
+	}
+}
+
+func WithFallbackObserver(observer func(model string, err error)) FallbackOption {
+	return func(config *FallbackConfig) {
+		config.OnFallback = observer
+	}
+}
@@ -0,0 +1,96 @@
+package option
+
+import (
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/models"
+	"os"
+)
+
+const (
+	defaultOllamaModel   = "llama3.2"
+	defaultOllamaBaseUrl = "http://localhost:11434"
+)
+
+// OllamaConfig doesn't have an api key, local model servers don't need it.
+type OllamaConfig struct {
+	DefaultModel string
+	BaseUrl      string
+	// RateLimit MaxInFlight is useful to not overload local model server.
+	RateLimit RateLimitConfig
+}
+
+type OllamaOption func(*OllamaConfig)
+
+func NewOllamaConfig(opts ...OllamaOption) (*OllamaConfig, error) {
+	config := &OllamaConfig{
+		DefaultModel: defaultOllamaModel,
+		BaseUrl:      defaultOllamaBaseUrl,
+	}
+
+	loadOllamaFromEnv(config)
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	err := validateOllama(config)
+	if err != nil {
+		return nil, fmt.Errorf("ollama config is invalid: %w", err)
+	}
+
+	return config, nil
+}
+
+func validateOllama(config *OllamaConfig) error {
+	if config.DefaultModel == "" {
+		return fmt.Errorf("model is empty")
+	}
+	if config.BaseUrl == "" {
+		return fmt.Errorf("baseurl is empty")
+	}
+
+	registry, err := models.Default()
+	if err != nil {
+		return fmt.Errorf("loading model registry: %w", err)
+	}
+	if err := registry.Validate(models.ProviderOllama, config.DefaultModel); err != nil {
+		return err
+	}
+	return validateRateLimit(config.RateLimit)
+}
+
+func loadOllamaFromEnv(config *OllamaConfig) {
+	model, ok := os.LookupEnv("OLLAMA_MODEL")
+	if ok {
+		config.DefaultModel = model
+	}
+
+	baseUrl, ok := os.LookupEnv("OLLAMA_BASE_URL")
+	if ok {
+		config.BaseUrl = baseUrl
+	}
+
+	loadRateLimitFromEnv("OLLAMA", &config.RateLimit)
+}
+
+func WithOllamaBaseModel(model string) OllamaOption {
+	return func(config *OllamaConfig) {
+		config.DefaultModel = model
+	}
+}
+
+func WithOllamaBaseUrl(baseUrl string) OllamaOption {
+	return func(config *OllamaConfig) {
+		config.BaseUrl = baseUrl
+	}
+}
+
+func WithOllamaRateLimit(maxInFlight, requestsPerMinute, tokensPerMinute int) OllamaOption {
+	return func(config *OllamaConfig) {
+		config.RateLimit = RateLimitConfig{
+			MaxInFlight:       maxInFlight,
+			RequestsPerMinute: requestsPerMinute,
+			TokensPerMinute:   tokensPerMinute,
+		}
+	}
+}
@@ -2,26 +2,70 @@ package option
 
 import (
 	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/models"
 	"os"
+	"strconv"
 )
 
 const (
 	defaultModel   = "gpt-3.5-turbo"
 	defaultBaseUrl = "https://api.openai.com/v1"
+
+	defaultEmbeddingModel       = "text-embedding-3-small"
+	defaultEmbeddingBatchSize   = 2048
+	defaultEmbeddingBatchTokens = 300000
+
+	defaultTranscriptionModel = "whisper-1"
+	defaultSpeechModel        = "tts-1"
+	defaultSpeechVoice        = "alloy"
+	defaultSpeechFormat       = "mp3"
 )
 
 type OpenaiConfig struct {
 	ApiKey       string
 	DefaultModel string
 	BaseUrl      string
+	// StructuredOutput sends JSON schema as response_format, turn it off for providers which reject it.
+	StructuredOutput bool
+
+	// Temperature, MaxTokens and TopP are defaults of every call, nil means provider default.
+	Temperature *float64
+	MaxTokens   *int64
+	TopP        *float64
+
+	EmbeddingModel string
+	// EmbeddingDimensions shortens vectors when > 0, supported only by newer models.
+	EmbeddingDimensions int64
+	// EmbeddingBatchSize and EmbeddingBatchTokens are limits of inputs sent in one request.
+	EmbeddingBatchSize   int
+	EmbeddingBatchTokens int
+
+	// AudioBaseUrl is used by the audio service instead of BaseUrl when set.
+	AudioBaseUrl       string
+	TranscriptionModel string
+	SpeechModel        string
+	SpeechVoice        string
+	SpeechFormat       string
+
+	RateLimit RateLimitConfig
 }
 
 type Option func(*OpenaiConfig)
 
 func NewOpenaiConfig(opts ...Option) (*OpenaiConfig, error) {
 	config := &OpenaiConfig{
-		DefaultModel: defaultModel,
-		BaseUrl:      defaultBaseUrl,
+		DefaultModel:     defaultModel,
+		BaseUrl:          defaultBaseUrl,
+		StructuredOutput: true,
+
+		EmbeddingModel:       defaultEmbeddingModel,
+		EmbeddingBatchSize:   defaultEmbeddingBatchSize,
+		EmbeddingBatchTokens: defaultEmbeddingBatchTokens,
+
+		TranscriptionModel: defaultTranscriptionModel,
+		SpeechModel:        defaultSpeechModel,
+		SpeechVoice:        defaultSpeechVoice,
+		SpeechFormat:       defaultSpeechFormat,
 	}
 
 	loadFromEnv(config)
@@ -39,7 +83,6 @@ func NewOpenaiConfig(opts ...Option) (*OpenaiConfig, error) {
 }
 
 func validate(config *OpenaiConfig) error {
-	// should have more real validators like Parsing URL, model validation etc.
 	if config.ApiKey == "" {
 		return fmt.Errorf("apikey is required")
 	}
@@ -49,7 +92,32 @@ func validate(config *OpenaiConfig) error {
 	if config.BaseUrl == "" {
 		return fmt.Errorf("baseurl is empty")
 	}
-	return nil
+	if config.EmbeddingModel == "" {
+		return fmt.Errorf("embedding model is empty")
+	}
+	if config.EmbeddingBatchSize <= 0 || config.EmbeddingBatchTokens <= 0 {
+		return fmt.Errorf("embedding batch limits must be positive")
+	}
+	if config.TranscriptionModel == "" || config.SpeechModel == "" || config.SpeechVoice == "" || config.SpeechFormat == "" {
+		return fmt.Errorf("audio models, voice and format can't be empty")
+	}
+
+	registry, err := models.Default()
+	if err != nil {
+		return fmt.Errorf("loading model registry: %w", err)
+	}
+	if err := registry.Validate(models.ProviderOpenai, config.DefaultModel); err != nil {
+		return err
+	}
+	if err := registry.ValidateEmbedding(models.ProviderOpenai, config.EmbeddingModel); err != nil {
+		return err
+	}
+	if config.MaxTokens != nil {
+		if err := registry.ValidateMaxTokens(config.DefaultModel, *config.MaxTokens); err != nil {
+			return err
+		}
+	}
+	return validateRateLimit(config.RateLimit)
 }
 
 func loadFromEnv(config *OpenaiConfig) {
@@ -68,6 +136,56 @@ func loadFromEnv(config *OpenaiConfig) {
 		config.BaseUrl = baseUrl
 	}
 
+	embeddingModel, ok := os.LookupEnv("OPENAI_EMBEDDING_MODEL")
+	if ok {
+		config.EmbeddingModel = embeddingModel
+	}
+
+	audioBaseUrl, ok := os.LookupEnv("OPENAI_AUDIO_BASE_URL")
+	if ok {
+		config.AudioBaseUrl = audioBaseUrl
+	}
+
+	transcriptionModel, ok := os.LookupEnv("OPENAI_TRANSCRIPTION_MODEL")
+	if ok {
+		config.TranscriptionModel = transcriptionModel
+	}
+
+	speechModel, ok := os.LookupEnv("OPENAI_SPEECH_MODEL")
+	if ok {
+		config.SpeechModel = speechModel
+	}
+
+	speechVoice, ok := os.LookupEnv("OPENAI_SPEECH_VOICE")
+	if ok {
+		config.SpeechVoice = speechVoice
+	}
+
+	loadRateLimitFromEnv("OPENAI", &config.RateLimit)
+
+	temperature, ok := os.LookupEnv("OPENAI_TEMPERATURE")
+	if ok {
+		parsed, err := strconv.ParseFloat(temperature, 64)
+		if err == nil {
+			config.Temperature = &parsed
+		}
+	}
+
+	maxTokens, ok := os.LookupEnv("OPENAI_MAX_TOKENS")
+	if ok {
+		parsed, err := strconv.ParseInt(maxTokens, 10, 64)
+		if err == nil {
+			config.MaxTokens = &parsed
+		}
+	}
+
+	structuredOutput, ok := os.LookupEnv("OPENAI_STRUCTURED_OUTPUT")
+	if ok {
+		enabled, err := strconv.ParseBool(structuredOutput)
+		if err == nil {
+			config.StructuredOutput = enabled
+		}
+	}
 }
 
 func WithApiKey(apiKey string) Option {
@@ -87,3 +205,76 @@ func WithBaseUrl(baseUrl string) Option {
 		config.BaseUrl = baseUrl
 	}
 }
+
+func WithStructuredOutput(enabled bool) Option {
+	return func(config *OpenaiConfig) {
+		config.StructuredOutput = enabled
+	}
+}
+
+func WithEmbeddingModel(model string) Option {
+	return func(config *OpenaiConfig) {
+		config.EmbeddingModel = model
+	}
+}
+
+func WithEmbeddingDimensions(dimensions int64) Option {
+	return func(config *OpenaiConfig) {
+		config.EmbeddingDimensions = dimensions
+	}
+}
+
+func WithEmbeddingBatchLimits(size, tokens int) Option {
+	return func(config *OpenaiConfig) {
+		config.EmbeddingBatchSize = size
+		config.EmbeddingBatchTokens = tokens
+	}
+}
+
+func WithRateLimit(maxInFlight, requestsPerMinute, tokensPerMinute int) Option {
+	return func(config *OpenaiConfig) {
+		config.RateLimit = RateLimitConfig{
+			MaxInFlight:       maxInFlight,
+			RequestsPerMinute: requestsPerMinute,
+			TokensPerMinute:   tokensPerMinute,
+		}
+	}
+}
+
+func WithDefaultTemperature(temperature float64) Option {
+	return func(config *OpenaiConfig) {
+		config.Temperature = &temperature
+	}
+}
+
+func WithDefaultMaxTokens(maxTokens int64) Option {
+	return func(config *OpenaiConfig) {
+		config.MaxTokens = &maxTokens
+	}
+}
+
+func WithDefaultTopP(topP float64) Option {
+	return func(config *OpenaiConfig) {
+		config.TopP = &topP
+	}
+}
+
+func WithAudioBaseUrl(baseUrl string) Option {
+	return func(config *OpenaiConfig) {
+		config.AudioBaseUrl = baseUrl
+	}
+}
+
+func WithTranscriptionModel(model string) Option {
+	return func(config *OpenaiConfig) {
+		config.TranscriptionModel = model
+	}
+}
+
+func WithSpeech(model, voice, format string) Option {
+	return func(config *OpenaiConfig) {
+		config.SpeechModel = model
+		config.SpeechVoice = voice
+		config.SpeechFormat = format
+	}
+}
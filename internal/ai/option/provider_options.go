@@ -0,0 +1,75 @@
+package option
+
+import (
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/models"
+	"log"
+)
+
+// AnthropicOptions translates settings shared by providers (base model, default max tokens and rate limit),
+// so options given to NewServiceFromEnv apply to whichever provider is picked.
+// Base model served by another provider is skipped, the anthropic default is used instead.
+func AnthropicOptions(opts ...Option) ([]AnthropicOption, error) {
+	config := shared(opts...)
+
+	var result []AnthropicOption
+	ok, err := servable(models.ProviderAnthropic, config.DefaultModel)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		result = append(result, WithAnthropicBaseModel(config.DefaultModel))
+	}
+	if config.MaxTokens != nil {
+		result = append(result, WithAnthropicMaxTokens(*config.MaxTokens))
+	}
+	if config.RateLimit != (RateLimitConfig{}) {
+		limit := config.RateLimit
+		result = append(result, WithAnthropicRateLimit(limit.MaxInFlight, limit.RequestsPerMinute, limit.TokensPerMinute))
+	}
+	return result, nil
+}
+
+// OllamaOptions translates settings shared by providers (base model and rate limit), see AnthropicOptions.
+func OllamaOptions(opts ...Option) ([]OllamaOption, error) {
+	config := shared(opts...)
+
+	var result []OllamaOption
+	ok, err := servable(models.ProviderOllama, config.DefaultModel)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		result = append(result, WithOllamaBaseModel(config.DefaultModel))
+	}
+	if config.RateLimit != (RateLimitConfig{}) {
+		limit := config.RateLimit
+		result = append(result, WithOllamaRateLimit(limit.MaxInFlight, limit.RequestsPerMinute, limit.TokensPerMinute))
+	}
+	return result, nil
+}
+
+// shared applies options to an empty config, so only explicitly set values are forwarded.
+func shared(opts ...Option) OpenaiConfig {
+	config := OpenaiConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
+// servable tells if the base model can be forwarded to the provider, a registry which failed to load is an error.
+func servable(provider, model string) (bool, error) {
+	if model == "" {
+		return false, nil
+	}
+	registry, err := models.Default()
+	if err != nil {
+		return false, fmt.Errorf("loading model registry: %w", err)
+	}
+	if err := registry.Validate(provider, model); err != nil {
+		log.Printf("base model isn't used by %s provider: %v", provider, err)
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,29 @@
+package option_test
+
+import (
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestProviderOptionsFailOnBrokenRegistry is the only test of the package which loads the default registry,
+// it's loaded once per process so the broken models file is set before.
+func TestProviderOptionsFailOnBrokenRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.json")
+	if err := os.WriteFile(path, []byte(`{"models": [`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AI_MODELS_FILE", path)
+
+	if opts, err := option.AnthropicOptions(option.WithBaseModel("claude-3-5-haiku-latest")); err == nil {
+		t.Errorf("anthropic options = %d, want registry error", len(opts))
+	}
+	if opts, err := option.OllamaOptions(option.WithBaseModel("llama3.2")); err == nil {
+		t.Errorf("ollama options = %d, want registry error", len(opts))
+	}
+	// without a base model there is nothing to validate
+	if _, err := option.AnthropicOptions(option.WithRateLimit(1, 10, 1000)); err != nil {
+		t.Errorf("options without base model: %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package option
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// RateLimitConfig is shared by providers, zero value of each limit means no limit.
+type RateLimitConfig struct {
+	MaxInFlight       int
+	RequestsPerMinute int
+	TokensPerMinute   int
+}
+
+func validateRateLimit(config RateLimitConfig) error {
+	if config.MaxInFlight < 0 || config.RequestsPerMinute < 0 || config.TokensPerMinute < 0 {
+		return fmt.Errorf("rate limits can't be negative")
+	}
+	return nil
+}
+
+// loadRateLimitFromEnv reads {prefix}_MAX_IN_FLIGHT, {prefix}_RPM and {prefix}_TPM.
+func loadRateLimitFromEnv(prefix string, config *RateLimitConfig) {
+	lookupInt := func(name string, target *int) {
+		value, ok := os.LookupEnv(fmt.Sprintf("%s_%s", prefix, name))
+		if !ok {
+			return
+		}
+		parsed, err := strconv.Atoi(value)
+		if err == nil {
+			*target = parsed
+		}
+	}
+
+	lookupInt("MAX_IN_FLIGHT", &config.MaxInFlight)
+	lookupInt("RPM", &config.RequestsPerMinute)
+	lookupInt("TPM", &config.TokensPerMinute)
+}
@@ -0,0 +1,97 @@
+package option
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxRetries     = 3
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+	defaultRetryBudget    = 2 * time.Minute
+)
+
+type RetryConfig struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Budget is the total time which can be spent on waiting between attempts of one call.
+	Budget time.Duration
+}
+
+type RetryOption func(*RetryConfig)
+
+func NewRetryConfig(opts ...RetryOption) (*RetryConfig, error) {
+	config := &RetryConfig{
+		MaxRetries:     defaultMaxRetries,
+		InitialBackoff: defaultInitialBackoff,
+		MaxBackoff:     defaultMaxBackoff,
+		Budget:         defaultRetryBudget,
+	}
+
+	loadRetryFromEnv(config)
+
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	err := validateRetry(config)
+	if err != nil {
+		return nil, fmt.Errorf("retry config is invalid: %w", err)
+	}
+
+	return config, nil
+}
+
+func validateRetry(config *RetryConfig) error {
+	if config.MaxRetries < 0 {
+		return fmt.Errorf("max retries can't be negative")
+	}
+	if config.InitialBackoff <= 0 || config.MaxBackoff < config.InitialBackoff {
+		return fmt.Errorf("backoff must be positive and max backoff can't be lower than initial")
+	}
+	if config.Budget <= 0 {
+		return fmt.Errorf("budget must be positive")
+	}
+	return nil
+}
+
+func loadRetryFromEnv(config *RetryConfig) {
+	maxRetries, ok := os.LookupEnv("AI_MAX_RETRIES")
+	if ok {
+		parsed, err := strconv.Atoi(maxRetries)
+		if err == nil {
+			config.MaxRetries = parsed
+		}
+	}
+
+	budget, ok := os.LookupEnv("AI_RETRY_BUDGET")
+	if ok {
+		parsed, err := time.ParseDuration(budget)
+		if err == nil {
+			config.Budget = parsed
+		}
+	}
+}
+
+func WithMaxRetries(maxRetries int) RetryOption {
+	return func(config *RetryConfig) {
+		config.MaxRetries = maxRetries
+	}
+}
+
+func WithBackoff(initial, max time.Duration) RetryOption {
+	return func(config *RetryConfig) {
+		config.InitialBackoff = initial
+		config.MaxBackoff = max
+	}
+}
+
+func WithRetryBudget(budget time.Duration) RetryOption {
+	return func(config *RetryConfig) {
+		config.Budget = budget
+	}
+}
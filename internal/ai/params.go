@@ -0,0 +1,87 @@
+package ai
+
+// GenerationParams are set per call, nil fields fall back to provider config defaults.
+type GenerationParams struct {
+	Temperature *float64
+	MaxTokens   *int64
+	Stop        []string
+	Seed        *int64
+	TopP        *float64
+}
+
+type CallOption func(*GenerationParams)
+
+func NewGenerationParams(opts ...CallOption) GenerationParams {
+	params := GenerationParams{}
+	for _, opt := range opts {
+		opt(&params)
+	}
+	return params
+}
+
+// withDefaults fills fields which weren't set for the call.
+func (p GenerationParams) withDefaults(defaults GenerationParams) GenerationParams {
+	if p.Temperature == nil {
+		p.Temperature = defaults.Temperature
+	}
+	if p.MaxTokens == nil {
+		p.MaxTokens = defaults.MaxTokens
+	}
+	if p.Stop == nil {
+		p.Stop = defaults.Stop
+	}
+	if p.Seed == nil {
+		p.Seed = defaults.Seed
+	}
+	if p.TopP == nil {
+		p.TopP = defaults.TopP
+	}
+	return p
+}
+
+func WithTemperature(temperature float64) CallOption {
+	return func(params *GenerationParams) {
+		params.Temperature = &temperature
+	}
+}
+
+func WithMaxTokens(maxTokens int64) CallOption {
+	return func(params *GenerationParams) {
+		params.MaxTokens = &maxTokens
+	}
+}
+
+func WithStop(stop ...string) CallOption {
+	return func(params *GenerationParams) {
+		params.Stop = stop
+	}
+}
+
+func WithSeed(seed int64) CallOption {
+	return func(params *GenerationParams) {
+		params.Seed = &seed
+	}
+}
+
+func WithTopP(topP float64) CallOption {
+	return func(params *GenerationParams) {
+		params.TopP = &topP
+	}
+}
+
+// WithParams sets all params at once, e.g. to pass params of a Request to ChatStream.
+func WithParams(generation GenerationParams) CallOption {
+	return func(params *GenerationParams) {
+		*params = generation
+	}
+}
+
+// Deterministic is shortcut for classification-like calls which should always answer the same.
+func Deterministic() CallOption {
+	return func(params *GenerationParams) {
+		temperature := 0.0
+		seed := int64(0)
+		params.Temperature = &temperature
+		params.Seed = &seed
+	}
+}
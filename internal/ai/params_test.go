@@ -0,0 +1,129 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestNewGenerationParams(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []CallOption
+		want GenerationParams
+	}{
+		{name: "none"},
+		{
+			name: "every option",
+			opts: []CallOption{WithTemperature(0.7), WithMaxTokens(100), WithStop("\n"), WithSeed(42), WithTopP(0.9)},
+			want: GenerationParams{Temperature: ptr(0.7), MaxTokens: ptr(int64(100)), Stop: []string{"\n"}, Seed: ptr(int64(42)), TopP: ptr(0.9)},
+		},
+		{
+			name: "deterministic keeps max tokens",
+			opts: []CallOption{WithMaxTokens(20), Deterministic()},
+			want: GenerationParams{Temperature: ptr(0.0), MaxTokens: ptr(int64(20)), Seed: ptr(int64(0))},
+		},
+		{
+			name: "later option wins",
+			opts: []CallOption{WithTemperature(0.7), WithTemperature(0.1)},
+			want: GenerationParams{Temperature: ptr(0.1)},
+		},
+		{
+			name: "params replace earlier options",
+			opts: []CallOption{WithTemperature(0.7), WithParams(GenerationParams{MaxTokens: ptr(int64(5))})},
+			want: GenerationParams{MaxTokens: ptr(int64(5))},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewGenerationParams(tt.opts...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("params = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerationParamsWithDefaults(t *testing.T) {
+	defaults := GenerationParams{Temperature: ptr(0.5), MaxTokens: ptr(int64(1000)), Stop: []string{"END"}, Seed: ptr(int64(1)), TopP: ptr(0.8)}
+
+	tests := []struct {
+		name   string
+		params GenerationParams
+		want   GenerationParams
+	}{
+		{name: "unset params take defaults", want: defaults},
+		{
+			name:   "set params are kept",
+			params: GenerationParams{Temperature: ptr(0.0), MaxTokens: ptr(int64(5))},
+			want:   GenerationParams{Temperature: ptr(0.0), MaxTokens: ptr(int64(5)), Stop: []string{"END"}, Seed: ptr(int64(1)), TopP: ptr(0.8)},
+		},
+		{
+			name:   "empty stop is set",
+			params: GenerationParams{Stop: []string{}},
+			want:   GenerationParams{Temperature: ptr(0.5), MaxTokens: ptr(int64(1000)), Stop: []string{}, Seed: ptr(int64(1)), TopP: ptr(0.8)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.params.withDefaults(defaults); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("params = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if got := (GenerationParams{}).withDefaults(GenerationParams{}); !reflect.DeepEqual(got, GenerationParams{}) {
+		t.Errorf("params without defaults = %+v, want none", got)
+	}
+}
+
+func TestOpenaiGenerationParamsFromEnv(t *testing.T) {
+	tests := []struct {
+		name            string
+		env             map[string]string
+		opts            []CallOption
+		wantTemperature any
+		wantMaxTokens   any
+	}{
+		{name: "provider defaults"},
+		{name: "env defaults", env: map[string]string{"OPENAI_TEMPERATURE": "0.3", "OPENAI_MAX_TOKENS": "200"}, wantTemperature: 0.3, wantMaxTokens: 200.0},
+		{name: "call options over env", env: map[string]string{"OPENAI_TEMPERATURE": "0.3", "OPENAI_MAX_TOKENS": "200"}, opts: []CallOption{Deterministic(), WithMaxTokens(20)}, wantTemperature: 0.0, wantMaxTokens: 20.0},
+		{name: "invalid env is ignored", env: map[string]string{"OPENAI_TEMPERATURE": "warm", "OPENAI_MAX_TOKENS": "many"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			var request map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+					t.Errorf("decoding request: %v", err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"choices": [{"index": 0, "message": {"role": "assistant", "content": "ok"}, "finish_reason": "stop"}]}`))
+			}))
+			defer server.Close()
+			as, err := NewOpenaiService(option.WithApiKey("key"), option.WithBaseUrl(server.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := as.Chat(context.Background(), []Message{UserMessage("hi")}, tt.opts...); err != nil {
+				t.Fatal(err)
+			}
+			if request["temperature"] != tt.wantTemperature || request["max_tokens"] != tt.wantMaxTokens {
+				t.Errorf("temperature = %v, max tokens = %v, want %v and %v", request["temperature"], request["max_tokens"], tt.wantTemperature, tt.wantMaxTokens)
+			}
+		})
+	}
+}
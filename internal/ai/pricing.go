@@ -0,0 +1,28 @@
+package ai
+
+import (
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/models"
+)
+
+// Pricing is in USD per million tokens.
+type Pricing = models.Pricing
+
+// PricingFor looks the model up in the model registry.
+func PricingFor(model string) (Pricing, bool) {
+	info, ok := models.Lookup(model)
+	if !ok {
+		return Pricing{}, false
+	}
+	return info.Pricing, true
+}
+
+// Cost returns false for models without pricing (e.g. unknown ones), their cost is 0.
+func Cost(model string, usage Usage) (float64, bool) {
+	p, ok := PricingFor(model)
+	if !ok {
+		return 0, false
+	}
+	uncached := usage.PromptTokens - usage.CachedTokens
+	cost := float64(uncached)*p.Input + float64(usage.CachedTokens)*p.CachedInput + float64(usage.CompletionTokens)*p.Output
+	return cost / 1_000_000, true
+}
@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/models"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"os"
+)
+
+const (
+	ProviderOpenai    = models.ProviderOpenai
+	ProviderAnthropic = models.ProviderAnthropic
+	ProviderOllama    = models.ProviderOllama
+)
+
+// NewServiceFromEnv picks the provider by AI_PROVIDER env, openai is the default.
+// Base model, default max tokens and rate limit of given options are forwarded to other providers too.
+// When AI_FALLBACK_MODELS is set the service is a fallback chain of listed provider:model pairs.
+func NewServiceFromEnv(opts ...option.Option) (Service, error) {
+	if _, ok := os.LookupEnv("AI_FALLBACK_MODELS"); ok {
+		targets, err := NewFallbackTargetsFromEnv(opts...)
+		if err != nil {
+			return nil, err
+		}
+		return NewFallbackService(targets)
+	}
+
+	provider, ok := os.LookupEnv("AI_PROVIDER")
+	if !ok {
+		provider = ProviderOpenai
+	}
+
+	switch provider {
+	case ProviderOpenai:
+		return NewOpenaiService(opts...)
+	case ProviderAnthropic:
+		anthropicOpts, err := option.AnthropicOptions(opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create anthropic service: %w", err)
+		}
+		return NewAnthropicService(anthropicOpts...)
+	case ProviderOllama:
+		ollamaOpts, err := option.OllamaOptions(opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ollama service: %w", err)
+		}
+		return NewOllamaService(ollamaOpts...)
+	default:
+		return nil, fmt.Errorf("unknown AI_PROVIDER: %s", provider)
+	}
+}
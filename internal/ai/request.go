@@ -0,0 +1,29 @@
+package ai
+
+type FinishReason string
+
+const (
+	FinishReasonStop          FinishReason = "stop"
+	FinishReasonLength        FinishReason = "length"
+	FinishReasonToolCalls     FinishReason = "tool_calls"
+	FinishReasonContentFilter FinishReason = "content_filter"
+)
+
+// Request is a full chat completion call, empty Model means the default one.
+type Request struct {
+	Model    string
+	Messages []Message
+	Tools    []ToolDefinition
+	// ResponseFormat is set by CompleteStructured
+	ResponseFormat *ResponseFormat
+	Params         GenerationParams
+}
+
+type Response struct {
+	Message      Message
+	Model        string
+	FinishReason FinishReason
+	Usage        Usage
+	// Cached is set when the response comes from cache and didn't cost anything.
+	Cached bool
+}
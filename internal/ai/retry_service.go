@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+)
+
+type retryService struct {
+	next   Service
+	config *option.RetryConfig
+}
+
+// NewRetryService retries transient failures (429, 5xx, network errors) of the wrapped service
+// with jittered exponential backoff, Retry-After of the provider is respected.
+func NewRetryService(next Service, opts ...option.RetryOption) (Service, error) {
+	config, err := option.NewRetryConfig(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create retry service: %w", err)
+	}
+
+	return &retryService{
+		next:   next,
+		config: config,
+	}, nil
+}
+
+func (r *retryService) Chat(ctx context.Context, messages []Message, opts ...CallOption) (string, error) {
+	return r.ChatWithModel(ctx, messages, "", opts...)
+}
+
+func (r *retryService) DefaultModels() []ServiceModel {
+	return DefaultModels(r.next)
+}
+
+func (r *retryService) ChatWithModel(ctx context.Context, messages []Message, model string, opts ...CallOption) (string, error) {
+	return AnswerContent(r.Complete(ctx, Request{
+		Model:    model,
+		Messages: messages,
+		Params:   NewGenerationParams(opts...),
+	}))
+}
+
+func (r *retryService) Complete(ctx context.Context, req Request) (Response, error) {
+	var resp Response
+	err := r.retry(ctx, func() error {
+		var err error
+		resp, err = r.next.Complete(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// ChatStream retries only opening of the stream, once any delta is sent the error is passed to the caller.
+func (r *retryService) ChatStream(ctx context.Context, messages []Message, model string, opts ...CallOption) (<-chan StreamChunk, error) {
+	var stream <-chan StreamChunk
+	var first StreamChunk
+	var opened bool
+	err := r.retry(ctx, func() error {
+		var err error
+		stream, err = r.next.ChatStream(ctx, messages, model, opts...)
+		if err != nil {
+			return err
+		}
+		first, opened = <-stream
+		if opened && first.Err != nil {
+			return first.Err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan StreamChunk)
+	go func() {
+		defer close(ch)
+		if !opened {
+			return
+		}
+		forwardStream(ctx, first, stream, ch)
+	}()
+	return ch, nil
+}
+
+func (r *retryService) retry(ctx context.Context, call func() error) error {
+	deadline := time.Now().Add(r.config.Budget)
+	for attempt := 0; ; attempt++ {
+		err := call()
+		if err == nil {
+			return nil
+		}
+		if attempt >= r.config.MaxRetries || !IsRetryable(err) {
+			return err
+		}
+
+		wait := r.backoff(attempt, err)
+		if time.Now().Add(wait).After(deadline) {
+			return fmt.Errorf("retry budget exceeded after %d attempts: %w", attempt+1, err)
+		}
+		log.Printf("AI call failed, retrying in %s (attempt %d): %v", wait, attempt+1, err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+func (r *retryService) backoff(attempt int, err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter
+	}
+
+	ceiling := r.config.InitialBackoff << attempt
+	if ceiling <= 0 || ceiling > r.config.MaxBackoff {
+		ceiling = r.config.MaxBackoff
+	}
+	// full jitter spreads parallel callers (e.g. scoring goroutines) which failed at the same moment
+	return min(r.config.InitialBackoff/2+rand.N(ceiling), r.config.MaxBackoff)
+}
+
+// IsRetryable reports transient errors: rate limits, server errors and network failures.
+// Validation errors (other 4xx) and context cancellation are never retried.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+			return true
+		}
+		return statusErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
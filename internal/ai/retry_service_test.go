@@ -0,0 +1,197 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failingService fails with errs in order and answers once they are used up.
+type failingService struct {
+	errs  []error
+	calls int
+}
+
+func (f *failingService) Chat(ctx context.Context, messages []Message, opts ...CallOption) (string, error) {
+	return f.ChatWithModel(ctx, messages, "", opts...)
+}
+
+func (f *failingService) ChatWithModel(ctx context.Context, messages []Message, model string, opts ...CallOption) (string, error) {
+	return AnswerContent(f.Complete(ctx, Request{Model: model, Messages: messages}))
+}
+
+func (f *failingService) ChatStream(ctx context.Context, messages []Message, model string, opts ...CallOption) (<-chan StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *failingService) Complete(ctx context.Context, req Request) (Response, error) {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return Response{}, f.errs[f.calls-1]
+	}
+	return Response{Message: AssistantMessage("ok"), FinishReason: FinishReasonStop}, nil
+}
+
+func TestRetryService(t *testing.T) {
+	rateLimited := &StatusError{StatusCode: http.StatusTooManyRequests, Err: errors.New("slow down")}
+	unavailable := &StatusError{StatusCode: http.StatusBadGateway, Err: errors.New("bad gateway")}
+	badRequest := &StatusError{StatusCode: http.StatusBadRequest, Err: errors.New("bad request")}
+
+	tests := []struct {
+		name       string
+		errs       []error
+		maxRetries int
+		wantCalls  int
+		wantErr    error
+	}{
+		{name: "no error", maxRetries: 3, wantCalls: 1},
+		{name: "rate limit then success", errs: []error{rateLimited, unavailable}, maxRetries: 3, wantCalls: 3},
+		{name: "network error", errs: []error{&net.OpError{Op: "dial", Err: errors.New("refused")}}, maxRetries: 3, wantCalls: 2},
+		{name: "retries exhausted", errs: []error{unavailable, unavailable, unavailable}, maxRetries: 2, wantCalls: 3, wantErr: ErrProviderUnavailable},
+		{name: "validation error isn't retried", errs: []error{badRequest}, maxRetries: 3, wantCalls: 1, wantErr: badRequest},
+		{name: "truncation isn't retried", errs: []error{&IncompleteError{Reason: FinishReasonLength}}, maxRetries: 3, wantCalls: 1, wantErr: ErrTruncated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &failingService{errs: tt.errs}
+			rs, err := NewRetryService(next, option.WithMaxRetries(tt.maxRetries), option.WithBackoff(time.Millisecond, 2*time.Millisecond))
+			if err != nil {
+				t.Fatalf("creating service: %v", err)
+			}
+
+			answer, err := rs.Chat(context.Background(), []Message{UserMessage("hi")})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil || answer != "ok" {
+				t.Fatalf("answer = %q, err = %v, want ok", answer, err)
+			}
+			if next.calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", next.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+		err     error
+		min     time.Duration
+		max     time.Duration
+	}{
+		{name: "first attempt", attempt: 0, err: ErrProviderUnavailable, min: 50 * time.Millisecond, max: 150 * time.Millisecond},
+		{name: "third attempt", attempt: 2, err: ErrProviderUnavailable, min: 50 * time.Millisecond, max: 450 * time.Millisecond},
+		{name: "capped by max backoff", attempt: 20, err: ErrProviderUnavailable, min: 50 * time.Millisecond, max: time.Second},
+		{name: "retry after", attempt: 0, err: &StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 7 * time.Second}, min: 7 * time.Second, max: 7 * time.Second},
+	}
+
+	config, err := option.NewRetryConfig(option.WithBackoff(100*time.Millisecond, time.Second))
+	if err != nil {
+		t.Fatalf("creating config: %v", err)
+	}
+	rs := &retryService{config: config}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 10_000 {
+				wait := rs.backoff(tt.attempt, tt.err)
+				if wait < tt.min || wait > tt.max {
+					t.Fatalf("backoff = %s, want between %s and %s", wait, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryBackoffNeverExceedsMax(t *testing.T) {
+	config, err := option.NewRetryConfig(option.WithBackoff(100*time.Millisecond, 150*time.Millisecond))
+	if err != nil {
+		t.Fatalf("creating config: %v", err)
+	}
+	rs := &retryService{config: config}
+
+	for attempt := range 64 {
+		for range 1_000 {
+			if wait := rs.backoff(attempt, ErrProviderUnavailable); wait > config.MaxBackoff {
+				t.Fatalf("attempt %d: backoff = %s, want at most %s", attempt, wait, config.MaxBackoff)
+			}
+		}
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	next := &failingService{errs: []error{&StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute}}}
+	rs, err := NewRetryService(next, option.WithRetryBudget(time.Second))
+	if err != nil {
+		t.Fatalf("creating service: %v", err)
+	}
+
+	start := time.Now()
+	_, err = rs.Chat(context.Background(), []Message{UserMessage("hi")})
+	if err == nil || !strings.Contains(err.Error(), "retry budget exceeded") || !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want budget exceeded wrapping rate limit", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("waited %s, retry after longer than budget shouldn't be waited for", time.Since(start))
+	}
+	if next.calls != 1 {
+		t.Errorf("calls = %d, want 1", next.calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{name: "missing", header: http.Header{}, want: 0},
+		{name: "seconds", header: http.Header{"Retry-After": {"3"}}, want: 3 * time.Second},
+		{name: "milliseconds win", header: http.Header{"Retry-After": {"3"}, "Retry-After-Ms": {"250"}}, want: 250 * time.Millisecond},
+		{name: "negative", header: http.Header{"Retry-After": {"-1"}}, want: 0},
+		{name: "past date", header: http.Header{"Retry-After": {"Wed, 21 Oct 2015 07:28:00 GMT"}}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.header); got != tt.want {
+				t.Errorf("parseRetryAfter = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestOpenaiClientDoesNotRetry checks the SDK retries are off, so they aren't multiplied by the retry service.
+func TestOpenaiClientDoesNotRetry(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"error": {"message": "overloaded", "type": "server_error"}}`))
+	}))
+	defer server.Close()
+
+	as, err := NewOpenaiService(option.WithApiKey("key"), option.WithBaseUrl(server.URL), option.WithBaseModel(OpenAiGPT4oMini))
+	if err != nil {
+		t.Fatalf("creating service: %v", err)
+	}
+	_, err = as.Chat(context.Background(), []Message{UserMessage("hi")})
+	if !errors.Is(err, ErrProviderUnavailable) {
+		t.Fatalf("err = %v, want provider unavailable", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("requests = %d, want 1", requests.Load())
+	}
+}
@@ -0,0 +1,117 @@
+package ai
+
+import (
+	"reflect"
+	"strings"
+)
+
+// JSONSchemaFor derives JSON schema of the value type in the strict form expected by response_format:
+// every field is required and no additional properties are allowed.
+// Maps and interfaces can't be described that way, check the schema with IsStrictSchema.
+func JSONSchemaFor(v any) map[string]any {
+	return schemaForType(reflect.TypeOf(v))
+}
+
+// IsStrictSchema reports whether OpenAI strict mode accepts the schema: every value has a type
+// and objects don't allow additional properties.
+func IsStrictSchema(schema map[string]any) bool {
+	if _, ok := schema["type"]; !ok {
+		return false
+	}
+	if schema["type"] == "object" && schema["additionalProperties"] != false {
+		return false
+	}
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		for _, property := range properties {
+			propertySchema, ok := property.(map[string]any)
+			if !ok || !IsStrictSchema(propertySchema) {
+				return false
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]any); ok && !IsStrictSchema(items) {
+		return false
+	}
+	return true
+}
+
+func schemaForType(t reflect.Type) map[string]any {
+	if t == nil {
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaForType(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{
+			"type":  "array",
+			"items": schemaForType(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]any{
+			"type":                 "object",
+			"additionalProperties": schemaForType(t.Elem()),
+		}
+	case reflect.Struct:
+		return schemaForStruct(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+func schemaForStruct(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		// fields of unexported embedded structs are promoted by encoding/json
+		if !field.IsExported() && !(field.Anonymous && field.Type.Kind() == reflect.Struct) {
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && name == field.Name {
+			embedded := schemaForStruct(field.Type)
+			for k, v := range embedded["properties"].(map[string]any) {
+				properties[k] = v
+			}
+			required = append(required, embedded["required"].([]string)...)
+			continue
+		}
+
+		fieldSchema := schemaForType(field.Type)
+		if description, ok := field.Tag.Lookup("description"); ok {
+			fieldSchema["description"] = description
+		}
+		properties[name] = fieldSchema
+		required = append(required, name)
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
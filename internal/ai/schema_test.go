@@ -0,0 +1,79 @@
+package ai
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONSchemaFor(t *testing.T) {
+	type embedded struct {
+		Note string `json:"note"`
+	}
+	type answer struct {
+		embedded
+		Title   string   `json:"title" description:"short title"`
+		Score   float64  `json:"score"`
+		Tags    []string `json:"tags,omitempty"`
+		Skipped string   `json:"-"`
+		hidden  string
+	}
+
+	schema := JSONSchemaFor(answer{})
+
+	wantProperties := map[string]any{
+		"note":  map[string]any{"type": "string"},
+		"title": map[string]any{"type": "string", "description": "short title"},
+		"score": map[string]any{"type": "number"},
+		"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+	}
+	if !reflect.DeepEqual(schema["properties"], wantProperties) {
+		t.Errorf("properties = %v, want %v", schema["properties"], wantProperties)
+	}
+	if got := schema["required"]; !reflect.DeepEqual(got, []string{"note", "title", "score", "tags"}) {
+		t.Errorf("required = %v", got)
+	}
+	if schema["additionalProperties"] != false {
+		t.Errorf("additionalProperties = %v, want false", schema["additionalProperties"])
+	}
+}
+
+func TestIsStrictSchema(t *testing.T) {
+	type nested struct {
+		Values []int `json:"values"`
+	}
+
+	tests := []struct {
+		name string
+		v    any
+		want bool
+	}{
+		{name: "scalars", v: struct {
+			A string `json:"a"`
+			B bool   `json:"b"`
+		}{}, want: true},
+		{name: "nested struct in slice", v: struct {
+			Items []nested `json:"items"`
+		}{}, want: true},
+		{name: "pointer", v: &nested{}, want: true},
+		{name: "map field", v: struct {
+			Labels map[string]string `json:"labels"`
+		}{}, want: false},
+		{name: "interface field", v: struct {
+			Value any `json:"value"`
+		}{}, want: false},
+		{name: "slice of interfaces", v: struct {
+			Values []any `json:"values"`
+		}{}, want: false},
+		{name: "map of structs in slice", v: struct {
+			Items []map[string]nested `json:"items"`
+		}{}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsStrictSchema(JSONSchemaFor(tt.v)); got != tt.want {
+				t.Errorf("IsStrictSchema() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
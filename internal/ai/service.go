@@ -10,9 +10,39 @@ const (
 	OpenRouterModelGPT4oMini     string = "openai/gpt-4o-mini"
 	OpenRouterModelGPT41Nano     string = "openai/gpt-4.1-nano"
 	OpenRouterModelGemini25Flash string = "google/gemini-2.5-flash-preview"
+
+	AnthropicClaudeSonnet4 string = "claude-sonnet-4-20250514"
+	AnthropicClaude35Haiku string = "claude-3-5-haiku-latest"
+
+	OpenAiTextEmbedding3Small string = "text-embedding-3-small"
+	OpenAiTextEmbedding3Large string = "text-embedding-3-large"
 )
 
 type Service interface {
-	Chat(ctx context.Context, messages []Message) (string, error)
-	ChatWithModel(ctx context.Context, messages []Message, model string) (string, error)
+	Chat(ctx context.Context, messages []Message, opts ...CallOption) (string, error)
+	ChatWithModel(ctx context.Context, messages []Message, model string, opts ...CallOption) (string, error)
+	// ChatStream sends the answer in deltas, empty model means the default one.
+	ChatStream(ctx context.Context, messages []Message, model string, opts ...CallOption) (<-chan StreamChunk, error)
+	Complete(ctx context.Context, req Request) (Response, error)
+}
+
+// ServiceModel is a model answering requests which don't set one.
+type ServiceModel struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+}
+
+// DefaultModeler is implemented by providers and decorators which know their default models.
+type DefaultModeler interface {
+	DefaultModels() []ServiceModel
+}
+
+// DefaultModels returns models used for requests without model in the order they are asked, a fallback chain
+// returns all its targets. It's empty when the service doesn't tell, e.g. fakes in tests.
+func DefaultModels(as Service) []ServiceModel {
+	modeler, ok := as.(DefaultModeler)
+	if !ok {
+		return nil
+	}
+	return modeler.DefaultModels()
 }
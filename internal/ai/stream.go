@@ -0,0 +1,39 @@
+package ai
+
+import (
+	"context"
+	"strings"
+)
+
+type StreamChunk struct {
+	Content string
+	Err     error
+}
+
+// CollectStream drains the stream and returns the whole answer.
+func CollectStream(stream <-chan StreamChunk) (string, error) {
+	builder := strings.Builder{}
+	for chunk := range stream {
+		if chunk.Err != nil {
+			return "", chunk.Err
+		}
+		builder.WriteString(chunk.Content)
+	}
+	return builder.String(), nil
+}
+
+// forwardStream sends already received first chunk and the rest of the stream to out.
+func forwardStream(ctx context.Context, first StreamChunk, stream <-chan StreamChunk, out chan<- StreamChunk) {
+	select {
+	case out <- first:
+	case <-ctx.Done():
+		return
+	}
+	for chunk := range stream {
+		select {
+		case out <- chunk:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
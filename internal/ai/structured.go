@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ResponseFormat asks the provider for JSON matching the schema, providers without support ignore it.
+type ResponseFormat struct {
+	Name   string
+	Schema map[string]any
+	// Strict is false for schemas with maps or interfaces, strict mode of OpenAI rejects them.
+	Strict bool
+}
+
+// Validator is checked after unmarshalling, the error is given back to the model to repair its answer.
+type Validator interface {
+	Validate() error
+}
+
+// CompleteStructured calls the model for JSON answer of type T and re-prompts it with the parsing or
+// validation error up to maxRepairs times.
+func CompleteStructured[T any](ctx context.Context, as Service, req Request, maxRepairs int) (T, error) {
+	var zero T
+	schema := JSONSchemaFor(zero)
+	req.ResponseFormat = &ResponseFormat{
+		Name:   schemaName(reflect.TypeOf(zero)),
+		Schema: schema,
+		Strict: IsStrictSchema(schema),
+	}
+	req.Messages = append([]Message{}, req.Messages...)
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRepairs; attempt++ {
+		resp, err := as.Complete(ctx, req)
+		if err != nil {
+			return zero, err
+		}
+		// repair prompt doesn't help with truncated or filtered answers
+		if err := checkFinishReason(resp); err != nil {
+			return zero, err
+		}
+
+		result, err := ParseStructured[T](resp.Message.Content)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+
+		req.Messages = append(req.Messages,
+			AssistantMessage(resp.Message.Content),
+			UserMessage(fmt.Sprintf("Your previous answer is invalid: %v. Write back with corrected JSON only, without any other text.", err)),
+		)
+	}
+
+	return zero, fmt.Errorf("no valid structured answer after %d repairs: %w", maxRepairs, lastErr)
+}
+
+// ParseStructured strips markdown fences and text around the JSON before unmarshalling it.
+func ParseStructured[T any](answer string) (T, error) {
+	var result T
+	err := json.Unmarshal([]byte(extractJSON(answer)), &result)
+	if err != nil {
+		return result, fmt.Errorf("parse json: %w", err)
+	}
+
+	if validator, ok := any(&result).(Validator); ok {
+		err = validator.Validate()
+		if err != nil {
+			return result, fmt.Errorf("validate: %w", err)
+		}
+	}
+	return result, nil
+}
+
+func extractJSON(answer string) string {
+	answer = strings.TrimSpace(answer)
+	if strings.HasPrefix(answer, "```") {
+		answer = strings.TrimPrefix(answer, "```")
+		answer = strings.TrimPrefix(answer, "json")
+		answer = strings.TrimSuffix(strings.TrimSpace(answer), "```")
+		answer = strings.TrimSpace(answer)
+	}
+
+	start := strings.IndexAny(answer, "{[")
+	if start < 0 {
+		return answer
+	}
+	end := strings.LastIndexAny(answer, "}]")
+	if end < start {
+		return answer
+	}
+	return answer[start : end+1]
+}
+
+func schemaName(t reflect.Type) string {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Name() == "" {
+		return "response"
+	}
+	return strings.ToLower(t.Name())
+}
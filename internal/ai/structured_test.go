@@ -0,0 +1,170 @@
+package ai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type rating struct {
+	Score int `json:"score"`
+}
+
+func (r *rating) Validate() error {
+	if r.Score < 1 || r.Score > 5 {
+		return errors.New("score must be from 1 to 5")
+	}
+	return nil
+}
+
+func TestParseStructured(t *testing.T) {
+	tests := []struct {
+		name    string
+		answer  string
+		want    int
+		wantErr bool
+	}{
+		{name: "plain json", answer: `{"score": 3}`, want: 3},
+		{name: "markdown fence", answer: "```json\n{\"score\": 4}\n```", want: 4},
+		{name: "text around", answer: `Sure! {"score": 2} Hope it helps.`, want: 2},
+		{name: "invalid json", answer: `{"score": }`, wantErr: true},
+		{name: "validation error", answer: `{"score": 9}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ai.ParseStructured[rating](tt.answer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Score != tt.want {
+				t.Errorf("score = %d, want %d", got.Score, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompleteStructuredRepairs(t *testing.T) {
+	tests := []struct {
+		name       string
+		answers    []string
+		maxRepairs int
+		want       int
+		wantCalls  int
+		wantErr    bool
+	}{
+		{name: "valid at once", answers: []string{`{"score": 5}`}, maxRepairs: 2, want: 5, wantCalls: 1},
+		{name: "repaired", answers: []string{`not json`, `{"score": 7}`, `{"score": 2}`}, maxRepairs: 2, want: 2, wantCalls: 3},
+		{name: "repairs exhausted", answers: []string{`nope`, `nope`}, maxRepairs: 1, wantCalls: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := testsupport.NewFakeService()
+			for _, answer := range tt.answers {
+				fake.On(ai.User, ".").Reply(answer).Times(1)
+			}
+
+			got, err := ai.CompleteStructured[rating](context.Background(), fake, ai.Request{
+				Messages: []ai.Message{ai.UserMessage("rate it")},
+			}, tt.maxRepairs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Score != tt.want {
+				t.Errorf("score = %d, want %d", got.Score, tt.want)
+			}
+			calls := fake.Calls()
+			if len(calls) != tt.wantCalls {
+				t.Fatalf("calls = %d, want %d", len(calls), tt.wantCalls)
+			}
+			if calls[0].ResponseFormat == nil || !calls[0].ResponseFormat.Strict {
+				t.Errorf("response format = %+v, want strict schema", calls[0].ResponseFormat)
+			}
+		})
+	}
+}
+
+func TestCompleteStructuredFailsOnTruncatedAnswer(t *testing.T) {
+	fake := testsupport.NewFakeService()
+	fake.On(ai.User, ".").ReplyResponse(ai.Response{
+		Message:      ai.AssistantMessage(`{"score":`),
+		FinishReason: ai.FinishReasonLength,
+	})
+
+	_, err := ai.CompleteStructured[rating](context.Background(), fake, ai.Request{
+		Messages: []ai.Message{ai.UserMessage("rate it")},
+	}, 2)
+	if !errors.Is(err, ai.ErrTruncated) {
+		t.Fatalf("err = %v, want ErrTruncated", err)
+	}
+	if got := len(fake.Calls()); got != 1 {
+		t.Errorf("calls = %d, want 1 without repairs", got)
+	}
+}
+
+func TestOpenaiStrictResponseFormat(t *testing.T) {
+	type labels struct {
+		Labels map[string]string `json:"labels"`
+	}
+
+	tests := []struct {
+		name       string
+		complete   func(context.Context, ai.Service) error
+		wantStrict bool
+	}{
+		{
+			name: "struct",
+			complete: func(ctx context.Context, as ai.Service) error {
+				_, err := ai.CompleteStructured[rating](ctx, as, ai.Request{Messages: []ai.Message{ai.UserMessage("rate")}}, 0)
+				return err
+			},
+			wantStrict: true,
+		},
+		{
+			name: "map field",
+			complete: func(ctx context.Context, as ai.Service) error {
+				_, err := ai.CompleteStructured[labels](ctx, as, ai.Request{Messages: []ai.Message{ai.UserMessage("label")}}, 0)
+				return err
+			},
+			wantStrict: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body struct {
+				ResponseFormat struct {
+					JSONSchema struct {
+						Strict bool `json:"strict"`
+					} `json:"json_schema"`
+				} `json:"response_format"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"id":"1","object":"chat.completion","model":"gpt-4o-mini","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"{\"score\":3,\"labels\":{}}"}}]}`))
+			}))
+			defer server.Close()
+
+			as, err := ai.NewOpenaiService(option.WithApiKey("key"), option.WithBaseUrl(server.URL), option.WithBaseModel("gpt-4o-mini"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = tt.complete(context.Background(), as)
+			if err != nil && !strings.Contains(err.Error(), "validate") {
+				t.Fatal(err)
+			}
+			if body.ResponseFormat.JSONSchema.Strict != tt.wantStrict {
+				t.Errorf("strict = %v, want %v", body.ResponseFormat.JSONSchema.Strict, tt.wantStrict)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package ai
+
+import (
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/models"
+	"github.com/pkoukk/tiktoken-go"
+	tiktokenloader "github.com/pkoukk/tiktoken-go-loader"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+type Encoding string
+
+const (
+	EncodingCL100K Encoding = "cl100k_base"
+	EncodingO200K  Encoding = "o200k_base"
+)
+
+// Per-message overhead of OpenAI chat format, the reply is primed with 3 more tokens.
+const (
+	tokensPerMessage = 3
+	tokensPerReply   = 3
+)
+
+// Tokenizer counts tokens with OpenAI BPE encodings, ranks are embedded so it works offline.
+type Tokenizer struct {
+	encoding Encoding
+	bpe      *tiktoken.Tiktoken
+}
+
+var (
+	tokenizersMu sync.Mutex
+	tokenizers   = map[Encoding]*Tokenizer{}
+	loaderOnce   sync.Once
+)
+
+func NewTokenizer(encoding Encoding) (*Tokenizer, error) {
+	loaderOnce.Do(func() {
+		tiktoken.SetBpeLoader(tiktokenloader.NewOfflineLoader())
+	})
+
+	tokenizersMu.Lock()
+	defer tokenizersMu.Unlock()
+	if tokenizer, ok := tokenizers[encoding]; ok {
+		return tokenizer, nil
+	}
+
+	bpe, err := tiktoken.GetEncoding(string(encoding))
+	if err != nil {
+		return nil, fmt.Errorf("loading %s encoding: %w", encoding, err)
+	}
+	tokenizer := &Tokenizer{
+		encoding: encoding,
+		bpe:      bpe,
+	}
+	tokenizers[encoding] = tokenizer
+	return tokenizer, nil
+}
+
+// TokenizerForModel picks the encoding from the model registry, cl100k_base approximates models of other vendors.
+func TokenizerForModel(model string) (*Tokenizer, error) {
+	encoding := EncodingCL100K
+	info, ok := models.Lookup(model)
+	if ok && info.Encoding != "" {
+		encoding = Encoding(info.Encoding)
+	}
+	return NewTokenizer(encoding)
+}
+
+func (t *Tokenizer) Encoding() Encoding {
+	return t.encoding
+}
+
+func (t *Tokenizer) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+	return len(t.bpe.EncodeOrdinary(text))
+}
+
+// CountMessages counts the prompt like OpenAI does, including per-message overhead and reply priming.
+func (t *Tokenizer) CountMessages(messages []Message) int {
+	tokens := tokensPerReply
+	for _, message := range messages {
+		tokens += tokensPerMessage + t.Count(string(message.Role)) + t.Count(message.Content)
+		for _, part := range message.Parts {
+			if part.Type == ContentPartText {
+				tokens += t.Count(part.Text)
+				continue
+			}
+			tokens += estimatePartTokens(part)
+		}
+		for _, toolCall := range message.ToolCalls {
+			tokens += t.Count(toolCall.Name) + t.Count(toolCall.Arguments)
+		}
+	}
+	return tokens
+}
+
+// Truncate cuts the text to at most maxTokens tokens, cut multibyte characters are dropped.
+func (t *Tokenizer) Truncate(text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+	tokens := t.bpe.EncodeOrdinary(text)
+	if len(tokens) <= maxTokens {
+		return text
+	}
+	truncated := t.bpe.Decode(tokens[:maxTokens])
+	for len(truncated) > 0 && !utf8.ValidString(truncated) {
+		truncated = truncated[:len(truncated)-1]
+	}
+	return strings.ToValidUTF8(truncated, "")
+}
@@ -0,0 +1,125 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+const defaultMaxToolSteps = 10
+
+// ToolDefinition describes a function for the model, Parameters is a JSON schema object.
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Parameters  map[string]any
+}
+
+type ToolFunc func(ctx context.Context, arguments string) (string, error)
+
+type ToolHandler struct {
+	Definition ToolDefinition
+	Func       ToolFunc
+}
+
+func NewTool(name, description string, parameters map[string]any, fn ToolFunc) ToolHandler {
+	return ToolHandler{
+		Definition: ToolDefinition{
+			Name:        name,
+			Description: description,
+			Parameters:  parameters,
+		},
+		Func: fn,
+	}
+}
+
+// ToolRunner keeps calling the model and executing requested tools until it gives final answer.
+type ToolRunner struct {
+	as    Service
+	tools map[string]ToolHandler
+	// definitions keep registration order, so prompts and cache keys don't change between runs
+	definitions []ToolDefinition
+	maxSteps    int
+}
+
+func NewToolRunner(as Service, maxSteps int, tools ...ToolHandler) *ToolRunner {
+	if maxSteps <= 0 {
+		maxSteps = defaultMaxToolSteps
+	}
+
+	registered := make(map[string]ToolHandler, len(tools))
+	var definitions []ToolDefinition
+	for _, tool := range tools {
+		if _, ok := registered[tool.Definition.Name]; !ok {
+			definitions = append(definitions, tool.Definition)
+		} else {
+			for i := range definitions {
+				if definitions[i].Name == tool.Definition.Name {
+					definitions[i] = tool.Definition
+				}
+			}
+		}
+		registered[tool.Definition.Name] = tool
+	}
+
+	return &ToolRunner{
+		as:          as,
+		tools:       registered,
+		definitions: definitions,
+		maxSteps:    maxSteps,
+	}
+}
+
+// Run returns the final response and all messages exchanged on the way (tool calls, tool results and the answer).
+func (t *ToolRunner) Run(ctx context.Context, req Request) (Response, []Message, error) {
+	req.Tools = append(append([]ToolDefinition{}, req.Tools...), t.definitions...)
+
+	messages := append([]Message{}, req.Messages...)
+	var exchanged []Message
+	for step := 0; step < t.maxSteps; step++ {
+		req.Messages = messages
+		resp, err := t.as.Complete(ctx, req)
+		if err != nil {
+			return Response{}, exchanged, fmt.Errorf("tool step %d: %w", step, err)
+		}
+		messages = append(messages, resp.Message)
+		exchanged = append(exchanged, resp.Message)
+
+		if len(resp.Message.ToolCalls) == 0 {
+			return resp, exchanged, checkFinishReason(resp)
+		}
+
+		results := t.execute(ctx, resp.Message.ToolCalls)
+		messages = append(messages, results...)
+		exchanged = append(exchanged, results...)
+	}
+
+	return Response{}, exchanged, fmt.Errorf("no final answer after %d tool steps", t.maxSteps)
+}
+
+func (t *ToolRunner) execute(ctx context.Context, calls []ToolCall) []Message {
+	results := make([]Message, len(calls))
+	wg := sync.WaitGroup{}
+	for i, call := range calls {
+		wg.Add(1)
+		go func(i int, call ToolCall) {
+			defer wg.Done()
+			results[i] = ToolMessage(call.ID, t.call(ctx, call))
+		}(i, call)
+	}
+	wg.Wait()
+	return results
+}
+
+// call never fails, errors are given back to the model so it can fix arguments or answer without the tool.
+func (t *ToolRunner) call(ctx context.Context, call ToolCall) string {
+	tool, ok := t.tools[call.Name]
+	if !ok {
+		return fmt.Sprintf("error: unknown tool %q", call.Name)
+	}
+	result, err := tool.Func(ctx, call.Arguments)
+	if err != nil {
+		return fmt.Sprintf("error: %v", err)
+	}
+	return result
+}
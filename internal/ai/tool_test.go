@@ -0,0 +1,112 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"reflect"
+	"testing"
+)
+
+func TestToolRunnerSendsToolsInRegistrationOrder(t *testing.T) {
+	names := []string{"weather", "clock", "search", "add", "zip"}
+	var tools []ai.ToolHandler
+	for _, name := range names {
+		tools = append(tools, ai.NewTool(name, "", nil, func(context.Context, string) (string, error) {
+			return "", nil
+		}))
+	}
+
+	for i := 0; i < 10; i++ {
+		fake := testsupport.NewFakeService()
+		fake.On(ai.User, ".").Reply("done")
+
+		_, _, err := ai.NewToolRunner(fake, 0, tools...).Run(context.Background(), ai.Request{
+			Messages: []ai.Message{ai.UserMessage("hi")},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var sent []string
+		for _, tool := range fake.Calls()[0].Tools {
+			sent = append(sent, tool.Name)
+		}
+		if !reflect.DeepEqual(sent, names) {
+			t.Fatalf("tools sent in order %v, want %v", sent, names)
+		}
+	}
+}
+
+func TestToolRunnerExecutesToolCalls(t *testing.T) {
+	tests := []struct {
+		name       string
+		call       ai.ToolCall
+		wantResult string
+	}{
+		{
+			name:       "known tool",
+			call:       ai.ToolCall{ID: "1", Name: "echo", Arguments: `{"text":"hi"}`},
+			wantResult: `{"text":"hi"}`,
+		},
+		{
+			name:       "unknown tool",
+			call:       ai.ToolCall{ID: "1", Name: "missing"},
+			wantResult: `error: unknown tool "missing"`,
+		},
+		{
+			name:       "failing tool",
+			call:       ai.ToolCall{ID: "1", Name: "fail"},
+			wantResult: "error: boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := testsupport.NewFakeService()
+			fake.On(ai.Tool, ".").Reply("final")
+			fake.On(ai.User, ".").ReplyResponse(ai.Response{
+				Message:      ai.Message{Role: ai.Assistant, ToolCalls: []ai.ToolCall{tt.call}},
+				FinishReason: ai.FinishReasonToolCalls,
+			})
+
+			runner := ai.NewToolRunner(fake, 3,
+				ai.NewTool("echo", "", nil, func(_ context.Context, arguments string) (string, error) {
+					return arguments, nil
+				}),
+				ai.NewTool("fail", "", nil, func(context.Context, string) (string, error) {
+					return "", errors.New("boom")
+				}),
+			)
+			resp, exchanged, err := runner.Run(context.Background(), ai.Request{Messages: []ai.Message{ai.UserMessage("hi")}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Message.Content != "final" {
+				t.Errorf("answer = %q, want final", resp.Message.Content)
+			}
+			if len(exchanged) != 3 || exchanged[1].Content != tt.wantResult {
+				t.Errorf("exchanged = %+v, want tool result %q", exchanged, tt.wantResult)
+			}
+		})
+	}
+}
+
+func TestToolRunnerStopsAfterMaxSteps(t *testing.T) {
+	fake := testsupport.NewFakeService()
+	fake.On(ai.User, ".").ReplyResponse(ai.Response{
+		Message: ai.Message{Role: ai.Assistant, ToolCalls: []ai.ToolCall{{ID: "1", Name: "loop"}}},
+	})
+	runner := ai.NewToolRunner(fake, 2, ai.NewTool("loop", "", nil, func(context.Context, string) (string, error) {
+		return "again", nil
+	}))
+
+	_, _, err := runner.Run(context.Background(), ai.Request{Messages: []ai.Message{ai.UserMessage("hi")}})
+	if err == nil {
+		t.Fatal("expected error after max steps")
+	}
+	if got := len(fake.Calls()); got != 2 {
+		t.Errorf("calls = %d, want 2", got)
+	}
+}
@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"context"
+	"sync"
+)
+
+type Usage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	// CachedTokens is the part of PromptTokens read from provider prompt cache.
+	CachedTokens int64 `json:"cached_tokens"`
+}
+
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		CachedTokens:     u.CachedTokens + other.CachedTokens,
+	}
+}
+
+func (u Usage) TotalTokens() int64 {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// UsageTracker sums usage of every call made with its context, e.g. whole websearch request.
+// Trackers nest, usage of the inner one is added to the outer one too, e.g. to get usage of one step of the request.
+type UsageTracker struct {
+	mu      sync.Mutex
+	usage   Usage
+	costUSD float64
+	byModel map[string]Usage
+	parent  *UsageTracker
+}
+
+type usageTrackerKey struct{}
+
+func WithUsageTracker(ctx context.Context) (context.Context, *UsageTracker) {
+	parent, _ := UsageTrackerFrom(ctx)
+	tracker := &UsageTracker{
+		byModel: map[string]Usage{},
+		parent:  parent,
+	}
+	return context.WithValue(ctx, usageTrackerKey{}, tracker), tracker
+}
+
+func UsageTrackerFrom(ctx context.Context) (*UsageTracker, bool) {
+	tracker, ok := ctx.Value(usageTrackerKey{}).(*UsageTracker)
+	return tracker, ok
+}
+
+// recordUsage is called by providers after every call which reached the API.
+func recordUsage(ctx context.Context, model string, usage Usage) {
+	tracker, ok := UsageTrackerFrom(ctx)
+	if !ok {
+		return
+	}
+	tracker.Add(model, usage)
+}
+
+func (t *UsageTracker) Add(model string, usage Usage) {
+	cost, _ := Cost(model, usage)
+
+	t.mu.Lock()
+	t.usage = t.usage.Add(usage)
+	t.costUSD += cost
+	t.byModel[model] = t.byModel[model].Add(usage)
+	t.mu.Unlock()
+
+	if t.parent != nil {
+		t.parent.Add(model, usage)
+	}
+}
+
+func (t *UsageTracker) Usage() Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.usage
+}
+
+// CostUSD skips models missing in the pricing table.
+func (t *UsageTracker) CostUSD() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.costUSD
+}
+
+func (t *UsageTracker) UsageByModel() map[string]Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result := make(map[string]Usage, len(t.byModel))
+	for model, usage := range t.byModel {
+		result[model] = usage
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+package ai_test
+
+import (
+	"context"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func sameCost(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCost(t *testing.T) {
+	tests := []struct {
+		name   string
+		model  string
+		usage  ai.Usage
+		want   float64
+		wantOk bool
+	}{
+		{name: "input and output", model: "gpt-4o-mini", usage: ai.Usage{PromptTokens: 1_000_000, CompletionTokens: 1_000_000}, want: 0.75, wantOk: true},
+		{name: "cached input", model: "gpt-4o-mini", usage: ai.Usage{PromptTokens: 1_000_000, CachedTokens: 400_000, CompletionTokens: 1_000_000}, want: 0.72, wantOk: true},
+		{name: "only cached input", model: "openai/gpt-4.1-nano", usage: ai.Usage{PromptTokens: 2_000_000, CachedTokens: 2_000_000}, want: 0.05, wantOk: true},
+		{name: "known model without pricing", model: "llama3.2", usage: ai.Usage{PromptTokens: 1_000_000}, wantOk: true},
+		{name: "unknown model", model: "mistral", usage: ai.Usage{PromptTokens: 1_000_000, CompletionTokens: 1_000_000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ai.Cost(tt.model, tt.usage)
+			if !sameCost(got, tt.want) || ok != tt.wantOk {
+				t.Errorf("cost = %v, %t, want %v, %t", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestUsageTracker(t *testing.T) {
+	ctx, outer := ai.WithUsageTracker(context.Background())
+	_, inner := ai.WithUsageTracker(ctx)
+
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			inner.Add("gpt-4o-mini", ai.Usage{PromptTokens: 10_000, CachedTokens: 4_000, CompletionTokens: 10_000})
+		}()
+		go func() {
+			defer wg.Done()
+			outer.Add("mistral", ai.Usage{PromptTokens: 1, CompletionTokens: 2})
+		}()
+	}
+	wg.Wait()
+
+	if got, want := inner.Usage(), (ai.Usage{PromptTokens: 1_000_000, CachedTokens: 400_000, CompletionTokens: 1_000_000}); got != want {
+		t.Errorf("inner usage = %+v, want %+v", got, want)
+	}
+	if got := inner.CostUSD(); !sameCost(got, 0.72) {
+		t.Errorf("inner cost = %v, want 0.72", got)
+	}
+
+	wantByModel := map[string]ai.Usage{
+		"gpt-4o-mini": {PromptTokens: 1_000_000, CachedTokens: 400_000, CompletionTokens: 1_000_000},
+		"mistral":     {PromptTokens: 100, CompletionTokens: 200},
+	}
+	if got := outer.UsageByModel(); !reflect.DeepEqual(got, wantByModel) {
+		t.Errorf("outer usage by model = %+v, want %+v", got, wantByModel)
+	}
+	if got := outer.Usage().TotalTokens(); got != 2_000_300 {
+		t.Errorf("outer total tokens = %d, want 2000300", got)
+	}
+	// unknown models are counted but cost nothing
+	if got := outer.CostUSD(); !sameCost(got, 0.72) {
+		t.Errorf("outer cost = %v, want 0.72", got)
+	}
+}
+
+func TestUsageTrackerFrom(t *testing.T) {
+	if _, ok := ai.UsageTrackerFrom(context.Background()); ok {
+		t.Error("tracker of plain context: want none")
+	}
+	ctx, tracker := ai.WithUsageTracker(context.Background())
+	if got, ok := ai.UsageTrackerFrom(ctx); !ok || got != tracker {
+		t.Errorf("tracker = %p, want %p", got, tracker)
+	}
+}
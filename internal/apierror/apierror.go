@@ -0,0 +1,92 @@
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+const (
+	CodeRateLimited           = "rate_limited"
+	CodeAuth                  = "auth"
+	CodeContextLengthExceeded = "context_length_exceeded"
+	CodeContentFiltered       = "content_filtered"
+	CodeTruncated             = "truncated"
+	CodeProviderUnavailable   = "provider_unavailable"
+	CodeTimeout               = "timeout"
+	CodeInternal              = "internal"
+	CodeInvalidRequest        = "invalid_request"
+	CodeNotFound              = "not_found"
+)
+
+// Error is the JSON body of failed responses, internal details are only logged.
+type Error struct {
+	Status     int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"error"`
+	RetryAfter int    `json:"retry_after,omitempty"`
+}
+
+// FromError maps errors of AI calls to HTTP status codes, unknown errors are 500.
+func FromError(err error) Error {
+	switch {
+	case errors.Is(err, ai.ErrRateLimited):
+		apiErr := Error{Status: http.StatusTooManyRequests, Code: CodeRateLimited, Message: "AI provider rate limit exceeded, retry later"}
+		var statusErr *ai.StatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+			apiErr.RetryAfter = int(math.Ceil(statusErr.RetryAfter.Seconds()))
+		}
+		return apiErr
+	case errors.Is(err, ai.ErrAuth):
+		// credentials of the server are wrong, not the ones of the client
+		return Error{Status: http.StatusBadGateway, Code: CodeAuth, Message: "AI provider rejected credentials of the server"}
+	case errors.Is(err, ai.ErrContextLengthExceeded):
+		return Error{Status: http.StatusRequestEntityTooLarge, Code: CodeContextLengthExceeded, Message: "message doesn't fit context window of the model"}
+	case errors.Is(err, ai.ErrInvalidRequest):
+		return Error{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Message: "request can't be sent to AI provider"}
+	case errors.Is(err, ai.ErrContentFiltered):
+		return Error{Status: http.StatusUnprocessableEntity, Code: CodeContentFiltered, Message: "answer was refused by content filter"}
+	case errors.Is(err, ai.ErrTruncated):
+		return Error{Status: http.StatusBadGateway, Code: CodeTruncated, Message: "answer was truncated by max tokens"}
+	case errors.Is(err, context.DeadlineExceeded):
+		return Error{Status: http.StatusGatewayTimeout, Code: CodeTimeout, Message: "AI provider didn't answer in time"}
+	case errors.Is(err, ai.ErrProviderUnavailable), isNetworkError(err):
+		return Error{Status: http.StatusServiceUnavailable, Code: CodeProviderUnavailable, Message: "AI provider is unavailable, retry later"}
+	default:
+		return Error{Status: http.StatusInternalServerError, Code: CodeInternal, Message: "internal error"}
+	}
+}
+
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// Write logs the error and writes it as JSON body with mapped status code.
+func Write(w http.ResponseWriter, message string, err error) {
+	log.Printf("%s: %v", message, err)
+	WriteError(w, FromError(err))
+}
+
+// BadRequest writes the client error, the message is sent as is so it mustn't contain internal details.
+func BadRequest(w http.ResponseWriter, message string) {
+	WriteError(w, Error{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Message: message})
+}
+
+func WriteError(w http.ResponseWriter, apiErr Error) {
+	w.Header().Set("Content-Type", "application/json")
+	if apiErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(apiErr.RetryAfter))
+	}
+	w.WriteHeader(apiErr.Status)
+	err := json.NewEncoder(w).Encode(apiErr)
+	if err != nil {
+		log.Printf("failed to encode error response: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package apierror_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/apierror"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantStatus     int
+		wantCode       string
+		wantRetryAfter int
+	}{
+		{name: "rate limited", err: ai.ErrRateLimited, wantStatus: http.StatusTooManyRequests, wantCode: apierror.CodeRateLimited},
+		{
+			name:           "rate limited with retry after",
+			err:            fmt.Errorf("chat: %w", &ai.StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 1500 * time.Millisecond, Err: ai.ErrRateLimited}),
+			wantStatus:     http.StatusTooManyRequests,
+			wantCode:       apierror.CodeRateLimited,
+			wantRetryAfter: 2,
+		},
+		{name: "auth", err: ai.ErrAuth, wantStatus: http.StatusBadGateway, wantCode: apierror.CodeAuth},
+		{name: "context length", err: ai.ErrContextLengthExceeded, wantStatus: http.StatusRequestEntityTooLarge, wantCode: apierror.CodeContextLengthExceeded},
+		{name: "invalid request", err: ai.ErrInvalidRequest, wantStatus: http.StatusBadRequest, wantCode: apierror.CodeInvalidRequest},
+		{name: "content filter", err: ai.ErrContentFiltered, wantStatus: http.StatusUnprocessableEntity, wantCode: apierror.CodeContentFiltered},
+		{name: "truncated", err: ai.ErrTruncated, wantStatus: http.StatusBadGateway, wantCode: apierror.CodeTruncated},
+		{name: "timeout", err: fmt.Errorf("chat: %w", context.DeadlineExceeded), wantStatus: http.StatusGatewayTimeout, wantCode: apierror.CodeTimeout},
+		{name: "provider unavailable", err: ai.ErrProviderUnavailable, wantStatus: http.StatusServiceUnavailable, wantCode: apierror.CodeProviderUnavailable},
+		{name: "network", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, wantStatus: http.StatusServiceUnavailable, wantCode: apierror.CodeProviderUnavailable},
+		{name: "unknown", err: errors.New("secret database password leaked"), wantStatus: http.StatusInternalServerError, wantCode: apierror.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			apierror.Write(w, "failed", tt.err)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("content type = %q, want JSON", contentType)
+			}
+			wantHeader := ""
+			if tt.wantRetryAfter > 0 {
+				wantHeader = strconv.Itoa(tt.wantRetryAfter)
+			}
+			if retryAfter := w.Header().Get("Retry-After"); retryAfter != wantHeader {
+				t.Errorf("Retry-After = %q, want %q", retryAfter, wantHeader)
+			}
+			body := w.Body.String()
+			var got struct {
+				Code       string `json:"code"`
+				Error      string `json:"error"`
+				RetryAfter int    `json:"retry_after"`
+			}
+			if err := json.Unmarshal([]byte(body), &got); err != nil {
+				t.Fatalf("body %s: %v", body, err)
+			}
+			if got.Code != tt.wantCode || got.Error == "" || got.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("body = %s, want code %s with message", body, tt.wantCode)
+			}
+			if tt.wantCode == apierror.CodeInternal && strings.Contains(body, tt.err.Error()) {
+				t.Errorf("body %s leaks the internal error", body)
+			}
+		})
+	}
+}
+
+func TestBadRequest(t *testing.T) {
+	w := httptest.NewRecorder()
+	apierror.BadRequest(w, "message is required")
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != `{"code":"invalid_request","error":"message is required"}` {
+		t.Errorf("body = %s", body)
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"net/http"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// RequestIDMiddleware takes X-Request-ID of the request or generates a new one, AI calls of the request are tagged with it.
+func RequestIDMiddleware(fn func(w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+		fn(w, r.WithContext(ai.WithRequestID(r.Context(), requestID)))
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	generated := regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{name: "generated"},
+		{name: "propagated", header: "req-from-proxy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inContext string
+			handler := RequestIDMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				inContext = ai.RequestIDFrom(r.Context())
+			})
+			r := httptest.NewRequest(http.MethodPost, "/api/thread", nil)
+			if tt.header != "" {
+				r.Header.Set("X-Request-ID", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler(w, r)
+
+			requestID := w.Header().Get("X-Request-ID")
+			if requestID != inContext {
+				t.Errorf("response id %q, context id %q", requestID, inContext)
+			}
+			if tt.header != "" && requestID != tt.header {
+				t.Errorf("request id = %q, want %q", requestID, tt.header)
+			}
+			if tt.header == "" && !generated.MatchString(requestID) {
+				t.Errorf("request id = %q, want 16 hex characters", requestID)
+			}
+		})
+	}
+}
+
+func TestNewRequestIDIsUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for range 100 {
+		id := newRequestID()
+		if seen[id] {
+			t.Fatalf("duplicate request id %q", id)
+		}
+		seen[id] = true
+	}
+}
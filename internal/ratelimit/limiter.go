@@ -0,0 +1,170 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter bounds in-flight requests and requests/tokens per minute, zero value of each limit means no limit.
+// Nil Limiter doesn't limit anything.
+type Limiter struct {
+	inFlight chan struct{}
+	requests *bucket
+	tokens   *bucket
+	limits   limits
+}
+
+type limits struct {
+	maxInFlight       int
+	requestsPerMinute int
+	tokensPerMinute   int
+}
+
+func New(maxInFlight, requestsPerMinute, tokensPerMinute int) *Limiter {
+	l := &Limiter{limits: limits{maxInFlight: maxInFlight, requestsPerMinute: requestsPerMinute, tokensPerMinute: tokensPerMinute}}
+	if maxInFlight > 0 {
+		l.inFlight = make(chan struct{}, maxInFlight)
+	}
+	if requestsPerMinute > 0 {
+		l.requests = newBucket(requestsPerMinute)
+	}
+	if tokensPerMinute > 0 {
+		l.tokens = newBucket(tokensPerMinute)
+	}
+	return l
+}
+
+// Reservation is what Acquire took for one request, Release must be called when the request is done.
+type Reservation struct {
+	tokens      *bucket
+	reserved    float64
+	releaseSlot func()
+	released    sync.Once
+	reconciled  sync.Once
+}
+
+// Release gives the in-flight slot back, calls after the first one do nothing.
+func (r *Reservation) Release() {
+	r.released.Do(r.releaseSlot)
+}
+
+// Reconcile replaces reserved tokens with tokens the request really used (e.g. from usage of the response),
+// unused ones are given back and the excess is taken from the minute limit. Calls after the first one do nothing.
+func (r *Reservation) Reconcile(used int) {
+	r.reconciled.Do(func() {
+		r.tokens.take(float64(used) - r.reserved)
+	})
+}
+
+// Acquire waits until the request with given reserved tokens can be sent, tokens should cover the prompt
+// and the longest answer (max tokens), so parallel requests can't overshoot the minute limit before usage is known.
+// The in-flight slot is taken first, so requests waiting for it don't use up minute limits, and everything taken
+// is given back when ctx is done before the request can be sent.
+func (l *Limiter) Acquire(ctx context.Context, tokens int) (*Reservation, error) {
+	if l == nil {
+		return &Reservation{releaseSlot: func() {}}, nil
+	}
+
+	releaseSlot := func() {}
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		releaseSlot = func() { <-l.inFlight }
+	}
+
+	_, err := l.requests.wait(ctx, 1)
+	if err != nil {
+		releaseSlot()
+		return nil, err
+	}
+	reserved, err := l.tokens.wait(ctx, float64(tokens))
+	if err != nil {
+		l.requests.refund(1)
+		releaseSlot()
+		return nil, err
+	}
+
+	return &Reservation{
+		tokens:      l.tokens,
+		reserved:    reserved,
+		releaseSlot: releaseSlot,
+	}, nil
+}
+
+type bucket struct {
+	mu        sync.Mutex
+	capacity  float64
+	available float64
+	perSecond float64
+	last      time.Time
+}
+
+func newBucket(perMinute int) *bucket {
+	return &bucket{
+		capacity:  float64(perMinute),
+		available: float64(perMinute),
+		perSecond: float64(perMinute) / 60,
+		last:      time.Now(),
+	}
+}
+
+// wait returns the number of taken tokens, it's at most capacity of the bucket.
+func (b *bucket) wait(ctx context.Context, n float64) (float64, error) {
+	if b == nil {
+		return 0, nil
+	}
+
+	for {
+		b.mu.Lock()
+		b.refill()
+
+		// request bigger than the whole minute limit would wait forever, so it takes the full bucket
+		n = min(n, b.capacity)
+		if b.available >= n {
+			b.available -= n
+			b.mu.Unlock()
+			return n, nil
+		}
+		wait := time.Duration((n - b.available) / b.perSecond * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (b *bucket) refill() {
+	now := time.Now()
+	b.available = min(b.capacity, b.available+now.Sub(b.last).Seconds()*b.perSecond)
+	b.last = now
+}
+
+// take doesn't wait, available tokens can go below zero, so later requests wait until the debt is paid.
+// Negative n gives tokens back.
+func (b *bucket) take(n float64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	b.available = min(b.capacity, b.available-n)
+}
+
+func (b *bucket) refund(n float64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.available = min(b.capacity, b.available+min(n, b.capacity))
+}
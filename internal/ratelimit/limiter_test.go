@@ -0,0 +1,164 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	tests := []struct {
+		name    string
+		limiter *Limiter
+		tokens  int
+		// held are acquired and not released before the checked call
+		held    int
+		wantErr bool
+	}{
+		{name: "nil limiter", limiter: nil, held: 3},
+		{name: "free slot", limiter: New(2, 0, 0), held: 1},
+		{name: "all slots taken", limiter: New(1, 0, 0), held: 1, wantErr: true},
+		{name: "requests per minute used up", limiter: New(0, 2, 0), held: 2, wantErr: true},
+		{name: "tokens per minute used up", limiter: New(0, 0, 100), tokens: 60, held: 1, wantErr: true},
+		{name: "request bigger than minute limit", limiter: New(0, 0, 100), tokens: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range tt.held {
+				if _, err := tt.limiter.Acquire(context.Background(), tt.tokens); err != nil {
+					t.Fatalf("acquiring held: %v", err)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			reservation, err := tt.limiter.Acquire(ctx, tt.tokens)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("err = %v, want deadline exceeded", err)
+			}
+			if err == nil {
+				reservation.Reconcile(tt.tokens)
+				reservation.Release()
+				reservation.Release()
+			}
+		})
+	}
+}
+
+func TestAcquireWaitingForSlotKeepsMinuteLimits(t *testing.T) {
+	l := New(1, 2, 0)
+	reservation, err := l.Acquire(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 3 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		_, err = l.Acquire(ctx, 0)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("err = %v, want deadline exceeded while slot is taken", err)
+		}
+	}
+	reservation.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, 0); err != nil {
+		t.Fatalf("second request of the minute: %v", err)
+	}
+}
+
+func TestAcquireRefundsOnCancel(t *testing.T) {
+	l := New(1, 2, 100)
+	reservation, err := l.Acquire(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reservation.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	_, err = l.Acquire(ctx, 100)
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded waiting for tokens", err)
+	}
+
+	if l.requests.available < 1 {
+		t.Errorf("available requests = %f, cancelled request should be given back", l.requests.available)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, 0); err != nil {
+		t.Errorf("slot of cancelled request wasn't released: %v", err)
+	}
+}
+
+func TestReservationReconcile(t *testing.T) {
+	tests := []struct {
+		name          string
+		reserved      int
+		used          int
+		wantAvailable float64
+	}{
+		{name: "unused tokens are given back", reserved: 800, used: 300, wantAvailable: 700},
+		{name: "exact estimate", reserved: 300, used: 300, wantAvailable: 700},
+		{name: "usage over reservation is a debt", reserved: 300, used: 1500, wantAvailable: -500},
+		{name: "reservation capped by minute limit", reserved: 5000, used: 1000, wantAvailable: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(0, 0, 1000)
+			reservation, err := l.Acquire(context.Background(), tt.reserved)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reservation.Reconcile(tt.used)
+			reservation.Reconcile(tt.used) // only the first call counts
+			reservation.Release()
+
+			// refill of the bucket since acquiring adds a few tokens at most
+			if got := l.tokens.available; got < tt.wantAvailable || got > tt.wantAvailable+1 {
+				t.Errorf("available tokens = %f, want %f", got, tt.wantAvailable)
+			}
+		})
+	}
+}
+
+func TestReconcileDebtDelaysNextRequest(t *testing.T) {
+	l := New(0, 0, 600)
+	reservation, err := l.Acquire(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reservation.Reconcile(1200)
+	reservation.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want deadline exceeded until usage over the minute limit is paid", err)
+	}
+}
+
+func TestShared(t *testing.T) {
+	a, err := Shared("test|a", 1, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same, err := Shared("test|a", 1, 0, 0); err != nil || same != a {
+		t.Errorf("same key returned another limiter: %v", err)
+	}
+	if _, err := Shared("test|a", 5, 0, 0); err == nil {
+		t.Error("same key with other limits: want error")
+	}
+	if b, err := Shared("test|b", 1, 0, 0); err != nil || b == a {
+		t.Errorf("different keys share the limiter: %v", err)
+	}
+}
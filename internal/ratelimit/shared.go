@@ -0,0 +1,32 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	sharedMu       sync.Mutex
+	sharedLimiters = map[string]*Limiter{}
+)
+
+// Shared returns one limiter per key (e.g. provider and account), so all clients of the account share its limits.
+// Every call of the key must give the same limits, otherwise some clients would silently get limits of another one.
+func Shared(key string, maxInFlight, requestsPerMinute, tokensPerMinute int) (*Limiter, error) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	want := limits{maxInFlight: maxInFlight, requestsPerMinute: requestsPerMinute, tokensPerMinute: tokensPerMinute}
+	limiter, ok := sharedLimiters[key]
+	if !ok {
+		limiter = New(maxInFlight, requestsPerMinute, tokensPerMinute)
+		sharedLimiters[key] = limiter
+		return limiter, nil
+	}
+	if limiter.limits != want {
+		have := limiter.limits
+		return nil, fmt.Errorf("shared limiter already has limits in flight %d, rpm %d, tpm %d, not in flight %d, rpm %d, tpm %d",
+			have.maxInFlight, have.requestsPerMinute, have.tokensPerMinute, maxInFlight, requestsPerMinute, tokensPerMinute)
+	}
+	return limiter, nil
+}
@@ -0,0 +1,84 @@
+package sse
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/apierror"
+	"net/http"
+	"strings"
+)
+
+const contentType = "text/event-stream"
+
+func IsRequested(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), contentType)
+}
+
+type Writer struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func NewWriter(w http.ResponseWriter) (*Writer, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("response writer does not support flushing")
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &Writer{
+		w:       w,
+		flusher: flusher,
+	}, nil
+}
+
+func (s *Writer) Send(event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshalling event data: %w", err)
+	}
+
+	_, err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload)
+	if err != nil {
+		return fmt.Errorf("writing event: %w", err)
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// Forward sends every delta as "delta" event and returns the whole answer.
+func (s *Writer) Forward(stream <-chan ai.StreamChunk) (string, error) {
+	builder := strings.Builder{}
+	for chunk := range stream {
+		if chunk.Err != nil {
+			return "", chunk.Err
+		}
+		builder.WriteString(chunk.Content)
+		err := s.Send("delta", struct {
+			Content string `json:"content"`
+		}{chunk.Content})
+		if err != nil {
+			return "", err
+		}
+	}
+	return builder.String(), nil
+}
+
+// SendError sends the error mapped like in JSON responses, headers are already sent so status code is in the body.
+func (s *Writer) SendError(err error) error {
+	apiErr := apierror.FromError(err)
+	return s.Send("error", struct {
+		apierror.Error
+		Status int `json:"status"`
+	}{apiErr, apiErr.Status})
+}
+
+func (s *Writer) SendDone(data any) error {
+	return s.Send("done", data)
+}
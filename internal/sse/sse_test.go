@@ -0,0 +1,178 @@
+package sse_test
+
+import (
+	"errors"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/apierror"
+	"github.com/TMateusz1/go-3rd-devs/internal/sse"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// flushRecorder counts flushes, so tests can check every event is flushed at once.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushRecorder) Flush() {
+	f.flushes++
+	f.ResponseRecorder.Flush()
+}
+
+// plainWriter can't flush.
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+func newWriter(t *testing.T) (*sse.Writer, *flushRecorder) {
+	t.Helper()
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	sw, err := sse.NewWriter(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sw, w
+}
+
+func stream(chunks ...ai.StreamChunk) <-chan ai.StreamChunk {
+	ch := make(chan ai.StreamChunk, len(chunks))
+	for _, chunk := range chunks {
+		ch <- chunk
+	}
+	close(ch)
+	return ch
+}
+
+func TestIsRequested(t *testing.T) {
+	for accept, want := range map[string]bool{"text/event-stream": true, "application/json, text/event-stream": true, "application/json": false, "": false} {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("Accept", accept)
+		if got := sse.IsRequested(r); got != want {
+			t.Errorf("accept %q: requested = %t, want %t", accept, got, want)
+		}
+	}
+}
+
+func TestNewWriter(t *testing.T) {
+	_, w := newWriter(t)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("status %d with headers %v, want event stream", w.Code, w.Header())
+	}
+	if w.flushes != 1 {
+		t.Errorf("flushes = %d, want headers flushed at once", w.flushes)
+	}
+
+	if _, err := sse.NewWriter(plainWriter{httptest.NewRecorder()}); err == nil {
+		t.Error("writer without flushing: want error")
+	}
+}
+
+func TestSend(t *testing.T) {
+	sw, w := newWriter(t)
+
+	if err := sw.Send("progress", map[string]string{"stage": "searching"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.SendDone(map[string]string{"answer": "4"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.Send("broken", func() {}); err == nil {
+		t.Error("unmarshallable data: want error")
+	}
+
+	want := "event: progress\ndata: {\"stage\":\"searching\"}\n\nevent: done\ndata: {\"answer\":\"4\"}\n\n"
+	if body := w.Body.String(); body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+	if w.flushes != 3 {
+		t.Errorf("flushes = %d, want headers and every event", w.flushes)
+	}
+}
+
+func TestForward(t *testing.T) {
+	tests := []struct {
+		name       string
+		chunks     []ai.StreamChunk
+		want       string
+		wantErr    error
+		wantDeltas []string
+	}{
+		{name: "deltas", chunks: []ai.StreamChunk{{Content: "Hello "}, {Content: "there"}}, want: "Hello there", wantDeltas: []string{"Hello ", "there"}},
+		{name: "empty stream"},
+		{name: "error chunk", chunks: []ai.StreamChunk{{Content: "Hel"}, {Err: ai.ErrTruncated}}, wantErr: ai.ErrTruncated, wantDeltas: []string{"Hel"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sw, w := newWriter(t)
+
+			answer, err := sw.Forward(stream(tt.chunks...))
+			if answer != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("forward = %q, %v, want %q, %v", answer, err, tt.want, tt.wantErr)
+			}
+
+			var deltas []string
+			for _, event := range testsupport.ReadEvents(t, w.Body) {
+				var delta struct {
+					Content string `json:"content"`
+				}
+				event.Decode(t, &delta)
+				if event.Name != "delta" {
+					t.Errorf("event = %s, want delta", event.Name)
+				}
+				deltas = append(deltas, delta.Content)
+			}
+			if !reflect.DeepEqual(deltas, tt.wantDeltas) {
+				t.Errorf("deltas = %q, want %q", deltas, tt.wantDeltas)
+			}
+		})
+	}
+}
+
+func TestSendError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantCode       string
+		wantStatus     int
+		wantRetryAfter int
+	}{
+		{name: "rate limited", err: &ai.StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 3 * time.Second, Err: errors.New("slow down")}, wantCode: apierror.CodeRateLimited, wantStatus: http.StatusTooManyRequests, wantRetryAfter: 3},
+		{name: "truncated", err: ai.ErrTruncated, wantCode: apierror.CodeTruncated, wantStatus: http.StatusBadGateway},
+		{name: "internal", err: errors.New("disk of 10.0.0.7 is full"), wantCode: apierror.CodeInternal, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sw, w := newWriter(t)
+
+			if err := sw.SendError(tt.err); err != nil {
+				t.Fatal(err)
+			}
+
+			events := testsupport.ReadEvents(t, w.Body)
+			if len(events) != 1 || events[0].Name != "error" {
+				t.Fatalf("events = %+v, want one error event", events)
+			}
+			var got map[string]any
+			events[0].Decode(t, &got)
+			want := map[string]any{"code": tt.wantCode, "error": apierror.FromError(tt.err).Message, "status": float64(tt.wantStatus)}
+			if tt.wantRetryAfter > 0 {
+				want["retry_after"] = float64(tt.wantRetryAfter)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("error event = %v, want %v", got, want)
+			}
+			// status code was already sent with headers of the stream
+			if w.Code != http.StatusOK {
+				t.Errorf("status = %d, want 200", w.Code)
+			}
+		})
+	}
+}
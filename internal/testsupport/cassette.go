@@ -0,0 +1,35 @@
+package testsupport
+
+import (
+	"flag"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/cassette"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"testing"
+)
+
+var recordCassettes = flag.Bool("record", false, "record cassettes in testdata with the AI provider configured by env")
+
+// CassetteService replays the cassette at path, with -record flag the real provider answers and the cassette is rewritten.
+// Replay fails the test when a recorded interaction isn't used, so stale fixtures don't pile up.
+func CassetteService(t *testing.T, path string, opts ...option.Option) ai.Service {
+	t.Helper()
+	if *recordCassettes {
+		as, err := ai.NewServiceFromEnv(opts...)
+		if err != nil {
+			t.Fatalf("creating service to record %s: %v", path, err)
+		}
+		return cassette.NewRecorder(as, path)
+	}
+
+	replayer, err := cassette.NewReplayer(path)
+	if err != nil {
+		t.Fatalf("loading cassette: %v", err)
+	}
+	t.Cleanup(func() {
+		if unused := replayer.Unused(); len(unused) > 0 {
+			t.Errorf("cassette %s has %d unused interactions, re-record it with -record", path, len(unused))
+		}
+	})
+	return replayer
+}
@@ -0,0 +1,176 @@
+package testsupport
+
+import (
+	"context"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// FakeService is scripted ai.Service, the first rule matching a request gives the reply.
+type FakeService struct {
+	mu    sync.Mutex
+	rules []*Rule
+	calls []ai.Request
+	// Fallback answers requests without matching rule, when nil such requests fail.
+	Fallback *ai.Response
+}
+
+type Rule struct {
+	role    ai.Role
+	pattern *regexp.Regexp
+	reply   func(ai.Request) (ai.Response, error)
+	times   int
+	used    int
+}
+
+func NewFakeService() *FakeService {
+	return &FakeService{}
+}
+
+// On adds a rule matching requests which have a message of the role with content matching pattern.
+func (f *FakeService) On(role ai.Role, pattern string) *Rule {
+	rule := &Rule{
+		role:    role,
+		pattern: regexp.MustCompile(pattern),
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, rule)
+	return rule
+}
+
+func (r *Rule) Reply(content string) *Rule {
+	return r.ReplyResponse(ai.Response{
+		Message:      ai.AssistantMessage(content),
+		FinishReason: ai.FinishReasonStop,
+	})
+}
+
+func (r *Rule) ReplyResponse(resp ai.Response) *Rule {
+	r.reply = func(ai.Request) (ai.Response, error) {
+		return resp, nil
+	}
+	return r
+}
+
+func (r *Rule) ReplyError(err error) *Rule {
+	r.reply = func(ai.Request) (ai.Response, error) {
+		return ai.Response{}, err
+	}
+	return r
+}
+
+func (r *Rule) ReplyFunc(fn func(ai.Request) (ai.Response, error)) *Rule {
+	r.reply = fn
+	return r
+}
+
+// Times limits how many requests the rule answers, next matching rule is used afterwards.
+func (r *Rule) Times(times int) *Rule {
+	r.times = times
+	return r
+}
+
+func (r *Rule) matches(req ai.Request) bool {
+	if r.times > 0 && r.used >= r.times {
+		return false
+	}
+	for _, message := range req.Messages {
+		if message.Role == r.role && r.pattern.MatchString(message.Content) {
+			return true
+		}
+	}
+	return false
+}
+
+// Calls returns every request the fake received in order.
+func (f *FakeService) Calls() []ai.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ai.Request{}, f.calls...)
+}
+
+func (f *FakeService) Chat(ctx context.Context, messages []ai.Message, opts ...ai.CallOption) (string, error) {
+	return f.ChatWithModel(ctx, messages, "", opts...)
+}
+
+func (f *FakeService) ChatWithModel(ctx context.Context, messages []ai.Message, model string, opts ...ai.CallOption) (string, error) {
+	return ai.AnswerContent(f.Complete(ctx, ai.Request{
+		Model:    model,
+		Messages: messages,
+		Params:   ai.NewGenerationParams(opts...),
+	}))
+}
+
+func (f *FakeService) Complete(ctx context.Context, req ai.Request) (ai.Response, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, req)
+	var matched *Rule
+	for _, rule := range f.rules {
+		if rule.matches(req) {
+			rule.used++
+			matched = rule
+			break
+		}
+	}
+	fallback := f.Fallback
+	f.mu.Unlock()
+
+	if matched != nil && matched.reply != nil {
+		resp, err := matched.reply(req)
+		if err == nil && resp.Model == "" {
+			resp.Model = req.Model
+		}
+		if err == nil {
+			recordUsage(ctx, resp)
+		}
+		return resp, err
+	}
+	if fallback != nil {
+		recordUsage(ctx, *fallback)
+		return *fallback, nil
+	}
+	return ai.Response{}, fmt.Errorf("fake ai service: no rule matches request with last message %q", lastContent(req))
+}
+
+// ChatStream sends the scripted reply word by word.
+func (f *FakeService) ChatStream(ctx context.Context, messages []ai.Message, model string, opts ...ai.CallOption) (<-chan ai.StreamChunk, error) {
+	resp, err := f.Complete(ctx, ai.Request{
+		Model:    model,
+		Messages: messages,
+		Params:   ai.NewGenerationParams(opts...),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan ai.StreamChunk)
+	go func() {
+		defer close(ch)
+		for _, word := range strings.SplitAfter(resp.Message.Content, " ") {
+			select {
+			case ch <- ai.StreamChunk{Content: word}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// recordUsage adds usage of the scripted response to the tracker of the context like providers do.
+func recordUsage(ctx context.Context, resp ai.Response) {
+	if tracker, ok := ai.UsageTrackerFrom(ctx); ok {
+		tracker.Add(resp.Model, resp.Usage)
+	}
+}
+
+func lastContent(req ai.Request) string {
+	if len(req.Messages) == 0 {
+		return ""
+	}
+	return req.Messages[len(req.Messages)-1].Content
+}
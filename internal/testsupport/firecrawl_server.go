@@ -0,0 +1,168 @@
+package testsupport
+
+import (
+	"encoding/json"
+	"github.com/TMateusz1/go-3rd-devs/internal/websearch"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+const FirecrawlApiKey = "test-firecrawl-key"
+
+// FirecrawlPage is a page known by the stand-in server, it's found by /search and served by /scrape.
+type FirecrawlPage struct {
+	websearch.WebPage
+	Markdown string
+}
+
+// FirecrawlServer implements /search and /scrape contracts of Firecrawl v1 API on httptest server.
+type FirecrawlServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	pages    []FirecrawlPage
+	searches []websearch.FirecrawlSearchRequest
+	scrapes  []websearch.FirecrawlScrapRequest
+}
+
+func NewFirecrawlServer(pages ...FirecrawlPage) *FirecrawlServer {
+	f := &FirecrawlServer{
+		pages: pages,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /search", f.authorized(f.handleSearch))
+	mux.HandleFunc("POST /scrape", f.authorized(f.handleScrape))
+	f.Server = httptest.NewServer(mux)
+	return f
+}
+
+// Options points websearch.NewService at this server.
+func (f *FirecrawlServer) Options() []websearch.Option {
+	return []websearch.Option{
+		websearch.WithFirecrawlBaseUrl(f.URL),
+		websearch.WithFirecrawlApiKey(FirecrawlApiKey),
+	}
+}
+
+func (f *FirecrawlServer) AddPage(page FirecrawlPage) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pages = append(f.pages, page)
+}
+
+func (f *FirecrawlServer) Searches() []websearch.FirecrawlSearchRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]websearch.FirecrawlSearchRequest{}, f.searches...)
+}
+
+func (f *FirecrawlServer) Scrapes() []websearch.FirecrawlScrapRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]websearch.FirecrawlScrapRequest{}, f.scrapes...)
+}
+
+func (f *FirecrawlServer) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+FirecrawlApiKey {
+			writeFirecrawlError(w, http.StatusUnauthorized, "Unauthorized: Invalid token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleSearch understands "site: {url}, {query}" queries made by websearch service and filters pages by the site.
+func (f *FirecrawlServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	var req websearch.FirecrawlSearchRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || req.Query == "" {
+		writeFirecrawlError(w, http.StatusBadRequest, "Bad Request: query is required")
+		return
+	}
+
+	f.mu.Lock()
+	f.searches = append(f.searches, req)
+	site := ""
+	if rest, ok := strings.CutPrefix(req.Query, "site: "); ok {
+		site, _, _ = strings.Cut(rest, ",")
+	}
+	results := []websearch.WebPage{}
+	for _, page := range f.pages {
+		if req.Limit > 0 && len(results) >= req.Limit {
+			break
+		}
+		if site == "" || strings.Contains(page.Url, hostOf(site)) {
+			results = append(results, page.WebPage)
+		}
+	}
+	f.mu.Unlock()
+
+	writeFirecrawlJSON(w, map[string]any{
+		"success": true,
+		"data":    results,
+	})
+}
+
+func (f *FirecrawlServer) handleScrape(w http.ResponseWriter, r *http.Request) {
+	var req websearch.FirecrawlScrapRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || req.Url == "" {
+		writeFirecrawlError(w, http.StatusBadRequest, "Bad Request: url is required")
+		return
+	}
+
+	f.mu.Lock()
+	f.scrapes = append(f.scrapes, req)
+	var found *FirecrawlPage
+	for i := range f.pages {
+		if f.pages[i].Url == req.Url {
+			found = &f.pages[i]
+			break
+		}
+	}
+	f.mu.Unlock()
+
+	if found == nil {
+		writeFirecrawlError(w, http.StatusNotFound, "Page not found")
+		return
+	}
+
+	writeFirecrawlJSON(w, map[string]any{
+		"success": true,
+		"data": map[string]any{
+			string(websearch.FirecrawlMarkdownFormat): found.Markdown,
+			"metadata": map[string]any{
+				"title":       found.Title,
+				"description": found.Description,
+				"sourceURL":   found.Url,
+				"statusCode":  http.StatusOK,
+			},
+		},
+	})
+}
+
+func hostOf(url string) string {
+	url = strings.TrimSpace(url)
+	url = strings.TrimPrefix(url, "https://")
+	url = strings.TrimPrefix(url, "http://")
+	url = strings.TrimPrefix(url, "www.")
+	host, _, _ := strings.Cut(url, "/")
+	return host
+}
+
+func writeFirecrawlJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeFirecrawlError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"success": false,
+		"error":   message,
+	})
+}
@@ -0,0 +1,65 @@
+package testsupport
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Event is one Server-Sent Event of a streamed response.
+type Event struct {
+	Name string
+	Data string
+}
+
+// Decode unmarshals JSON data of the event.
+func (e Event) Decode(t *testing.T, v any) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(e.Data), v); err != nil {
+		t.Fatalf("decoding %s event %s: %v", e.Name, e.Data, err)
+	}
+}
+
+// ReadEvents parses the stream, the test fails on lines which aren't event or data of an event.
+func ReadEvents(t *testing.T, body io.Reader) []Event {
+	t.Helper()
+	var events []Event
+	var event Event
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if event.Name != "" {
+				events = append(events, event)
+			}
+			event = Event{}
+		case strings.HasPrefix(line, "event: "):
+			event.Name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.Data = strings.TrimPrefix(line, "data: ")
+		default:
+			t.Fatalf("unexpected line of event stream: %q", line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if event.Name != "" {
+		t.Fatalf("event %s isn't terminated by an empty line", event.Name)
+	}
+	return events
+}
+
+// EventNames returns names of events with consecutive duplicates collapsed, e.g. [progress delta done].
+func EventNames(events []Event) []string {
+	var names []string
+	for _, event := range events {
+		if len(names) == 0 || names[len(names)-1] != event.Name {
+			names = append(names, event.Name)
+		}
+	}
+	return names
+}
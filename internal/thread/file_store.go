@@ -0,0 +1,221 @@
+package thread
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	threadFileSuffix   = ".json"
+	messagesFileSuffix = ".messages.jsonl"
+)
+
+type fileStore struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+// NewFileStore keeps every thread in dir as JSON file with its metadata and JSON lines file with its transcript,
+// so threads survive restarts.
+func NewFileStore(dir string) (Store, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("creating thread store dir: %w", err)
+	}
+	return &fileStore{dir: dir}, nil
+}
+
+func (f *fileStore) Create(_ context.Context, th Thread) error {
+	if !idPattern.MatchString(th.ID) {
+		return fmt.Errorf("invalid thread id: %q", th.ID)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.write(th)
+}
+
+func (f *fileStore) Get(_ context.Context, id string) (Thread, error) {
+	if !idPattern.MatchString(id) {
+		return Thread{}, ErrNotFound
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.read(f.threadPath(id))
+}
+
+func (f *fileStore) List(_ context.Context) ([]Thread, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading thread store dir: %w", err)
+	}
+	var result []Thread
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, threadFileSuffix) {
+			continue
+		}
+		th, err := f.read(filepath.Join(f.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, th)
+	}
+
+	sortThreads(result)
+	return result, nil
+}
+
+func (f *fileStore) Update(_ context.Context, th Thread) error {
+	if !idPattern.MatchString(th.ID) {
+		return ErrNotFound
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := os.Stat(f.threadPath(th.ID)); errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return f.write(th)
+}
+
+func (f *fileStore) Delete(_ context.Context, id string) error {
+	if !idPattern.MatchString(id) {
+		return ErrNotFound
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := os.Remove(f.threadPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("deleting thread: %w", err)
+	}
+	err = os.Remove(f.messagesPath(id))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("deleting thread messages: %w", err)
+	}
+	return nil
+}
+
+func (f *fileStore) AppendMessages(_ context.Context, id string, messages ...Message) error {
+	if !idPattern.MatchString(id) {
+		return ErrNotFound
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := os.Stat(f.threadPath(id)); errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+
+	var data []byte
+	for _, message := range messages {
+		line, err := json.Marshal(message)
+		if err != nil {
+			return fmt.Errorf("encoding thread message: %w", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	file, err := os.OpenFile(f.messagesPath(id), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening thread messages: %w", err)
+	}
+	_, err = file.Write(data)
+	closeErr := file.Close()
+	if err != nil || closeErr != nil {
+		return fmt.Errorf("writing thread messages: %w", errors.Join(err, closeErr))
+	}
+	return nil
+}
+
+func (f *fileStore) Messages(_ context.Context, id string, offset, limit int) ([]Message, int, error) {
+	if !idPattern.MatchString(id) {
+		return nil, 0, ErrNotFound
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if _, err := os.Stat(f.threadPath(id)); errors.Is(err, os.ErrNotExist) {
+		return nil, 0, ErrNotFound
+	}
+
+	file, err := os.Open(f.messagesPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return []Message{}, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("opening thread messages: %w", err)
+	}
+	defer file.Close()
+
+	var result []Message
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var message Message
+		err := json.Unmarshal(scanner.Bytes(), &message)
+		if err != nil {
+			return nil, 0, fmt.Errorf("decoding thread message: %w", err)
+		}
+		result = append(result, message)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("reading thread messages: %w", err)
+	}
+	return page(result, offset, limit), len(result), nil
+}
+
+func (f *fileStore) read(path string) (Thread, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Thread{}, ErrNotFound
+	}
+	if err != nil {
+		return Thread{}, fmt.Errorf("reading thread: %w", err)
+	}
+
+	var th Thread
+	err = json.Unmarshal(data, &th)
+	if err != nil {
+		return Thread{}, fmt.Errorf("decoding thread %s: %w", filepath.Base(path), err)
+	}
+	return th, nil
+}
+
+func (f *fileStore) write(th Thread) error {
+	data, err := json.Marshal(th)
+	if err != nil {
+		return fmt.Errorf("encoding thread: %w", err)
+	}
+
+	// write and rename, so restart in the middle of write doesn't leave half written thread
+	tmp, err := os.CreateTemp(f.dir, th.ID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating thread file: %w", err)
+	}
+	_, err = tmp.Write(data)
+	closeErr := tmp.Close()
+	if err != nil || closeErr != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("writing thread: %w", errors.Join(err, closeErr))
+	}
+	return os.Rename(tmp.Name(), f.threadPath(th.ID))
+}
+
+func (f *fileStore) threadPath(id string) string {
+	return filepath.Join(f.dir, id+threadFileSuffix)
+}
+
+func (f *fileStore) messagesPath(id string) string {
+	return filepath.Join(f.dir, id+messagesFileSuffix)
+}
@@ -0,0 +1,171 @@
+package thread_test
+
+import (
+	"context"
+	"errors"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/thread"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var created = time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newThread(id string, age time.Duration) thread.Thread {
+	return thread.Thread{
+		ID:        id,
+		Memory:    thread.MemoryWindow,
+		Metadata:  map[string]string{"user": "ada"},
+		CreatedAt: created.Add(-age),
+		UpdatedAt: created.Add(-age),
+	}
+}
+
+func stores(t *testing.T) map[string]thread.Store {
+	t.Helper()
+	fileStore, err := thread.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]thread.Store{"file": fileStore, "memory": thread.NewMemoryStore()}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			older, newer := newThread("older", time.Hour), newThread("newer", 0)
+			for _, th := range []thread.Thread{newer, older} {
+				if err := store.Create(ctx, th); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := store.Get(ctx, newer.ID)
+			if err != nil || !reflect.DeepEqual(got, newer) {
+				t.Errorf("get = %+v, %v, want %+v", got, err, newer)
+			}
+			list, err := store.List(ctx)
+			if err != nil || len(list) != 2 || list[0].ID != older.ID || list[1].ID != newer.ID {
+				t.Errorf("list = %+v, %v, want from the oldest", list, err)
+			}
+
+			newer.Summary, newer.SummarizedMessages = "The user greeted.", 2
+			if err := store.Update(ctx, newer); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := store.Get(ctx, newer.ID); got.Summary != newer.Summary || got.SummarizedMessages != 2 {
+				t.Errorf("updated = %+v", got)
+			}
+
+			messages := []thread.Message{
+				{Role: ai.User, Content: "hi", CreatedAt: created},
+				{Role: ai.Assistant, Content: "hello", Model: "gpt-4o-mini", Usage: &ai.Usage{PromptTokens: 3, CompletionTokens: 1}, CreatedAt: created},
+			}
+			if err := store.AppendMessages(ctx, newer.ID, messages[:1]...); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.AppendMessages(ctx, newer.ID, messages[1:]...); err != nil {
+				t.Fatal(err)
+			}
+			transcript, total, err := store.Messages(ctx, newer.ID, 0, 0)
+			if err != nil || total != 2 || !reflect.DeepEqual(transcript, messages) {
+				t.Errorf("messages = %+v (%d), %v, want %+v", transcript, total, err, messages)
+			}
+			if transcript, total, err := store.Messages(ctx, older.ID, 0, 0); err != nil || total != 0 || transcript == nil {
+				t.Errorf("messages of empty thread = %#v (%d), %v, want empty", transcript, total, err)
+			}
+
+			if err := store.Delete(ctx, newer.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Get(ctx, newer.ID); !errors.Is(err, thread.ErrNotFound) {
+				t.Errorf("get of deleted thread: err = %v, want not found", err)
+			}
+			if _, _, err := store.Messages(ctx, newer.ID, 0, 0); !errors.Is(err, thread.ErrNotFound) {
+				t.Errorf("messages of deleted thread: err = %v, want not found", err)
+			}
+		})
+	}
+}
+
+func TestStoreUnknownThread(t *testing.T) {
+	ctx := context.Background()
+	hi := thread.Message{Role: ai.User, Content: "hi"}
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			tests := []struct {
+				name string
+				call func(id string) error
+			}{
+				{name: "get", call: func(id string) error { _, err := store.Get(ctx, id); return err }},
+				{name: "update", call: func(id string) error { return store.Update(ctx, newThread(id, 0)) }},
+				{name: "delete", call: func(id string) error { return store.Delete(ctx, id) }},
+				{name: "append", call: func(id string) error { return store.AppendMessages(ctx, id, hi) }},
+				{name: "messages", call: func(id string) error { _, _, err := store.Messages(ctx, id, 0, 0); return err }},
+			}
+
+			for _, tt := range tests {
+				for _, id := range []string{"missing", "../missing"} {
+					if err := tt.call(id); !errors.Is(err, thread.ErrNotFound) {
+						t.Errorf("%s %q: err = %v, want not found", tt.name, id, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFileStoreSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := thread.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	th := newThread("kept", 0)
+	if err := store.Create(ctx, th); err != nil {
+		t.Fatal(err)
+	}
+	message := thread.Message{Role: ai.User, Content: "remember me", CreatedAt: created}
+	if err := store.AppendMessages(ctx, th.ID, message); err != nil {
+		t.Fatal(err)
+	}
+	// leftover of the write interrupted by restart
+	if err := os.WriteFile(filepath.Join(dir, "kept.123.tmp"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted, err := thread.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := restarted.List(ctx)
+	if err != nil || !reflect.DeepEqual(list, []thread.Thread{th}) {
+		t.Errorf("list = %+v, %v, want %+v", list, err, th)
+	}
+	transcript, _, err := restarted.Messages(ctx, th.ID, 0, 0)
+	if err != nil || !reflect.DeepEqual(transcript, []thread.Message{message}) {
+		t.Errorf("messages = %+v, %v", transcript, err)
+	}
+}
+
+func TestFileStoreRejectsInvalidID(t *testing.T) {
+	dir := t.TempDir()
+	store, err := thread.NewFileStore(filepath.Join(dir, "threads"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"", "../escaped", "a/b", "thread.json"} {
+		if err := store.Create(context.Background(), newThread(id, 0)); err == nil {
+			t.Errorf("create %q: want error", id)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("files outside of the store: %v", entries)
+	}
+}
@@ -0,0 +1,264 @@
+package thread
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	MemorySummary     = "summary"
+	MemoryWindow      = "window"
+	MemoryTokenBudget = "token_budget"
+	MemoryHybrid      = "hybrid"
+
+	defaultMemoryTurns  = 5
+	defaultMemoryTokens = 2000
+)
+
+var ErrUnknownMemory = errors.New("unknown memory strategy")
+
+// Context is the part of the conversation sent to AI with the next question.
+type Context struct {
+	Summary  string
+	Messages []ai.Message
+}
+
+// Memory decides what the model remembers of the thread.
+type Memory interface {
+	Context(th Thread, transcript []Message) Context
+	// Update is called after the turn is recorded and returns the thread with the summary of the transcript.
+	Update(ctx context.Context, th Thread, transcript []Message) (Thread, error)
+}
+
+// Memories are strategies threads can choose from, the default one is used when thread doesn't choose.
+type Memories struct {
+	byName      map[string]Memory
+	defaultName string
+}
+
+// NewMemoriesFromEnv creates all strategies, THREAD_MEMORY is the default one, summary when not set.
+func NewMemoriesFromEnv(as ai.Service) (*Memories, error) {
+	turns, err := envInt("THREAD_MEMORY_TURNS", defaultMemoryTurns)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := envInt("THREAD_MEMORY_TOKENS", defaultMemoryTokens)
+	if err != nil {
+		return nil, err
+	}
+	tokenizer, err := ai.NewTokenizer(ai.EncodingO200K)
+	if err != nil {
+		return nil, fmt.Errorf("creating memory tokenizer: %w", err)
+	}
+
+	memories := &Memories{
+		byName: map[string]Memory{
+			MemorySummary:     NewSummaryMemory(as),
+			MemoryWindow:      NewWindowMemory(turns),
+			MemoryTokenBudget: NewTokenBudgetMemory(tokenizer, tokens),
+			MemoryHybrid:      NewHybridMemory(as, turns),
+		},
+		defaultName: MemorySummary,
+	}
+	if name := os.Getenv("THREAD_MEMORY"); name != "" {
+		if _, ok := memories.byName[name]; !ok {
+			return nil, fmt.Errorf("THREAD_MEMORY %q: %w", name, ErrUnknownMemory)
+		}
+		memories.defaultName = name
+	}
+	return memories, nil
+}
+
+func envInt(name string, defaultValue int) (int, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 1 {
+		return 0, fmt.Errorf("parsing %s: must be positive number", name)
+	}
+	return parsed, nil
+}
+
+// Name validates the strategy name, empty one is the default strategy.
+func (m *Memories) Name(name string) (string, error) {
+	if name == "" {
+		return m.defaultName, nil
+	}
+	if _, ok := m.byName[name]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownMemory, name)
+	}
+	return name, nil
+}
+
+// For returns the strategy of the thread, threads created before strategies existed use the default one.
+func (m *Memories) For(th Thread) (Memory, error) {
+	name, err := m.Name(th.Memory)
+	if err != nil {
+		return nil, err
+	}
+	return m.byName[name], nil
+}
+
+// summaryMemory rewrites one summary after each turn, only turns which aren't summarized yet are sent verbatim.
+type summaryMemory struct {
+	as ai.Service
+}
+
+func NewSummaryMemory(as ai.Service) Memory {
+	return &summaryMemory{as: as}
+}
+
+func (s *summaryMemory) Context(th Thread, transcript []Message) Context {
+	return Context{
+		Summary:  th.Summary,
+		Messages: toAIMessages(unsummarized(th, transcript, len(transcript))),
+	}
+}
+
+func (s *summaryMemory) Update(ctx context.Context, th Thread, transcript []Message) (Thread, error) {
+	return summarize(ctx, s.as, th, transcript, len(transcript))
+}
+
+// windowMemory sends the last turns verbatim and forgets older ones.
+type windowMemory struct {
+	turns int
+}
+
+func NewWindowMemory(turns int) Memory {
+	return &windowMemory{turns: turns}
+}
+
+func (w *windowMemory) Context(_ Thread, transcript []Message) Context {
+	return Context{Messages: toAIMessages(lastTurns(transcript, w.turns))}
+}
+
+func (w *windowMemory) Update(_ context.Context, th Thread, _ []Message) (Thread, error) {
+	return th, nil
+}
+
+// tokenBudgetMemory sends as many recent messages as fit in the token budget.
+type tokenBudgetMemory struct {
+	tokenizer *ai.Tokenizer
+	maxTokens int
+}
+
+func NewTokenBudgetMemory(tokenizer *ai.Tokenizer, maxTokens int) Memory {
+	return &tokenBudgetMemory{
+		tokenizer: tokenizer,
+		maxTokens: maxTokens,
+	}
+}
+
+func (t *tokenBudgetMemory) Context(_ Thread, transcript []Message) Context {
+	messages := toAIMessages(transcript)
+	tokens := 0
+	start := len(messages)
+	for start > 0 {
+		tokens += t.tokenizer.CountMessages(messages[start-1 : start])
+		if tokens > t.maxTokens {
+			break
+		}
+		start--
+	}
+	// answer without its question would confuse the model
+	if start < len(messages) && messages[start].Role == ai.Assistant {
+		start++
+	}
+	return Context{Messages: messages[start:]}
+}
+
+func (t *tokenBudgetMemory) Update(_ context.Context, th Thread, _ []Message) (Thread, error) {
+	return th, nil
+}
+
+// hybridMemory sends the last turns verbatim and the summary of the older ones.
+type hybridMemory struct {
+	as    ai.Service
+	turns int
+}
+
+func NewHybridMemory(as ai.Service, turns int) Memory {
+	return &hybridMemory{
+		as:    as,
+		turns: turns,
+	}
+}
+
+// Context sends turns which left the window verbatim too when they aren't summarized yet.
+func (h *hybridMemory) Context(th Thread, transcript []Message) Context {
+	window := lastTurns(transcript, h.turns)
+	return Context{
+		Summary:  th.Summary,
+		Messages: toAIMessages(unsummarized(th, transcript, len(transcript)-len(window))),
+	}
+}
+
+// Update summarizes only turns which left the window.
+func (h *hybridMemory) Update(ctx context.Context, th Thread, transcript []Message) (Thread, error) {
+	return summarize(ctx, h.as, th, transcript, len(transcript)-len(lastTurns(transcript, h.turns)))
+}
+
+// summarize adds messages of the transcript up to end, which aren't in the summary yet, to the summary of the thread.
+func summarize(ctx context.Context, as ai.Service, th Thread, transcript []Message, end int) (Thread, error) {
+	if end <= th.SummarizedMessages {
+		return th, nil
+	}
+	summary, err := as.Chat(ctx, []ai.Message{
+		ai.SystemMessage(getNewSummaryPrompt(th.Summary, transcript[th.SummarizedMessages:end])),
+		ai.UserMessage("Please summarize conversation in short way."),
+	})
+	if err != nil {
+		return th, fmt.Errorf("failed to summarize thread: %w", err)
+	}
+	th.Summary = summary
+	th.SummarizedMessages = end
+	return th, nil
+}
+
+// unsummarized returns messages missing in the summary, at least the ones from start.
+func unsummarized(th Thread, transcript []Message, start int) []Message {
+	start = min(start, th.SummarizedMessages, len(transcript))
+	return transcript[start:]
+}
+
+// lastTurns returns messages of the last turns, a turn starts with the message of the user.
+func lastTurns(transcript []Message, turns int) []Message {
+	start := len(transcript)
+	for start > 0 && turns > 0 {
+		start--
+		if transcript[start].Role == ai.User {
+			turns--
+		}
+	}
+	return transcript[start:]
+}
+
+func toAIMessages(transcript []Message) []ai.Message {
+	result := make([]ai.Message, 0, len(transcript))
+	for _, message := range transcript {
+		result = append(result, ai.Message{Role: message.Role, Content: message.Content})
+	}
+	return result
+}
+
+func formatTranscript(transcript []Message) string {
+	builder := strings.Builder{}
+	for _, message := range transcript {
+		switch message.Role {
+		case ai.User:
+			builder.WriteString("User: ")
+		case ai.Assistant:
+			builder.WriteString("Assistant: ")
+		}
+		builder.WriteString(message.Content)
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
@@ -0,0 +1,96 @@
+package thread
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+type memoryStore struct {
+	mu       sync.RWMutex
+	threads  map[string]Thread
+	messages map[string][]Message
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{
+		threads:  make(map[string]Thread),
+		messages: make(map[string][]Message),
+	}
+}
+
+func (m *memoryStore) Create(_ context.Context, th Thread) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.threads[th.ID] = th
+	return nil
+}
+
+func (m *memoryStore) Get(_ context.Context, id string) (Thread, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	th, ok := m.threads[id]
+	if !ok {
+		return Thread{}, ErrNotFound
+	}
+	return th, nil
+}
+
+func (m *memoryStore) List(_ context.Context) ([]Thread, error) {
+	m.mu.RLock()
+	result := make([]Thread, 0, len(m.threads))
+	for _, th := range m.threads {
+		result = append(result, th)
+	}
+	m.mu.RUnlock()
+
+	sortThreads(result)
+	return result, nil
+}
+
+func (m *memoryStore) Update(_ context.Context, th Thread) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.threads[th.ID]; !ok {
+		return ErrNotFound
+	}
+	m.threads[th.ID] = th
+	return nil
+}
+
+func (m *memoryStore) Delete(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.threads[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.threads, id)
+	delete(m.messages, id)
+	return nil
+}
+
+func (m *memoryStore) AppendMessages(_ context.Context, id string, messages ...Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.threads[id]; !ok {
+		return ErrNotFound
+	}
+	m.messages[id] = append(m.messages[id], messages...)
+	return nil
+}
+
+func (m *memoryStore) Messages(_ context.Context, id string, offset, limit int) ([]Message, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.threads[id]; !ok {
+		return nil, 0, ErrNotFound
+	}
+	messages := m.messages[id]
+	return page(messages, offset, limit), len(messages), nil
+}
+
+func sortThreads(threads []Thread) {
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].CreatedAt.Before(threads[j].CreatedAt)
+	})
+}
@@ -0,0 +1,227 @@
+package thread_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"github.com/TMateusz1/go-3rd-devs/internal/thread"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// transcriptOf returns turns u0, a0, u1, a1, ... of the user and the assistant.
+func transcriptOf(turns int) []thread.Message {
+	var transcript []thread.Message
+	for i := range turns {
+		transcript = append(transcript,
+			thread.Message{Role: ai.User, Content: fmt.Sprintf("u%d", i)},
+			thread.Message{Role: ai.Assistant, Content: fmt.Sprintf("a%d", i)},
+		)
+	}
+	return transcript
+}
+
+func contents(messages []ai.Message) []string {
+	result := []string{}
+	for _, message := range messages {
+		result = append(result, message.Content)
+	}
+	return result
+}
+
+func TestMemoryContext(t *testing.T) {
+	tokenizer, err := ai.NewTokenizer(ai.EncodingO200K)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript := transcriptOf(4)
+	// tokens of the last n messages of the transcript
+	lastTokens := func(n int) int {
+		tokens := 0
+		for _, message := range transcript[len(transcript)-n:] {
+			tokens += tokenizer.CountMessages([]ai.Message{{Role: message.Role, Content: message.Content}})
+		}
+		return tokens
+	}
+	summarized := thread.Thread{Summary: "The user counts.", SummarizedMessages: 4}
+
+	tests := []struct {
+		name        string
+		memory      thread.Memory
+		th          thread.Thread
+		transcript  []thread.Message
+		wantSummary string
+		want        []string
+	}{
+		{name: "summary of new thread", memory: thread.NewSummaryMemory(nil), transcript: transcript, want: []string{"u0", "a0", "u1", "a1", "u2", "a2", "u3", "a3"}},
+		{name: "summary with unsummarized turns", memory: thread.NewSummaryMemory(nil), th: summarized, transcript: transcript, wantSummary: "The user counts.", want: []string{"u2", "a2", "u3", "a3"}},
+		{name: "window", memory: thread.NewWindowMemory(2), th: summarized, transcript: transcript, want: []string{"u2", "a2", "u3", "a3"}},
+		{name: "window over transcript", memory: thread.NewWindowMemory(5), transcript: transcript[:2], want: []string{"u0", "a0"}},
+		{name: "window of unanswered question", memory: thread.NewWindowMemory(1), transcript: append(transcriptOf(1), thread.Message{Role: ai.User, Content: "u1"}), want: []string{"u1"}},
+		{name: "token budget", memory: thread.NewTokenBudgetMemory(tokenizer, lastTokens(4)), transcript: transcript, want: []string{"u2", "a2", "u3", "a3"}},
+		{name: "token budget drops answer without question", memory: thread.NewTokenBudgetMemory(tokenizer, lastTokens(3)), transcript: transcript, want: []string{"u3", "a3"}},
+		{name: "token budget too small", memory: thread.NewTokenBudgetMemory(tokenizer, 1), transcript: transcript, want: []string{}},
+		{name: "hybrid with summarized older turns", memory: thread.NewHybridMemory(nil, 1), th: thread.Thread{Summary: "The user counts.", SummarizedMessages: 6}, transcript: transcript, wantSummary: "The user counts.", want: []string{"u3", "a3"}},
+		{name: "hybrid with turns left window before summary", memory: thread.NewHybridMemory(nil, 1), th: summarized, transcript: transcript, wantSummary: "The user counts.", want: []string{"u2", "a2", "u3", "a3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.memory.Context(tt.th, tt.transcript)
+			if got.Summary != tt.wantSummary {
+				t.Errorf("summary = %q, want %q", got.Summary, tt.wantSummary)
+			}
+			if messages := contents(got.Messages); !reflect.DeepEqual(messages, tt.want) {
+				t.Errorf("messages = %v, want %v", messages, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryUpdate(t *testing.T) {
+	transcript := transcriptOf(4)
+	summarized := thread.Thread{Summary: "The user counts.", SummarizedMessages: 4}
+
+	tests := []struct {
+		name           string
+		memory         func(as ai.Service) thread.Memory
+		th             thread.Thread
+		reply          error
+		wantSummarized []string
+		wantSummary    string
+		wantMessages   int
+		wantErr        bool
+	}{
+		{
+			name:           "summary of new thread",
+			memory:         thread.NewSummaryMemory,
+			wantSummarized: []string{"User: u0", "Assistant: a3"},
+			wantSummary:    "new summary",
+			wantMessages:   8,
+		},
+		{
+			name:           "summary adds only new turns",
+			memory:         thread.NewSummaryMemory,
+			th:             summarized,
+			wantSummarized: []string{"<previous_summary>The user counts.</previous_summary>", "User: u2", "Assistant: a3"},
+			wantSummary:    "new summary",
+			wantMessages:   8,
+		},
+		{
+			name:         "summary without new turns",
+			memory:       thread.NewSummaryMemory,
+			th:           thread.Thread{Summary: "The user counts.", SummarizedMessages: 8},
+			wantSummary:  "The user counts.",
+			wantMessages: 8,
+		},
+		{
+			name:           "hybrid summarizes turns which left window",
+			memory:         func(as ai.Service) thread.Memory { return thread.NewHybridMemory(as, 1) },
+			th:             summarized,
+			wantSummarized: []string{"User: u2", "Assistant: a2"},
+			wantSummary:    "new summary",
+			wantMessages:   6,
+		},
+		{
+			name:         "window doesn't summarize",
+			memory:       func(ai.Service) thread.Memory { return thread.NewWindowMemory(1) },
+			th:           summarized,
+			wantSummary:  "The user counts.",
+			wantMessages: 4,
+		},
+		{
+			name:         "failed summary keeps thread",
+			memory:       thread.NewSummaryMemory,
+			th:           summarized,
+			reply:        ai.ErrProviderUnavailable,
+			wantSummary:  "The user counts.",
+			wantMessages: 4,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := testsupport.NewFakeService()
+			if tt.reply != nil {
+				fake.On(ai.System, ".").ReplyError(tt.reply)
+			} else {
+				fake.On(ai.System, "summarize").Reply("new summary")
+			}
+
+			th, err := tt.memory(fake).Update(context.Background(), tt.th, transcript)
+			if (err != nil) != tt.wantErr || !errors.Is(err, tt.reply) {
+				t.Fatalf("err = %v, want %v", err, tt.reply)
+			}
+			if th.Summary != tt.wantSummary || th.SummarizedMessages != tt.wantMessages {
+				t.Errorf("thread = %q of %d messages, want %q of %d", th.Summary, th.SummarizedMessages, tt.wantSummary, tt.wantMessages)
+			}
+
+			calls := fake.Calls()
+			if len(tt.wantSummarized) == 0 {
+				if len(calls) != 0 && tt.reply == nil {
+					t.Errorf("calls = %d, want none", len(calls))
+				}
+				return
+			}
+			if len(calls) != 1 {
+				t.Fatalf("calls = %d, want 1", len(calls))
+			}
+			prompt := calls[0].Messages[0].Content
+			for _, want := range tt.wantSummarized {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("prompt misses %q:\n%s", want, prompt)
+				}
+			}
+			if tt.th.SummarizedMessages > 0 && strings.Contains(prompt, "User: u1") {
+				t.Errorf("prompt has summarized turns:\n%s", prompt)
+			}
+		})
+	}
+}
+
+func TestNewMemoriesFromEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantDefault string
+		wantErr     error
+	}{
+		{name: "summary by default", wantDefault: thread.MemorySummary},
+		{name: "configured default", env: map[string]string{"THREAD_MEMORY": thread.MemoryHybrid}, wantDefault: thread.MemoryHybrid},
+		{name: "unknown default", env: map[string]string{"THREAD_MEMORY": "forever"}, wantErr: thread.ErrUnknownMemory},
+		{name: "invalid turns", env: map[string]string{"THREAD_MEMORY_TURNS": "0"}},
+		{name: "invalid tokens", env: map[string]string{"THREAD_MEMORY_TOKENS": "many"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			memories, err := thread.NewMemoriesFromEnv(testsupport.NewFakeService())
+			if tt.wantDefault == "" {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name, err := memories.Name(""); err != nil || name != tt.wantDefault {
+				t.Errorf("default = %q, %v, want %q", name, err, tt.wantDefault)
+			}
+			if _, err := memories.Name("forever"); !errors.Is(err, thread.ErrUnknownMemory) {
+				t.Errorf("unknown memory: err = %v, want %v", err, thread.ErrUnknownMemory)
+			}
+			if _, err := memories.For(thread.Thread{Memory: thread.MemoryWindow}); err != nil {
+				t.Errorf("memory of thread: %v", err)
+			}
+		})
+	}
+}
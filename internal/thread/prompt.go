@@ -0,0 +1,12 @@
+package thread
+
+import "fmt"
+
+func getNewSummaryPrompt(previousSummary string, transcript []Message) string {
+	return fmt.Sprintf(`
+Please summarize the following conversation in a concise manner, incorporating the previous summary if available:
+<previous_summary>%s</previous_summary>
+<current_turn> 
+%s</current_turn>
+`, previousSummary, formatTranscript(transcript))
+}
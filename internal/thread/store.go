@@ -0,0 +1,38 @@
+package thread
+
+import (
+	"context"
+	"os"
+)
+
+// Store persists threads with their summary, metadata and transcript. Get, Update and Delete of unknown thread return ErrNotFound.
+type Store interface {
+	Create(ctx context.Context, th Thread) error
+	Get(ctx context.Context, id string) (Thread, error)
+	List(ctx context.Context) ([]Thread, error)
+	Update(ctx context.Context, th Thread) error
+	Delete(ctx context.Context, id string) error
+	AppendMessages(ctx context.Context, id string, messages ...Message) error
+	// Messages returns messages from offset, all of them when limit isn't positive, and the number of all messages.
+	Messages(ctx context.Context, id string, offset, limit int) ([]Message, int, error)
+}
+
+// NewStoreFromEnv uses file store when THREAD_STORE_DIR is set, otherwise threads are kept in memory.
+func NewStoreFromEnv() (Store, error) {
+	dir, ok := os.LookupEnv("THREAD_STORE_DIR")
+	if ok && dir != "" {
+		return NewFileStore(dir)
+	}
+	return NewMemoryStore(), nil
+}
+
+func page(messages []Message, offset, limit int) []Message {
+	if offset < 0 || offset >= len(messages) {
+		return []Message{}
+	}
+	messages = messages[offset:]
+	if limit > 0 && limit < len(messages) {
+		messages = messages[:limit]
+	}
+	return append([]Message{}, messages...)
+}
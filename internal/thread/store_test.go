@@ -0,0 +1,85 @@
+package thread_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/thread"
+	"reflect"
+	"testing"
+)
+
+func TestStoreMessagesPage(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset int
+		limit  int
+		want   []string
+	}{
+		{name: "all", want: []string{"m0", "m1", "m2", "m3", "m4"}},
+		{name: "first page", limit: 2, want: []string{"m0", "m1"}},
+		{name: "middle page", offset: 2, limit: 2, want: []string{"m2", "m3"}},
+		{name: "last partial page", offset: 4, limit: 2, want: []string{"m4"}},
+		{name: "rest from offset", offset: 3, want: []string{"m3", "m4"}},
+		{name: "limit over total", limit: 10, want: []string{"m0", "m1", "m2", "m3", "m4"}},
+		{name: "offset at total", offset: 5, limit: 2, want: []string{}},
+		{name: "offset over total", offset: 9, want: []string{}},
+		{name: "negative offset", offset: -1, limit: 2, want: []string{}},
+	}
+
+	ctx := context.Background()
+	for name, store := range stores(t) {
+		th := newThread("paged", 0)
+		if err := store.Create(ctx, th); err != nil {
+			t.Fatal(err)
+		}
+		for i := range 5 {
+			if err := store.AppendMessages(ctx, th.ID, thread.Message{Role: ai.User, Content: fmt.Sprintf("m%d", i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				messages, total, err := thread.NewThreads(store).Messages(ctx, th.ID, tt.offset, tt.limit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := []string{}
+				for _, message := range messages {
+					got = append(got, message.Content)
+				}
+				if !reflect.DeepEqual(got, tt.want) || total != 5 {
+					t.Errorf("page = %v of %d, want %v of 5", got, total, tt.want)
+				}
+				if messages == nil {
+					t.Error("page is nil, want empty slice encoded as []")
+				}
+			})
+		}
+	}
+}
+
+func TestStoreMessagesPageIsCopy(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			th := newThread("copied", 0)
+			if err := store.Create(ctx, th); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.AppendMessages(ctx, th.ID, thread.Message{Role: ai.User, Content: "kept"}); err != nil {
+				t.Fatal(err)
+			}
+
+			messages, _, err := store.Messages(ctx, th.ID, 0, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			messages[0].Content = "changed by caller"
+			if messages, _, _ := store.Messages(ctx, th.ID, 0, 1); messages[0].Content != "kept" {
+				t.Errorf("stored message = %q, want kept", messages[0].Content)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package thread
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSummaryAttempts    = 3
+	defaultSummaryBackoff     = 2 * time.Second
+	defaultSummaryWaitTimeout = 10 * time.Second
+	summaryAttemptTimeout     = 2 * time.Minute
+)
+
+type SummarizerOption func(*Summarizer)
+
+// WithSummaryRetries sets attempts of one summarization, the wait between them doubles from backoff.
+func WithSummaryRetries(attempts int, backoff time.Duration) SummarizerOption {
+	return func(s *Summarizer) {
+		s.attempts = attempts
+		s.backoff = backoff
+	}
+}
+
+// WithSummaryWaitTimeout limits how long the next turn waits for the pending summary.
+func WithSummaryWaitTimeout(timeout time.Duration) SummarizerOption {
+	return func(s *Summarizer) {
+		s.waitTimeout = timeout
+	}
+}
+
+// Summarizer updates memory of threads in background, so answers don't wait for the summary.
+// One thread is summarized by one worker at a time, turns finished meanwhile are summarized by the same worker after it.
+// Memory folds every message which isn't summarized yet, so summarization which failed all attempts is caught up after the next turn.
+type Summarizer struct {
+	threads     *Threads
+	memories    *Memories
+	attempts    int
+	backoff     time.Duration
+	waitTimeout time.Duration
+
+	mu   sync.Mutex
+	jobs map[string]*summaryJob
+}
+
+type summaryJob struct {
+	done  chan struct{}
+	again bool
+}
+
+func NewSummarizer(threads *Threads, memories *Memories, opts ...SummarizerOption) *Summarizer {
+	s := &Summarizer{
+		threads:     threads,
+		memories:    memories,
+		attempts:    defaultSummaryAttempts,
+		backoff:     defaultSummaryBackoff,
+		waitTimeout: defaultSummaryWaitTimeout,
+		jobs:        make(map[string]*summaryJob),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Schedule updates memory of the thread with its recorded turns in background.
+func (s *Summarizer) Schedule(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[id]; ok {
+		job.again = true
+		return
+	}
+	job := &summaryJob{done: make(chan struct{})}
+	s.jobs[id] = job
+	go s.run(id, job)
+}
+
+// Wait blocks until the pending summary of the thread is saved, at most wait timeout.
+// Memory sends turns missing in the summary verbatim, so the turn which doesn't wait longer still has the whole context.
+func (s *Summarizer) Wait(ctx context.Context, id string) {
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	timer := time.NewTimer(s.waitTimeout)
+	defer timer.Stop()
+	select {
+	case <-job.done:
+	case <-timer.C:
+		log.Printf("summary of thread %s is still pending, answering without it", id)
+	case <-ctx.Done():
+	}
+}
+
+func (s *Summarizer) run(id string, job *summaryJob) {
+	defer close(job.done)
+	for {
+		s.summarizeWithRetries(id)
+
+		s.mu.Lock()
+		if !job.again {
+			delete(s.jobs, id)
+			s.mu.Unlock()
+			return
+		}
+		job.again = false
+		s.mu.Unlock()
+	}
+}
+
+func (s *Summarizer) summarizeWithRetries(id string) {
+	wait := s.backoff
+	for attempt := 1; ; attempt++ {
+		err := s.summarize(id)
+		if err == nil || errors.Is(err, ErrNotFound) {
+			return
+		}
+		if attempt >= s.attempts {
+			log.Printf("failed to summarize thread %s, it's caught up after the next turn: %v", id, err)
+			return
+		}
+		log.Printf("failed to summarize thread %s, attempt %d of %d: %v", id, attempt, s.attempts, err)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+func (s *Summarizer) summarize(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), summaryAttemptTimeout)
+	defer cancel()
+
+	th, err := s.threads.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	memory, err := s.memories.For(th)
+	if err != nil {
+		return err
+	}
+	transcript, _, err := s.threads.Messages(ctx, id, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	updated, err := memory.Update(ctx, th, transcript)
+	if err != nil {
+		return fmt.Errorf("failed to update thread memory: %w", err)
+	}
+	if updated.Summary == th.Summary && updated.SummarizedMessages == th.SummarizedMessages {
+		return nil
+	}
+	return s.threads.SetSummary(ctx, id, updated.Summary, updated.SummarizedMessages)
+}
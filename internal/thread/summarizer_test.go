@@ -0,0 +1,178 @@
+package thread_test
+
+import (
+	"context"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"github.com/TMateusz1/go-3rd-devs/internal/thread"
+	"testing"
+	"time"
+)
+
+type summarizerFixture struct {
+	fake    *testsupport.FakeService
+	threads *thread.Threads
+	id      string
+}
+
+// newSummarizerFixture creates a thread with summary memory and one turn in its transcript.
+func newSummarizerFixture(t *testing.T) summarizerFixture {
+	t.Helper()
+	f := summarizerFixture{fake: testsupport.NewFakeService(), threads: thread.NewThreads(thread.NewMemoryStore())}
+	th, err := f.threads.Create(context.Background(), thread.MemorySummary, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.id = th.ID
+	f.turn(t)
+	return f
+}
+
+func (f summarizerFixture) summarizer(t *testing.T, opts ...thread.SummarizerOption) *thread.Summarizer {
+	t.Helper()
+	memories, err := thread.NewMemoriesFromEnv(f.fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return thread.NewSummarizer(f.threads, memories, opts...)
+}
+
+func (f summarizerFixture) turn(t *testing.T) {
+	t.Helper()
+	err := f.threads.AppendMessages(context.Background(), f.id, thread.Message{Role: ai.User, Content: "hi"}, thread.Message{Role: ai.Assistant, Content: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f summarizerFixture) thread(t *testing.T) thread.Thread {
+	t.Helper()
+	th, err := f.threads.Get(context.Background(), f.id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return th
+}
+
+func TestSummarizerRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		attempts     int
+		wantCalls    int
+		wantSummary  string
+		wantMessages int
+	}{
+		{name: "first attempt", attempts: 3, wantCalls: 1, wantSummary: "summary", wantMessages: 2},
+		{name: "retried failure", failures: 2, attempts: 3, wantCalls: 3, wantSummary: "summary", wantMessages: 2},
+		{name: "all attempts failed", failures: 3, attempts: 3, wantCalls: 3},
+		{name: "single attempt", failures: 1, attempts: 1, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSummarizerFixture(t)
+			if tt.failures > 0 {
+				f.fake.On(ai.System, "summarize").ReplyError(ai.ErrProviderUnavailable).Times(tt.failures)
+			}
+			f.fake.On(ai.System, "summarize").Reply("summary")
+			s := f.summarizer(t, thread.WithSummaryRetries(tt.attempts, time.Millisecond))
+
+			s.Schedule(f.id)
+			s.Wait(context.Background(), f.id)
+
+			if calls := len(f.fake.Calls()); calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if th := f.thread(t); th.Summary != tt.wantSummary || th.SummarizedMessages != tt.wantMessages {
+				t.Errorf("thread = %q of %d messages, want %q of %d", th.Summary, th.SummarizedMessages, tt.wantSummary, tt.wantMessages)
+			}
+		})
+	}
+}
+
+func TestSummarizerBackoffDoubles(t *testing.T) {
+	f := newSummarizerFixture(t)
+	f.fake.On(ai.System, "summarize").ReplyError(ai.ErrRateLimited)
+	s := f.summarizer(t, thread.WithSummaryRetries(3, 20*time.Millisecond))
+
+	start := time.Now()
+	s.Schedule(f.id)
+	s.Wait(context.Background(), f.id)
+
+	// 20ms after the first attempt and 40ms after the second one
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("3 attempts took %s, want at least 60ms of backoff", elapsed)
+	}
+}
+
+func TestSummarizerCatchesUpAfterFailedTurn(t *testing.T) {
+	f := newSummarizerFixture(t)
+	f.fake.On(ai.System, "summarize").ReplyError(ai.ErrProviderUnavailable).Times(2)
+	f.fake.On(ai.System, "summarize").Reply("summary of both turns")
+	s := f.summarizer(t, thread.WithSummaryRetries(2, time.Millisecond))
+
+	s.Schedule(f.id)
+	s.Wait(context.Background(), f.id)
+	if th := f.thread(t); th.SummarizedMessages != 0 {
+		t.Fatalf("summarized messages = %d after failed attempts, want 0", th.SummarizedMessages)
+	}
+
+	f.turn(t)
+	s.Schedule(f.id)
+	s.Wait(context.Background(), f.id)
+	if th := f.thread(t); th.Summary != "summary of both turns" || th.SummarizedMessages != 4 {
+		t.Errorf("thread = %q of %d messages, want both turns", th.Summary, th.SummarizedMessages)
+	}
+}
+
+func TestSummarizerSkipsDeletedThread(t *testing.T) {
+	f := newSummarizerFixture(t)
+	f.fake.On(ai.System, "summarize").Reply("summary")
+	s := f.summarizer(t, thread.WithSummaryRetries(3, time.Second))
+	if err := f.threads.Delete(context.Background(), f.id); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	s.Schedule(f.id)
+	s.Wait(context.Background(), f.id)
+
+	if calls := len(f.fake.Calls()); calls != 0 || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("calls = %d in %s, want none without retries", calls, time.Since(start))
+	}
+}
+
+func TestSummarizerRunsAgainForTurnsFinishedMeanwhile(t *testing.T) {
+	f := newSummarizerFixture(t)
+	started, release := make(chan struct{}), make(chan struct{})
+	f.fake.On(ai.System, "summarize").ReplyFunc(func(ai.Request) (ai.Response, error) {
+		close(started)
+		<-release
+		return ai.Response{Message: ai.AssistantMessage("summary of first turn")}, nil
+	}).Times(1)
+	f.fake.On(ai.System, "summarize").Reply("summary of both turns")
+	s := f.summarizer(t, thread.WithSummaryWaitTimeout(10*time.Millisecond))
+
+	s.Schedule(f.id)
+	<-started
+	f.turn(t)
+	s.Schedule(f.id)
+
+	start := time.Now()
+	s.Wait(context.Background(), f.id)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("wait took %s, want wait timeout", elapsed)
+	}
+
+	close(release)
+	for i := 0; i < 100 && f.thread(t).SummarizedMessages != 4; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if calls := len(f.fake.Calls()); calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+	if th := f.thread(t); th.Summary != "summary of both turns" || th.SummarizedMessages != 4 {
+		t.Errorf("thread = %q of %d messages, want both turns", th.Summary, th.SummarizedMessages)
+	}
+}
@@ -0,0 +1,159 @@
+package thread
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"regexp"
+	"sync"
+	"time"
+)
+
+var ErrNotFound = errors.New("thread not found")
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type Thread struct {
+	ID      string `json:"id"`
+	Summary string `json:"summary"`
+	// SummarizedMessages is the number of transcript messages included in the summary.
+	SummarizedMessages int `json:"summarized_messages"`
+	// Memory is the name of the memory strategy, the default one when empty.
+	Memory    string            `json:"memory,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Message is one entry of the transcript of the thread, answers have the model and usage of the call.
+type Message struct {
+	Role      ai.Role   `json:"role"`
+	Content   string    `json:"content"`
+	Model     string    `json:"model,omitempty"`
+	Usage     *ai.Usage `json:"usage,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Threads keeps conversations in the store, turns of one thread are serialized with its lock.
+type Threads struct {
+	store Store
+	mu    sync.Mutex
+	locks map[string]*threadLock
+}
+
+// threadLock is a semaphore of one slot, so waiting for it can be canceled.
+// refs counts holders and waiters, the lock is dropped from Threads when nobody uses it.
+type threadLock struct {
+	sem  chan struct{}
+	refs int
+}
+
+func NewThreads(store Store) *Threads {
+	return &Threads{
+		store: store,
+		locks: make(map[string]*threadLock),
+	}
+}
+
+func (t *Threads) Create(ctx context.Context, memory string, metadata map[string]string) (Thread, error) {
+	now := time.Now()
+	th := Thread{
+		ID:        NewID(),
+		Memory:    memory,
+		Metadata:  metadata,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	err := t.store.Create(ctx, th)
+	if err != nil {
+		return Thread{}, fmt.Errorf("creating thread: %w", err)
+	}
+	return th, nil
+}
+
+func (t *Threads) Get(ctx context.Context, id string) (Thread, error) {
+	return t.store.Get(ctx, id)
+}
+
+// List returns threads from the oldest one.
+func (t *Threads) List(ctx context.Context) ([]Thread, error) {
+	return t.store.List(ctx)
+}
+
+func (t *Threads) Delete(ctx context.Context, id string) error {
+	return t.store.Delete(ctx, id)
+}
+
+// Lock waits until the running turn of the thread ends or ctx is done, the returned function releases the thread.
+func (t *Threads) Lock(ctx context.Context, id string) (func(), error) {
+	_, err := t.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	lock, ok := t.locks[id]
+	if !ok {
+		lock = &threadLock{sem: make(chan struct{}, 1)}
+		t.locks[id] = lock
+	}
+	lock.refs++
+	t.mu.Unlock()
+
+	select {
+	case lock.sem <- struct{}{}:
+	case <-ctx.Done():
+		t.release(id, lock)
+		return nil, fmt.Errorf("waiting for thread %s: %w", id, ctx.Err())
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-lock.sem
+			t.release(id, lock)
+		})
+	}, nil
+}
+
+func (t *Threads) release(id string, lock *threadLock) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lock.refs--
+	if lock.refs == 0 {
+		delete(t.locks, id)
+	}
+}
+
+func (t *Threads) SetSummary(ctx context.Context, id string, summary string, summarizedMessages int) error {
+	th, err := t.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	th.Summary = summary
+	th.SummarizedMessages = summarizedMessages
+	th.UpdatedAt = time.Now()
+	return t.store.Update(ctx, th)
+}
+
+func (t *Threads) AppendMessages(ctx context.Context, id string, messages ...Message) error {
+	err := t.store.AppendMessages(ctx, id, messages...)
+	if err != nil {
+		return fmt.Errorf("recording thread messages: %w", err)
+	}
+	return nil
+}
+
+// Messages returns the page of the transcript from the oldest message and the number of all messages.
+func (t *Threads) Messages(ctx context.Context, id string, offset, limit int) ([]Message, int, error) {
+	return t.store.Messages(ctx, id, offset, limit)
+}
+
+func NewID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,117 @@
+package thread_test
+
+import (
+	"context"
+	"errors"
+	"github.com/TMateusz1/go-3rd-devs/internal/thread"
+	"testing"
+	"time"
+)
+
+// tryLock fails when the thread stays locked for a moment.
+func tryLock(t *testing.T, threads *thread.Threads, id string) (func(), error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	return threads.Lock(ctx, id)
+}
+
+func TestThreadsLock(t *testing.T) {
+	ctx := context.Background()
+	store := thread.NewMemoryStore()
+	threads := thread.NewThreads(store)
+	th, err := threads.Create(ctx, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := threads.Lock(ctx, th.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tryLock(t, threads, th.ID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("lock of locked thread: err = %v, want deadline exceeded", err)
+	}
+
+	unlock()
+	unlock() // releasing twice is a no-op
+	second, err := tryLock(t, threads, th.ID)
+	if err != nil {
+		t.Fatalf("lock of released thread: %v", err)
+	}
+	if _, err := tryLock(t, threads, th.ID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("lock after double unlock: err = %v, want deadline exceeded", err)
+	}
+	second()
+
+	if _, err := threads.Lock(ctx, "missing"); !errors.Is(err, thread.ErrNotFound) {
+		t.Errorf("lock of missing thread: err = %v, want not found", err)
+	}
+}
+
+func TestThreadsLockWaitsForHolder(t *testing.T) {
+	ctx := context.Background()
+	threads := thread.NewThreads(thread.NewMemoryStore())
+	th, err := threads.Create(ctx, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := threads.Lock(ctx, th.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locked := make(chan error)
+	go func() {
+		waiterUnlock, err := threads.Lock(ctx, th.ID)
+		if err == nil {
+			waiterUnlock()
+		}
+		locked <- err
+	}()
+	select {
+	case err := <-locked:
+		t.Fatalf("waiter locked held thread: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case err := <-locked:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter didn't lock released thread")
+	}
+}
+
+func TestThreadsDeleteKeepsHeldLock(t *testing.T) {
+	ctx := context.Background()
+	store := thread.NewMemoryStore()
+	threads := thread.NewThreads(store)
+	th, err := threads.Create(ctx, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := threads.Lock(ctx, th.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := threads.Delete(ctx, th.ID); err != nil {
+		t.Fatal(err)
+	}
+	// the same id again, e.g. a thread restored while the turn of the deleted one still runs
+	if err := store.Create(ctx, th); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tryLock(t, threads, th.ID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("lock of thread held before delete: err = %v, want deadline exceeded", err)
+	}
+
+	unlock()
+	if _, err := tryLock(t, threads, th.ID); err != nil {
+		t.Errorf("lock after release: %v", err)
+	}
+}
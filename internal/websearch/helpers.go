@@ -8,13 +8,13 @@ import (
 	"net/http"
 )
 
-func prepareFirecrawlSearchRequest(ctx context.Context, apikey string, searchRequestBody FirecrawlSearchRequest) (*http.Request, error) {
+func prepareFirecrawlSearchRequest(ctx context.Context, baseUri, apikey string, searchRequestBody FirecrawlSearchRequest) (*http.Request, error) {
 	bodyBytes, err := json.Marshal(searchRequestBody)
 	if err != nil {
 		return nil, fmt.Errorf("marshalling search request body: %w", err)
 
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/search", firecrawlApiBaseUri), bytes.NewReader(bodyBytes))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/search", baseUri), bytes.NewReader(bodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
@@ -23,13 +23,13 @@ func prepareFirecrawlSearchRequest(ctx context.Context, apikey string, searchReq
 	return req, nil
 }
 
-func prepareFirecrawlScrapRequest(ctx context.Context, apikey string, scrapRequest FirecrawlScrapRequest) (*http.Request, error) {
+func prepareFirecrawlScrapRequest(ctx context.Context, baseUri, apikey string, scrapRequest FirecrawlScrapRequest) (*http.Request, error) {
 	bodyBytes, err := json.Marshal(scrapRequest)
 	if err != nil {
 		return nil, fmt.Errorf("marshalling search request body: %w", err)
 
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/scrape", firecrawlApiBaseUri), bytes.NewReader(bodyBytes))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/scrape", baseUri), bytes.NewReader(bodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
@@ -37,12 +37,3 @@ func prepareFirecrawlScrapRequest(ctx context.Context, apikey string, scrapReque
 	req.Header.Set("Content-Type", "application/json")
 	return req, nil
 }
-
-func parseDomainQueries(answer string) (QueryDomains, error) {
-	var queries QueryDomains
-	err := json.Unmarshal([]byte(answer), &queries)
-	if err != nil {
-		return QueryDomains{}, fmt.Errorf("failed to parse domain queries: %w", err)
-	}
-	return queries, nil
-}
@@ -0,0 +1,69 @@
+package websearch
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ratelimit"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultFirecrawlMaxInFlight = 5
+	// defaultFirecrawlRequestsPerMinute is the scrape limit of the free plan, raise it with FIRECRAWL_RPM on paid plans.
+	defaultFirecrawlRequestsPerMinute = 10
+)
+
+type Option func(*service)
+
+func WithFirecrawlApiKey(apiKey string) Option {
+	return func(s *service) {
+		s.fireCrawlerApiKey = apiKey
+	}
+}
+
+// WithFirecrawlBaseUrl points the service at Firecrawl compatible server, e.g. local stand-in in tests.
+func WithFirecrawlBaseUrl(baseUrl string) Option {
+	return func(s *service) {
+		s.firecrawlBaseUri = strings.TrimSuffix(baseUrl, "/")
+	}
+}
+
+// WithFirecrawlLimiter replaces the limiter configured by FIRECRAWL_MAX_IN_FLIGHT and FIRECRAWL_RPM env.
+func WithFirecrawlLimiter(limiter *ratelimit.Limiter) Option {
+	return func(s *service) {
+		s.limiter = limiter
+	}
+}
+
+// firecrawlLimiterFromEnv shares the limiter of the Firecrawl account between services.
+func firecrawlLimiterFromEnv(baseUri, apiKey string) (*ratelimit.Limiter, error) {
+	maxInFlight := defaultFirecrawlMaxInFlight
+	requestsPerMinute := defaultFirecrawlRequestsPerMinute
+
+	value, ok := os.LookupEnv("FIRECRAWL_MAX_IN_FLIGHT")
+	if ok {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("parsing FIRECRAWL_MAX_IN_FLIGHT: %w", err)
+		}
+		maxInFlight = parsed
+	}
+
+	value, ok = os.LookupEnv("FIRECRAWL_RPM")
+	if ok {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("parsing FIRECRAWL_RPM: %w", err)
+		}
+		requestsPerMinute = parsed
+	}
+
+	key := fmt.Sprintf("firecrawl|%s|%x", baseUri, sha256.Sum256([]byte(apiKey)))
+	limiter, err := ratelimit.Shared(key, maxInFlight, requestsPerMinute, 0)
+	if err != nil {
+		return nil, fmt.Errorf("firecrawl rate limit: %w", err)
+	}
+	return limiter, nil
+}
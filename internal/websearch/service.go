@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/ratelimit"
 	"log"
 	"net/http"
 	"os"
@@ -25,28 +26,47 @@ type Service interface {
 }
 
 const (
-	doSearch string = "1"
-)
+	doSearch             string = "1"
+	maxStructuredRepairs int    = 2
+	maxScoredPages       int    = 3
 
-func NewService(as ai.Service) (Service, error) {
-	firecrawlApiKey, ok := os.LookupEnv("FIRECRAWL_API_KEY")
-	if !ok {
-		return nil, fmt.Errorf("creating websearch service: missing FIRECRAWL_API_KEY env")
-	}
+	// classificationMaxTokens leaves room for whitespace or a short preamble, answers cut by it fail with ai.ErrTruncated.
+	classificationMaxTokens int64 = 32
+	scoringMaxTokens        int64 = 300
+)
 
-	return &service{
+func NewService(as ai.Service, opts ...Option) (Service, error) {
+	s := &service{
 		as: as,
 		c: http.Client{
 			Timeout: 1 * time.Minute,
 		},
-		fireCrawlerApiKey: firecrawlApiKey,
-	}, nil
+		fireCrawlerApiKey: os.Getenv("FIRECRAWL_API_KEY"),
+		firecrawlBaseUri:  firecrawlApiBaseUri,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.fireCrawlerApiKey == "" {
+		return nil, fmt.Errorf("creating websearch service: missing FIRECRAWL_API_KEY env")
+	}
+	if s.limiter == nil {
+		limiter, err := firecrawlLimiterFromEnv(s.firecrawlBaseUri, s.fireCrawlerApiKey)
+		if err != nil {
+			return nil, fmt.Errorf("creating websearch service: %w", err)
+		}
+		s.limiter = limiter
+	}
+	return s, nil
 }
 
 type service struct {
 	fireCrawlerApiKey string
+	firecrawlBaseUri  string
 	as                ai.Service
 	c                 http.Client
+	limiter           *ratelimit.Limiter
 }
 
 func (s *service) SearchForSpecificPages(ctx context.Context, domains QueryDomains) ([]SearchResult, error) {
@@ -65,28 +85,16 @@ func (s *service) SearchForSpecificPages(ctx context.Context, domains QueryDomai
 				Limit:   3,
 				Timeout: 60000,
 			}
-			req, err := prepareFirecrawlSearchRequest(ctx, s.fireCrawlerApiKey, searchRequestBody)
+			req, err := prepareFirecrawlSearchRequest(ctx, s.firecrawlBaseUri, s.fireCrawlerApiKey, searchRequestBody)
 			if err != nil {
 				errCh <- fmt.Errorf("creating request: %w", err)
 				return
 			}
 
-			resp, err := s.c.Do(req)
-			if err != nil {
-				errCh <- fmt.Errorf("response error: %w", err)
-				return
-			}
-			defer resp.Body.Close()
-
-			if resp.StatusCode >= 400 {
-				errCh <- fmt.Errorf("response code %d", resp.StatusCode)
-				return
-			}
 			var result FirecrawlSearchResponse
-
-			err = json.NewDecoder(resp.Body).Decode(&result)
+			err = s.doFirecrawl(ctx, req, &result)
 			if err != nil {
-				errCh <- fmt.Errorf("decode response: %w", err)
+				errCh <- err
 				return
 			}
 
@@ -111,13 +119,37 @@ func (s *service) SearchForSpecificPages(ctx context.Context, domains QueryDomai
 	return results, nil
 }
 
+// doFirecrawl decodes the response into result, the in-flight slot is held until the body is read.
+func (s *service) doFirecrawl(ctx context.Context, req *http.Request, result any) error {
+	reservation, err := s.limiter.Acquire(ctx, 0)
+	if err != nil {
+		return fmt.Errorf("waiting for firecrawl rate limit: %w", err)
+	}
+	defer reservation.Release()
+
+	resp, err := s.c.Do(req)
+	if err != nil {
+		return fmt.Errorf("response error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("response code %d", resp.StatusCode)
+	}
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
 func (s *service) IsSearchRequired(ctx context.Context, query string) bool {
 	msg := []ai.Message{
 		ai.SystemMessage(useSearchPrompt),
 		ai.UserMessage(query),
 	}
 
-	answer, err := s.as.Chat(ctx, msg)
+	answer, err := s.as.Chat(ctx, msg, ai.Deterministic(), ai.WithMaxTokens(classificationMaxTokens))
 	if err != nil {
 		log.Printf("failed to chat with AI: %v", err)
 		return false
@@ -131,13 +163,13 @@ func (s *service) GetDomainQueries(ctx context.Context, query string, allowedDom
 		ai.SystemMessage(getAskDomainPrompt(allowedDomains)),
 		ai.UserMessage(query),
 	}
-	answer, err := s.as.Chat(ctx, msg)
+	queries, err := ai.CompleteStructured[QueryDomains](ctx, s.as, ai.Request{Messages: msg}, maxStructuredRepairs)
 	if err != nil {
 		log.Printf("failed to get domain queries: %v", err)
 		return QueryDomains{}, fmt.Errorf("failed to get domain queries: %w", err)
 	}
 
-	return parseDomainQueries(answer)
+	return queries, nil
 
 }
 
@@ -162,20 +194,17 @@ func (s *service) ScoreResults(ctx context.Context, results []SearchResult, user
 			go func(page WebPage, query string) {
 				defer wg.Done()
 				userPrompt := userScoringPrompt(page, result.Query, userQuery)
-				resp, err := s.as.Chat(ctx, []ai.Message{
-					ai.SystemMessage(systemScoringPrompt),
-					ai.UserMessage(userPrompt),
-				})
+				scoringResult, err := ai.CompleteStructured[scoringAiResponse](ctx, s.as, ai.Request{
+					Messages: []ai.Message{
+						ai.SystemMessage(systemScoringPrompt),
+						ai.UserMessage(userPrompt),
+					},
+					Params: ai.NewGenerationParams(ai.Deterministic(), ai.WithMaxTokens(scoringMaxTokens)),
+				}, maxStructuredRepairs)
 				if err != nil {
 					errCh <- fmt.Errorf("ai response: %w", err)
 					return
 				}
-				var scoringResult scoringAiResponse
-				err = json.Unmarshal([]byte(resp), &scoringResult)
-				if err != nil {
-					errCh <- fmt.Errorf("unmarshal ai response: %w", err)
-					return
-				}
 				scoringCh <- webPageScore{webPage: page, scoring: scoringResult.Score}
 			}(page, result.Query)
 
@@ -197,7 +226,7 @@ func (s *service) ScoreResults(ctx context.Context, results []SearchResult, user
 	}
 
 	sort.Slice(webPageScores, func(i, j int) bool {
-		return webPageScores[i].scoring < webPageScores[j].scoring
+		return webPageScores[i].scoring > webPageScores[j].scoring
 	})
 
 	var scoredWebPage []WebPage
@@ -205,7 +234,7 @@ func (s *service) ScoreResults(ctx context.Context, results []SearchResult, user
 		scoredWebPage = append(scoredWebPage, v.webPage)
 	}
 
-	return scoredWebPage[:3], nil
+	return scoredWebPage[:min(maxScoredPages, len(scoredWebPage))], nil
 
 }
 
@@ -224,27 +253,15 @@ func (s *service) ScrapWebpages(ctx context.Context, pages []WebPage, userQuery
 				Formats: []FirecrawlScrapFormat{FirecrawlMarkdownFormat},
 			}
 
-			req, err := prepareFirecrawlScrapRequest(ctx, s.fireCrawlerApiKey, firecrawlScrapReq)
+			req, err := prepareFirecrawlScrapRequest(ctx, s.firecrawlBaseUri, s.fireCrawlerApiKey, firecrawlScrapReq)
 			if err != nil {
 				errCh <- fmt.Errorf("creating request: %w", err)
 				return
 			}
-			resp, err := s.c.Do(req)
-			if err != nil {
-				errCh <- fmt.Errorf("response error: %w", err)
-				return
-			}
-			defer resp.Body.Close()
-
-			if resp.StatusCode >= 400 {
-				errCh <- fmt.Errorf("response code %d", resp.StatusCode)
-				return
-			}
-
 			var result FirecrawlScrapResponse
-			err = json.NewDecoder(resp.Body).Decode(&result)
+			err = s.doFirecrawl(ctx, req, &result)
 			if err != nil {
-				errCh <- fmt.Errorf("decode response: %w", err)
+				errCh <- err
 				return
 			}
 
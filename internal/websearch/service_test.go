@@ -0,0 +1,270 @@
+package websearch_test
+
+import (
+	"context"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/ratelimit"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"github.com/TMateusz1/go-3rd-devs/internal/websearch"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScoreResultsPicksBestPages(t *testing.T) {
+	scores := map[string]string{
+		"https://a.dev": `{"reason": "off topic", "score": 0.1}`,
+		"https://b.dev": `{"reason": "exact answer", "score": 0.9}`,
+		"https://c.dev": `{"reason": "related", "score": 0.5}`,
+		"https://d.dev": `{"reason": "partly", "score": 0.7}`,
+	}
+
+	tests := []struct {
+		name  string
+		pages []string
+		want  []string
+	}{
+		{name: "best three by score", pages: []string{"https://a.dev", "https://b.dev", "https://c.dev", "https://d.dev"}, want: []string{"https://b.dev", "https://d.dev", "https://c.dev"}},
+		{name: "fewer than three", pages: []string{"https://a.dev", "https://c.dev"}, want: []string{"https://c.dev", "https://a.dev"}},
+		{name: "no pages", pages: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := testsupport.NewFakeService()
+			for url, score := range scores {
+				fake.On(ai.User, "Resource: "+url).Reply(score)
+			}
+			ws, err := websearch.NewService(fake, websearch.WithFirecrawlApiKey("key"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var results []websearch.WebPage
+			for _, url := range tt.pages {
+				results = append(results, websearch.WebPage{Url: url})
+			}
+			scored, err := ws.ScoreResults(context.Background(), []websearch.SearchResult{{Query: "q", Results: results}}, "question")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, page := range scored {
+				got = append(got, page.Url)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFirecrawlSlotHeldUntilBodyIsRead checks slow bodies count as in flight, the server sends headers before the body.
+func TestFirecrawlSlotHeldUntilBodyIsRead(t *testing.T) {
+	var active, maxActive atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := active.Add(1)
+		defer active.Add(-1)
+		if current > maxActive.Load() {
+			maxActive.Store(current)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"success": true, "data": []}`))
+	}))
+	defer server.Close()
+
+	ws, err := websearch.NewService(testsupport.NewFakeService(),
+		websearch.WithFirecrawlApiKey("key"),
+		websearch.WithFirecrawlBaseUrl(server.URL),
+		websearch.WithFirecrawlLimiter(ratelimit.New(1, 0, 0)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ws.SearchForSpecificPages(context.Background(), websearch.QueryDomains{Queries: []websearch.Query{
+		{Q: "a", Url: "a.dev"},
+		{Q: "b", Url: "b.dev"},
+		{Q: "c", Url: "c.dev"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxActive.Load() != 1 {
+		t.Errorf("max parallel requests = %d, want 1", maxActive.Load())
+	}
+}
+
+var firecrawlPages = []testsupport.FirecrawlPage{
+	{WebPage: websearch.WebPage{Url: "https://go.dev/doc/effective_go", Title: "Effective Go"}, Markdown: "# Effective Go"},
+	{WebPage: websearch.WebPage{Url: "https://go.dev/blog/pipelines", Title: "Pipelines"}, Markdown: "# Pipelines"},
+	{WebPage: websearch.WebPage{Url: "https://en.wikipedia.org/wiki/Go_(programming_language)", Title: "Go"}, Markdown: "# Go (programming language)"},
+}
+
+func newFirecrawlService(t *testing.T, fake *testsupport.FakeService) (websearch.Service, *testsupport.FirecrawlServer) {
+	t.Helper()
+	firecrawl := testsupport.NewFirecrawlServer(firecrawlPages...)
+	t.Cleanup(firecrawl.Close)
+	ws, err := websearch.NewService(fake, firecrawl.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ws, firecrawl
+}
+
+func TestSearchForSpecificPages(t *testing.T) {
+	tests := []struct {
+		name    string
+		queries []websearch.Query
+		want    map[string][]string
+	}{
+		{
+			name:    "pages of each site",
+			queries: []websearch.Query{{Q: "concurrency", Url: "https://go.dev"}, {Q: "go language", Url: "https://en.wikipedia.org"}},
+			want: map[string][]string{
+				"concurrency": {"https://go.dev/doc/effective_go", "https://go.dev/blog/pipelines"},
+				"go language": {"https://en.wikipedia.org/wiki/Go_(programming_language)"},
+			},
+		},
+		{name: "site without pages", queries: []websearch.Query{{Q: "gpt", Url: "https://openai.com"}}, want: map[string][]string{"gpt": nil}},
+		{name: "no queries", want: map[string][]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws, firecrawl := newFirecrawlService(t, testsupport.NewFakeService())
+
+			results, err := ws.SearchForSpecificPages(context.Background(), websearch.QueryDomains{Queries: tt.queries})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := map[string][]string{}
+			for _, result := range results {
+				var urls []string
+				for _, page := range result.Results {
+					urls = append(urls, page.Url)
+				}
+				got[result.Query] = urls
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("results = %v, want %v", got, tt.want)
+			}
+			for _, search := range firecrawl.Searches() {
+				if search.Limit != 3 {
+					t.Errorf("search %q limit = %d, want 3", search.Query, search.Limit)
+				}
+			}
+		})
+	}
+}
+
+func TestScrapWebpages(t *testing.T) {
+	tests := []struct {
+		name    string
+		urls    []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "known pages",
+			urls: []string{"https://go.dev/doc/effective_go", "https://go.dev/blog/pipelines"},
+			want: map[string]string{"https://go.dev/doc/effective_go": "# Effective Go", "https://go.dev/blog/pipelines": "# Pipelines"},
+		},
+		{name: "unknown page", urls: []string{"https://go.dev/doc/effective_go", "https://go.dev/missing"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws, firecrawl := newFirecrawlService(t, testsupport.NewFakeService())
+
+			var pages []websearch.WebPage
+			for _, url := range tt.urls {
+				pages = append(pages, websearch.WebPage{Url: url})
+			}
+			scrapped, err := ws.ScrapWebpages(context.Background(), pages, "question")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(firecrawl.Scrapes()) != len(tt.urls) {
+				t.Errorf("scrapes = %d, want %d", len(firecrawl.Scrapes()), len(tt.urls))
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got := map[string]string{}
+			for _, page := range scrapped {
+				got[page.Url] = page.Content
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("contents = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUnmatchedPromptFails checks prompts nobody scripted fail loudly instead of being answered by accident.
+func TestUnmatchedPromptFails(t *testing.T) {
+	fake := testsupport.NewFakeService()
+	fake.On(ai.User, "Resource: https://go.dev/doc/effective_go").Reply(`{"reason": "exact", "score": 1}`)
+	ws, _ := newFirecrawlService(t, fake)
+
+	_, err := ws.ScoreResults(context.Background(), []websearch.SearchResult{{Query: "q", Results: []websearch.WebPage{
+		{Url: "https://go.dev/doc/effective_go"},
+		{Url: "https://go.dev/blog/pipelines"},
+	}}}, "question")
+	if err == nil || !strings.Contains(err.Error(), "no rule matches") {
+		t.Errorf("score err = %v, want unmatched prompt error", err)
+	}
+
+	_, err = ws.GetDomainQueries(context.Background(), "question", nil)
+	if err == nil || !strings.Contains(err.Error(), "no rule matches") {
+		t.Errorf("domain queries err = %v, want unmatched prompt error", err)
+	}
+	if ws.IsSearchRequired(context.Background(), "question") {
+		t.Error("unmatched classification prompt asked for search")
+	}
+}
+
+func TestSearchPipeline(t *testing.T) {
+	fake := testsupport.NewFakeService()
+	fake.On(ai.System, "keyword-based queries").Reply(`{"_thoughts": "go docs", "queries": [{"q": "concurrency", "url": "https://go.dev"}]}`)
+	fake.On(ai.User, "Resource: https://go.dev/doc/effective_go").Reply(`{"reason": "general", "score": 0.4}`)
+	fake.On(ai.User, "Resource: https://go.dev/blog/pipelines").Reply(`{"reason": "concurrency patterns", "score": 0.9}`)
+	ws, firecrawl := newFirecrawlService(t, fake)
+	ctx := context.Background()
+
+	queries, err := ws.GetDomainQueries(ctx, "Go concurrency patterns?", []websearch.AllowedDomain{{Domain: "Go DEV", Url: "https://go.dev"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := ws.SearchForSpecificPages(ctx, queries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scored, err := ws.ScoreResults(ctx, results, "Go concurrency patterns?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scrapped, err := ws.ScrapWebpages(ctx, scored[:1], "Go concurrency patterns?")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scrapped) != 1 || scrapped[0].Url != "https://go.dev/blog/pipelines" || scrapped[0].Content != "# Pipelines" {
+		t.Errorf("scrapped = %+v, want the best scored page", scrapped)
+	}
+	if searches := firecrawl.Searches(); len(searches) != 1 || searches[0].Query != "site: https://go.dev, concurrency" {
+		t.Errorf("searches = %+v", searches)
+	}
+}
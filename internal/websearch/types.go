@@ -1,5 +1,9 @@
 package websearch
 
+import (
+	"fmt"
+)
+
 type AllowedDomain struct {
 	Domain string `json:"domain"`
 	Url    string `json:"url"`
@@ -10,6 +14,15 @@ type QueryDomains struct {
 	Queries  []Query `json:"queries"`
 }
 
+func (q *QueryDomains) Validate() error {
+	for i, query := range q.Queries {
+		if query.Q == "" || query.Url == "" {
+			return fmt.Errorf("query %d must have both \"q\" and \"url\"", i)
+		}
+	}
+	return nil
+}
+
 type Query struct {
 	Q   string `json:"q"`
 	Url string `json:"url"`
@@ -64,3 +77,10 @@ type scoringAiResponse struct {
 	Reason string  `json:"reason"`
 	Score  float64 `json:"score"`
 }
+
+func (r *scoringAiResponse) Validate() error {
+	if r.Score < 0 || r.Score > 1 {
+		return fmt.Errorf("score %v is out of range 0-1", r.Score)
+	}
+	return nil
+}
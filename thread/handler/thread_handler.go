@@ -5,18 +5,44 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/apierror"
+	"github.com/TMateusz1/go-3rd-devs/internal/sse"
+	"github.com/TMateusz1/go-3rd-devs/internal/thread"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 )
 
+type answerResponse struct {
+	ThreadID string   `json:"thread_id"`
+	Answer   string   `json:"answer"`
+	Usage    ai.Usage `json:"usage"`
+	CostUSD  float64  `json:"cost_usd"`
+}
+
+func newAnswerResponse(threadID string, answer string, tracker *ai.UsageTracker) answerResponse {
+	return answerResponse{
+		ThreadID: threadID,
+		Answer:   answer,
+		Usage:    tracker.Usage(),
+		CostUSD:  tracker.CostUSD(),
+	}
+}
+
 type ThreadHandler struct {
-	as      ai.Service
-	summary string
+	as         ai.Service
+	threads    *thread.Threads
+	memories   *thread.Memories
+	summarizer *thread.Summarizer
 }
 
-func NewThreadHandler(as ai.Service) *ThreadHandler {
+func NewThreadHandler(as ai.Service, threads *thread.Threads, memories *thread.Memories, summarizer *thread.Summarizer) *ThreadHandler {
 	return &ThreadHandler{
-		as: as,
+		as:         as,
+		threads:    threads,
+		memories:   memories,
+		summarizer: summarizer,
 	}
 }
 
@@ -24,39 +50,46 @@ func (h *ThreadHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
 	type request struct {
-		Message string `json:"message"`
+		ThreadID string `json:"thread_id"`
+		Message  string `json:"message"`
+		// Memory is the memory strategy of the thread created for the request, it's ignored for existing threads.
+		Memory string `json:"memory"`
 	}
 
 	var req request
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		apierror.BadRequest(w, "body must be JSON with message")
 		return
 	}
-
-	questionMessages := []ai.Message{
-		ai.SystemMessage(fmt.Sprintf("You are a helpful assistant who speaks using as few words as possible. <summary>%s</summary>", h.summary)),
-		ai.UserMessage(req.Message),
+	if strings.TrimSpace(req.Message) == "" {
+		apierror.BadRequest(w, "message must not be empty")
+		return
 	}
 
-	answer, err := h.as.Chat(r.Context(), questionMessages)
+	t, err := h.beginTurn(r.Context(), req.ThreadID, req.Memory)
 	if err != nil {
-		log.Printf("failed to chat with AI: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeThreadError(w, err)
 		return
 	}
+	defer h.endTurn(r.Context(), t)
 
-	err = h.setNewSummary(r.Context(), req.Message, answer)
+	ctx, tracker := ai.WithUsageTracker(r.Context())
+	r = r.WithContext(ctx)
+
+	if sse.IsRequested(r) {
+		h.handleStream(w, r, tracker, t, req.Message)
+		return
+	}
+
+	answer, err := h.answer(r.Context(), t, req.Message)
 	if err != nil {
-		log.Printf("failed to set new summary: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		apierror.Write(w, "failed to answer", err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(struct {
-		Answer string `json:"answer"`
-	}{answer})
+	err = json.NewEncoder(w).Encode(newAnswerResponse(t.thread.ID, answer, tracker))
 	if err != nil {
 		log.Printf("failed to encode response: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -65,25 +98,176 @@ func (h *ThreadHandler) Handle(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func (h *ThreadHandler) setNewSummary(ctx context.Context, message string, answer string) error {
-	newSummary, err := h.as.Chat(ctx, []ai.Message{
-		ai.SystemMessage(getNewSummaryPrompt(h.summary, message, answer)),
-		ai.UserMessage("Please summarize conversation in short way."),
-	})
+func (h *ThreadHandler) handleStream(w http.ResponseWriter, r *http.Request, tracker *ai.UsageTracker, t *turn, message string) {
+	askedAt := time.Now()
+	answerCtx, answerTracker := ai.WithUsageTracker(r.Context())
+	stream, err := h.as.ChatStream(answerCtx, questionMessages(t, message), "")
+	if err != nil {
+		apierror.Write(w, "failed to chat with AI", err)
+		return
+	}
+
+	sw, err := sse.NewWriter(w)
+	if err != nil {
+		log.Printf("failed to create sse writer: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	answer, err := sw.Forward(stream)
+	if err != nil {
+		log.Printf("failed to stream answer: %v", err)
+		_ = sw.SendError(err)
+		return
+	}
+
+	err = h.finishTurn(r.Context(), t, message, askedAt, answer, answerTracker)
+	if err != nil {
+		log.Printf("failed to finish turn: %v", err)
+		_ = sw.SendError(err)
+		return
+	}
+
+	err = sw.SendDone(newAnswerResponse(t.thread.ID, answer, tracker))
+	if err != nil {
+		log.Printf("failed to send done event: %v", err)
+	}
+}
+
+// turn is the thread locked for one question, with the memory strategy which builds the prompt from its transcript.
+type turn struct {
+	thread     thread.Thread
+	memory     thread.Memory
+	transcript []thread.Message
+	unlock     func()
+	// created threads are deleted by endTurn when the turn isn't finished, so failed questions don't leave empty threads.
+	created  bool
+	finished bool
+}
+
+// beginTurn takes the thread of the request, a new one with the memory strategy is created when id is empty.
+// The thread is locked until endTurn, so turns of one thread don't race on its summary.
+func (h *ThreadHandler) beginTurn(ctx context.Context, id string, memory string) (*turn, error) {
+	created := id == ""
+	if created {
+		name, err := h.memories.Name(memory)
+		if err != nil {
+			return nil, err
+		}
+		th, err := h.threads.Create(ctx, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		id = th.ID
+	}
+	unlock, err := h.threads.Lock(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to chat with AI: %w", err)
+		h.deleteCreated(ctx, created, id)
+		return nil, err
 	}
-	h.summary = newSummary
+	h.summarizer.Wait(ctx, id)
+
+	t, err := h.loadTurn(ctx, id)
+	if err != nil {
+		h.deleteCreated(ctx, created, id)
+		unlock()
+		return nil, err
+	}
+	t.unlock = unlock
+	t.created = created
+	return t, nil
+}
+
+// endTurn releases the thread, the thread created for the turn is deleted when the question wasn't answered.
+func (h *ThreadHandler) endTurn(ctx context.Context, t *turn) {
+	h.deleteCreated(ctx, t.created && !t.finished, t.thread.ID)
+	t.unlock()
+}
+
+func (h *ThreadHandler) deleteCreated(ctx context.Context, created bool, id string) {
+	if !created {
+		return
+	}
+	// the request may be already canceled, which is often why the turn failed
+	err := h.threads.Delete(context.WithoutCancel(ctx), id)
+	if err != nil {
+		log.Printf("failed to delete thread %s of failed turn: %v", id, err)
+	}
+}
+
+func (h *ThreadHandler) loadTurn(ctx context.Context, id string) (*turn, error) {
+	th, err := h.threads.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	memory, err := h.memories.For(th)
+	if err != nil {
+		return nil, err
+	}
+	transcript, _, err := h.threads.Messages(ctx, id, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &turn{
+		thread:     th,
+		memory:     memory,
+		transcript: transcript,
+	}, nil
+}
+
+// answer asks AI with the memory of the thread and records the new turn, the thread must be locked.
+func (h *ThreadHandler) answer(ctx context.Context, t *turn, message string) (string, error) {
+	askedAt := time.Now()
+	answerCtx, answerTracker := ai.WithUsageTracker(ctx)
+	answer, err := h.as.Chat(answerCtx, questionMessages(t, message))
+	if err != nil {
+		return "", fmt.Errorf("failed to chat with AI: %w", err)
+	}
+
+	err = h.finishTurn(ctx, t, message, askedAt, answer, answerTracker)
+	if err != nil {
+		return "", err
+	}
+	return answer, nil
+}
+
+// finishTurn records the question and the answer in the transcript, the answer with model and usage of its call.
+// Memory of the thread is updated in background, the answer doesn't wait for it.
+func (h *ThreadHandler) finishTurn(ctx context.Context, t *turn, message string, askedAt time.Time, answer string, tracker *ai.UsageTracker) error {
+	usage := tracker.Usage()
+	recorded := []thread.Message{
+		{Role: ai.User, Content: message, CreatedAt: askedAt},
+		{Role: ai.Assistant, Content: answer, Model: answeredModel(tracker), Usage: &usage, CreatedAt: time.Now()},
+	}
+	err := h.threads.AppendMessages(ctx, t.thread.ID, recorded...)
+	if err != nil {
+		return err
+	}
+	t.finished = true
+	h.summarizer.Schedule(t.thread.ID)
 	return nil
 }
 
-func getNewSummaryPrompt(previousSummary, userMessage, assistantMessage string) string {
-	return fmt.Sprintf(`
-Please summarize the following conversation in a concise manner, incorporating the previous summary if available:
-<previous_summary>%s</previous_summary>
-<current_turn> 
-User: %s 
-Assistant: %s 
-</current_turn>
-`, previousSummary, userMessage, assistantMessage)
+// answeredModel is the model with the most completion tokens, other ones could only be tried by fallback chain.
+func answeredModel(tracker *ai.UsageTracker) string {
+	var model string
+	var completionTokens int64 = -1
+	for name, usage := range tracker.UsageByModel() {
+		if usage.CompletionTokens > completionTokens {
+			model, completionTokens = name, usage.CompletionTokens
+		}
+	}
+	return model
+}
+
+func questionMessages(t *turn, message string) []ai.Message {
+	memory := t.memory.Context(t.thread, t.transcript)
+
+	system := "You are a helpful assistant who speaks using as few words as possible."
+	if memory.Summary != "" {
+		system = fmt.Sprintf("%s <summary>%s</summary>", system, memory.Summary)
+	}
+	messages := []ai.Message{ai.SystemMessage(system)}
+	messages = append(messages, memory.Messages...)
+	return append(messages, ai.UserMessage(message))
 }
@@ -0,0 +1,193 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/cassette"
+	"github.com/TMateusz1/go-3rd-devs/internal/apierror"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"github.com/TMateusz1/go-3rd-devs/internal/thread"
+	"github.com/TMateusz1/go-3rd-devs/thread/handler"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type threadAnswer struct {
+	ThreadID string `json:"thread_id"`
+	Answer   string `json:"answer"`
+	Error    string `json:"error"`
+	Code     string `json:"code"`
+}
+
+type threadFixture struct {
+	handler    *handler.ThreadHandler
+	threads    *thread.Threads
+	summarizer *thread.Summarizer
+}
+
+func newThreadFixture(t *testing.T, as ai.Service) threadFixture {
+	t.Helper()
+	memories, err := thread.NewMemoriesFromEnv(as)
+	if err != nil {
+		t.Fatal(err)
+	}
+	threads := thread.NewThreads(thread.NewMemoryStore())
+	summarizer := thread.NewSummarizer(threads, memories, thread.WithSummaryRetries(1, time.Millisecond))
+	return threadFixture{
+		handler:    handler.NewThreadHandler(as, threads, memories, summarizer),
+		threads:    threads,
+		summarizer: summarizer,
+	}
+}
+
+func (f threadFixture) ask(t *testing.T, threadID, memory, message string) (int, threadAnswer) {
+	t.Helper()
+	body, err := json.Marshal(map[string]string{"thread_id": threadID, "memory": memory, "message": message})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	f.handler.Handle(w, httptest.NewRequest(http.MethodPost, "/api/thread", strings.NewReader(string(body))))
+
+	var answer threadAnswer
+	if err := json.NewDecoder(w.Body).Decode(&answer); err != nil {
+		t.Fatalf("decoding response %d: %v", w.Code, err)
+	}
+	return w.Code, answer
+}
+
+func TestThreadHandlerReplaysConversation(t *testing.T) {
+	tests := []struct {
+		name        string
+		cassette    string
+		memory      string
+		questions   []string
+		answers     []string
+		wantSummary string
+	}{
+		{
+			name:        "summary memory",
+			cassette:    "testdata/thread_summary.json",
+			memory:      thread.MemorySummary,
+			questions:   []string{"My name is Ada and I write Go.", "What is my name?"},
+			answers:     []string{"Nice to meet you, Ada.", "Ada."},
+			wantSummary: "The user, Ada, writes Go and asked for their name.",
+		},
+		{
+			name:      "window memory",
+			cassette:  "testdata/thread_window.json",
+			memory:    thread.MemoryWindow,
+			questions: []string{"Remember number 42.", "Which number?"},
+			answers:   []string{"Remembered.", "42."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newThreadFixture(t, testsupport.CassetteService(t, tt.cassette))
+
+			threadID := ""
+			for i, question := range tt.questions {
+				status, answer := f.ask(t, threadID, tt.memory, question)
+				if status != http.StatusOK {
+					t.Fatalf("turn %d: status = %d, error %q", i, status, answer.Error)
+				}
+				if answer.Answer != tt.answers[i] {
+					t.Errorf("turn %d: answer = %q, want %q", i, answer.Answer, tt.answers[i])
+				}
+				threadID = answer.ThreadID
+			}
+
+			f.summarizer.Wait(context.Background(), threadID)
+			th, err := f.threads.Get(context.Background(), threadID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if th.Summary != tt.wantSummary {
+				t.Errorf("summary = %q, want %q", th.Summary, tt.wantSummary)
+			}
+			_, total, err := f.threads.Messages(context.Background(), threadID, 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != 2*len(tt.questions) {
+				t.Errorf("recorded messages = %d, want %d", total, 2*len(tt.questions))
+			}
+		})
+	}
+}
+
+func TestThreadHandlerFailsOnUnrecordedRequest(t *testing.T) {
+	replayer, err := cassette.NewReplayer("testdata/thread_window.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := newThreadFixture(t, replayer)
+
+	status, answer := f.ask(t, "", thread.MemoryWindow, "A question nobody recorded")
+	if status != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", status, http.StatusInternalServerError)
+	}
+	if answer.Error == "" || answer.ThreadID != "" {
+		t.Errorf("response = %+v, want error without answer", answer)
+	}
+	// the thread created for the failed question is deleted
+	threads, err := f.threads.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(threads) != 0 {
+		t.Errorf("threads = %d, want none", len(threads))
+	}
+}
+
+func TestThreadHandlerRejectsEmptyMessage(t *testing.T) {
+	fake := testsupport.NewFakeService()
+	f := newThreadFixture(t, fake)
+
+	for _, message := range []string{"", "  \n"} {
+		status, answer := f.ask(t, "", thread.MemoryWindow, message)
+		if status != http.StatusBadRequest || answer.Code != apierror.CodeInvalidRequest {
+			t.Errorf("message %q: status = %d, code %q, want %d", message, status, answer.Code, http.StatusBadRequest)
+		}
+	}
+	if calls := fake.Calls(); len(calls) != 0 {
+		t.Errorf("AI calls = %d, want none", len(calls))
+	}
+}
+
+func TestThreadHandlerStreams(t *testing.T) {
+	fake := testsupport.NewFakeService()
+	fake.On(ai.User, "My name is Ada").Reply("Nice to meet you, Ada.")
+	fake.On(ai.System, "summarize").Reply("The user is Ada.")
+	f := newThreadFixture(t, fake)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/thread", strings.NewReader(`{"message":"My name is Ada."}`))
+	r.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+	f.handler.Handle(w, r)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status = %d, content type %q, want event stream", w.Code, w.Header().Get("Content-Type"))
+	}
+	events := testsupport.ReadEvents(t, w.Body)
+	if names := testsupport.EventNames(events); !reflect.DeepEqual(names, []string{"delta", "done"}) {
+		t.Fatalf("events = %v, want deltas and done", names)
+	}
+	var done threadAnswer
+	events[len(events)-1].Decode(t, &done)
+	if done.Answer != "Nice to meet you, Ada." || done.ThreadID == "" {
+		t.Fatalf("done = %+v, want the answer with thread id", done)
+	}
+
+	f.summarizer.Wait(context.Background(), done.ThreadID)
+	transcript, _, err := f.threads.Messages(context.Background(), done.ThreadID, 0, 0)
+	if err != nil || len(transcript) != 2 || transcript[1].Content != done.Answer {
+		t.Errorf("transcript = %+v, %v, want the streamed turn", transcript, err)
+	}
+}
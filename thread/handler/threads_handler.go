@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/apierror"
+	"github.com/TMateusz1/go-3rd-devs/internal/thread"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultMessagesLimit = 50
+	maxMessagesLimit     = 200
+)
+
+// Create starts a new empty thread with optional memory strategy and metadata, its id is sent with messages of the conversation.
+func (h *ThreadHandler) Create(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	type request struct {
+		Memory   string            `json:"memory"`
+		Metadata map[string]string `json:"metadata"`
+	}
+
+	var req request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		apierror.BadRequest(w, "body must be JSON with optional memory and metadata")
+		return
+	}
+
+	memory, err := h.memories.Name(req.Memory)
+	if err != nil {
+		writeThreadError(w, err)
+		return
+	}
+	th, err := h.threads.Create(r.Context(), memory, req.Metadata)
+	if err != nil {
+		writeThreadError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, th)
+}
+
+func (h *ThreadHandler) List(w http.ResponseWriter, r *http.Request) {
+	threads, err := h.threads.List(r.Context())
+	if err != nil {
+		writeThreadError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Threads []thread.Thread `json:"threads"`
+	}{threads})
+}
+
+// Get returns the thread with its summary, metadata and the number of messages of the transcript.
+func (h *ThreadHandler) Get(w http.ResponseWriter, r *http.Request) {
+	th, err := h.threads.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeThreadError(w, err)
+		return
+	}
+	_, total, err := h.threads.Messages(r.Context(), th.ID, 0, 1)
+	if err != nil {
+		writeThreadError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		thread.Thread
+		MessageCount int `json:"message_count"`
+	}{th, total})
+}
+
+// Messages returns the transcript from the oldest message, paginated with offset and limit query params.
+func (h *ThreadHandler) Messages(w http.ResponseWriter, r *http.Request) {
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		apierror.BadRequest(w, "offset must be non-negative number")
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultMessagesLimit)
+	if err != nil || limit < 1 || limit > maxMessagesLimit {
+		apierror.BadRequest(w, fmt.Sprintf("limit must be number from 1 to %d", maxMessagesLimit))
+		return
+	}
+
+	messages, total, err := h.threads.Messages(r.Context(), r.PathValue("id"), offset, limit)
+	if err != nil {
+		writeThreadError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Messages []thread.Message `json:"messages"`
+		Offset   int              `json:"offset"`
+		Limit    int              `json:"limit"`
+		Total    int              `json:"total"`
+	}{messages, offset, limit, total})
+}
+
+func (h *ThreadHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	err := h.threads.Delete(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeThreadError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func queryInt(r *http.Request, name string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func writeThreadError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, thread.ErrNotFound):
+		apierror.WriteError(w, apierror.Error{Status: http.StatusNotFound, Code: apierror.CodeNotFound, Message: "thread not found"})
+	case errors.Is(err, thread.ErrUnknownMemory):
+		apierror.BadRequest(w, fmt.Sprintf("memory must be one of %s, %s, %s, %s", thread.MemorySummary, thread.MemoryWindow, thread.MemoryTokenBudget, thread.MemoryHybrid))
+	default:
+		apierror.Write(w, "thread error", err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(data)
+	if err != nil {
+		log.Printf("failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/apierror"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"github.com/TMateusz1/go-3rd-devs/internal/thread"
+	"github.com/TMateusz1/go-3rd-devs/thread/handler"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// brokenStore fails listing like an unreadable store directory.
+type brokenStore struct {
+	thread.Store
+}
+
+func (brokenStore) List(context.Context) ([]thread.Thread, error) {
+	return nil, errors.New("open /var/threads: permission denied")
+}
+
+func threadsMux(t *testing.T, store thread.Store) *http.ServeMux {
+	t.Helper()
+	as := testsupport.NewFakeService()
+	memories, err := thread.NewMemoriesFromEnv(as)
+	if err != nil {
+		t.Fatal(err)
+	}
+	threads := thread.NewThreads(store)
+	h := handler.NewThreadHandler(as, threads, memories, thread.NewSummarizer(threads, memories))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/thread", h.Handle)
+	mux.HandleFunc("GET /api/thread", h.List)
+	mux.HandleFunc("POST /api/thread/new", h.Create)
+	mux.HandleFunc("GET /api/thread/{id}", h.Get)
+	mux.HandleFunc("GET /api/thread/{id}/messages", h.Messages)
+	mux.HandleFunc("DELETE /api/thread/{id}", h.Delete)
+	return mux
+}
+
+func TestThreadsHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		store      thread.Store
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantCode   string
+		wantError  string
+	}{
+		{name: "get unknown thread", method: http.MethodGet, target: "/api/thread/missing", wantStatus: http.StatusNotFound, wantCode: apierror.CodeNotFound, wantError: "thread not found"},
+		{name: "messages of unknown thread", method: http.MethodGet, target: "/api/thread/missing/messages", wantStatus: http.StatusNotFound, wantCode: apierror.CodeNotFound, wantError: "thread not found"},
+		{name: "delete unknown thread", method: http.MethodDelete, target: "/api/thread/missing", wantStatus: http.StatusNotFound, wantCode: apierror.CodeNotFound, wantError: "thread not found"},
+		{name: "answer in unknown thread", method: http.MethodPost, target: "/api/thread", body: `{"thread_id": "missing", "message": "hi"}`, wantStatus: http.StatusNotFound, wantCode: apierror.CodeNotFound, wantError: "thread not found"},
+		{
+			name:       "create with unknown memory",
+			method:     http.MethodPost,
+			target:     "/api/thread/new",
+			body:       `{"memory": "forever"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeInvalidRequest,
+			wantError:  "memory must be one of summary, window, token_budget, hybrid",
+		},
+		{name: "create with invalid body", method: http.MethodPost, target: "/api/thread/new", body: `{"memory":`, wantStatus: http.StatusBadRequest, wantCode: apierror.CodeInvalidRequest, wantError: "body must be JSON"},
+		{name: "answer with invalid body", method: http.MethodPost, target: "/api/thread", body: `hi`, wantStatus: http.StatusBadRequest, wantCode: apierror.CodeInvalidRequest, wantError: "body must be JSON"},
+		{name: "broken store", store: brokenStore{thread.NewMemoryStore()}, method: http.MethodGet, target: "/api/thread", wantStatus: http.StatusInternalServerError, wantCode: apierror.CodeInternal, wantError: "internal error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.store
+			if store == nil {
+				store = thread.NewMemoryStore()
+			}
+			w := httptest.NewRecorder()
+			threadsMux(t, store).ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("content type = %q, want JSON", contentType)
+			}
+			var got apierror.Error
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Code != tt.wantCode || !strings.HasPrefix(got.Message, tt.wantError) {
+				t.Errorf("error = %+v, want code %s with %q", got, tt.wantCode, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestThreadMessagesPagination(t *testing.T) {
+	store := thread.NewMemoryStore()
+	th := thread.Thread{ID: "paged"}
+	if err := store.Create(context.Background(), th); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 60 {
+		if err := store.AppendMessages(context.Background(), th.ID, thread.Message{Role: ai.User, Content: fmt.Sprintf("m%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mux := threadsMux(t, store)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantFirst  string
+		wantCount  int
+		wantOffset int
+		wantLimit  int
+	}{
+		{name: "default limit", wantStatus: http.StatusOK, wantFirst: "m0", wantCount: 50, wantLimit: 50},
+		{name: "page", query: "?offset=10&limit=5", wantStatus: http.StatusOK, wantFirst: "m10", wantCount: 5, wantOffset: 10, wantLimit: 5},
+		{name: "last partial page", query: "?offset=55&limit=10", wantStatus: http.StatusOK, wantFirst: "m55", wantCount: 5, wantOffset: 55, wantLimit: 10},
+		{name: "past the end", query: "?offset=60", wantStatus: http.StatusOK, wantCount: 0, wantOffset: 60, wantLimit: 50},
+		{name: "max limit", query: "?limit=200", wantStatus: http.StatusOK, wantFirst: "m0", wantCount: 60, wantLimit: 200},
+		{name: "limit over max", query: "?limit=201", wantStatus: http.StatusBadRequest},
+		{name: "zero limit", query: "?limit=0", wantStatus: http.StatusBadRequest},
+		{name: "negative offset", query: "?offset=-1", wantStatus: http.StatusBadRequest},
+		{name: "not a number", query: "?offset=ten", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/thread/paged/messages"+tt.query, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			var got struct {
+				Messages []thread.Message `json:"messages"`
+				Offset   int              `json:"offset"`
+				Limit    int              `json:"limit"`
+				Total    int              `json:"total"`
+				Code     string           `json:"code"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if got.Code != apierror.CodeInvalidRequest {
+					t.Errorf("code = %q, want %q", got.Code, apierror.CodeInvalidRequest)
+				}
+				return
+			}
+			if got.Messages == nil || len(got.Messages) != tt.wantCount || got.Total != 60 || got.Offset != tt.wantOffset || got.Limit != tt.wantLimit {
+				t.Errorf("page = %d messages, offset %d, limit %d of %d, want %d, offset %d, limit %d of 60",
+					len(got.Messages), got.Offset, got.Limit, got.Total, tt.wantCount, tt.wantOffset, tt.wantLimit)
+			}
+			if tt.wantCount > 0 && got.Messages[0].Content != tt.wantFirst {
+				t.Errorf("first message = %q, want %q", got.Messages[0].Content, tt.wantFirst)
+			}
+		})
+	}
+}
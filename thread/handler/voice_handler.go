@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/apierror"
+	"log"
+	"net/http"
+)
+
+const maxAudioUploadSize = 25 << 20
+
+type voiceResponse struct {
+	ThreadID   string `json:"thread_id"`
+	Transcript string `json:"transcript"`
+	Answer     string `json:"answer"`
+	// Audio is base64 encoded synthesized answer.
+	Audio            string `json:"audio,omitempty"`
+	AudioContentType string `json:"audio_content_type,omitempty"`
+	// AudioError is set instead of Audio when the answer was recorded but couldn't be synthesized.
+	AudioError *apierror.Error `json:"audio_error,omitempty"`
+	Usage      ai.Usage        `json:"usage"`
+	CostUSD    float64         `json:"cost_usd"`
+}
+
+// VoiceHandler is a voice front-end of the thread, it shares the conversation with ThreadHandler.
+type VoiceHandler struct {
+	th    *ThreadHandler
+	audio ai.AudioService
+}
+
+func NewVoiceHandler(th *ThreadHandler, audio ai.AudioService) *VoiceHandler {
+	return &VoiceHandler{
+		th:    th,
+		audio: audio,
+	}
+}
+
+// Handle transcribes the audio form file, answers it in the thread and synthesizes the answer.
+func (h *VoiceHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAudioUploadSize)
+	file, header, err := r.FormFile("audio")
+	if err != nil {
+		log.Printf("failed to read audio upload: %v", err)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			apierror.WriteError(w, apierror.Error{Status: http.StatusRequestEntityTooLarge, Code: apierror.CodeInvalidRequest, Message: fmt.Sprintf("audio exceeds %d MB", maxAudioUploadSize>>20)})
+			return
+		}
+		apierror.BadRequest(w, "audio file is required")
+		return
+	}
+	defer file.Close()
+
+	ctx, tracker := ai.WithUsageTracker(r.Context())
+	r = r.WithContext(ctx)
+
+	transcription, err := h.audio.Transcribe(r.Context(), ai.TranscriptionRequest{
+		Audio:    file,
+		Filename: header.Filename,
+		Language: r.FormValue("language"),
+	})
+	if err != nil {
+		apierror.Write(w, "failed to transcribe", err)
+		return
+	}
+	if transcription.Text == "" {
+		apierror.WriteError(w, apierror.Error{Status: http.StatusUnprocessableEntity, Code: apierror.CodeInvalidRequest, Message: "no speech recognized"})
+		return
+	}
+
+	t, err := h.th.beginTurn(r.Context(), r.FormValue("thread_id"), r.FormValue("memory"))
+	if err != nil {
+		writeThreadError(w, err)
+		return
+	}
+	answer, err := h.th.answer(r.Context(), t, transcription.Text)
+	h.th.endTurn(r.Context(), t)
+	if err != nil {
+		apierror.Write(w, "failed to answer", err)
+		return
+	}
+
+	resp := voiceResponse{
+		ThreadID:   t.thread.ID,
+		Transcript: transcription.Text,
+		Answer:     answer,
+	}
+	// the answer is already in the thread, so failed synthesis mustn't lose it
+	speech, err := h.audio.Speak(r.Context(), ai.SpeechRequest{
+		Input: answer,
+		Voice: r.FormValue("voice"),
+	})
+	if err != nil {
+		log.Printf("failed to synthesize answer: %v", err)
+		audioErr := apierror.FromError(err)
+		resp.AudioError = &audioErr
+	} else {
+		resp.Audio = base64.StdEncoding.EncodeToString(speech.Audio)
+		resp.AudioContentType = speech.ContentType
+	}
+	resp.Usage = tracker.Usage()
+	resp.CostUSD = tracker.CostUSD()
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		log.Printf("failed to encode response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,170 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/apierror"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"github.com/TMateusz1/go-3rd-devs/internal/thread"
+	"github.com/TMateusz1/go-3rd-devs/thread/handler"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeAudio transcribes every upload to the transcript and speaks the answer as its bytes.
+type fakeAudio struct {
+	transcript    string
+	transcribeErr error
+	speakErr      error
+}
+
+func (f fakeAudio) Transcribe(_ context.Context, req ai.TranscriptionRequest) (ai.Transcription, error) {
+	if _, err := io.ReadAll(req.Audio); err != nil {
+		return ai.Transcription{}, err
+	}
+	return ai.Transcription{Text: f.transcript}, f.transcribeErr
+}
+
+func (f fakeAudio) Speak(_ context.Context, req ai.SpeechRequest) (ai.Speech, error) {
+	if f.speakErr != nil {
+		return ai.Speech{}, f.speakErr
+	}
+	return ai.Speech{Audio: []byte(req.Input), ContentType: "audio/mpeg"}, nil
+}
+
+type voiceAnswer struct {
+	ThreadID   string          `json:"thread_id"`
+	Transcript string          `json:"transcript"`
+	Answer     string          `json:"answer"`
+	Audio      string          `json:"audio"`
+	AudioError *apierror.Error `json:"audio_error"`
+	Error      string          `json:"error"`
+	Code       string          `json:"code"`
+}
+
+func voiceRequest(t *testing.T, withAudio bool) *http.Request {
+	t.Helper()
+	body := bytes.Buffer{}
+	mw := multipart.NewWriter(&body)
+	if err := mw.WriteField("memory", thread.MemoryWindow); err != nil {
+		t.Fatal(err)
+	}
+	if withAudio {
+		part, err := mw.CreateFormFile("audio", "question.webm")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := part.Write([]byte("webm")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/thread/voice", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestVoiceHandler(t *testing.T) {
+	tests := []struct {
+		name          string
+		audio         fakeAudio
+		withoutUpload bool
+		wantStatus    int
+		wantCode      string
+		wantAnswer    string
+		wantAudio     string
+		wantAudioCode string
+		wantMessages  int
+	}{
+		{
+			name:         "spoken answer",
+			audio:        fakeAudio{transcript: "What is Go?"},
+			wantStatus:   http.StatusOK,
+			wantAnswer:   "A programming language.",
+			wantAudio:    "A programming language.",
+			wantMessages: 2,
+		},
+		{
+			name:          "failed synthesis keeps text answer",
+			audio:         fakeAudio{transcript: "What is Go?", speakErr: ai.ErrProviderUnavailable},
+			wantStatus:    http.StatusOK,
+			wantAnswer:    "A programming language.",
+			wantAudioCode: apierror.CodeProviderUnavailable,
+			wantMessages:  2,
+		},
+		{
+			name:          "missing audio",
+			withoutUpload: true,
+			wantStatus:    http.StatusBadRequest,
+			wantCode:      apierror.CodeInvalidRequest,
+		},
+		{
+			name:       "no speech",
+			audio:      fakeAudio{},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   apierror.CodeInvalidRequest,
+		},
+		{
+			name:       "failed transcription",
+			audio:      fakeAudio{transcribeErr: ai.ErrRateLimited},
+			wantStatus: http.StatusTooManyRequests,
+			wantCode:   apierror.CodeRateLimited,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := testsupport.NewFakeService()
+			fake.On(ai.User, "What is Go").Reply("A programming language.")
+			f := newThreadFixture(t, fake)
+			w := httptest.NewRecorder()
+			handler.NewVoiceHandler(f.handler, tt.audio).Handle(w, voiceRequest(t, !tt.withoutUpload))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("content type = %q, want JSON", contentType)
+			}
+			var answer voiceAnswer
+			if err := json.NewDecoder(w.Body).Decode(&answer); err != nil {
+				t.Fatal(err)
+			}
+			if answer.Code != tt.wantCode || (tt.wantCode != "") != (answer.Error != "") {
+				t.Errorf("error = %q with code %q, want code %q", answer.Error, answer.Code, tt.wantCode)
+			}
+			if answer.Answer != tt.wantAnswer {
+				t.Errorf("answer = %q, want %q", answer.Answer, tt.wantAnswer)
+			}
+			if audio, _ := base64.StdEncoding.DecodeString(answer.Audio); string(audio) != tt.wantAudio {
+				t.Errorf("audio = %q, want %q", audio, tt.wantAudio)
+			}
+			audioCode := ""
+			if answer.AudioError != nil {
+				audioCode = answer.AudioError.Code
+			}
+			if audioCode != tt.wantAudioCode {
+				t.Errorf("audio error code = %q, want %q", audioCode, tt.wantAudioCode)
+			}
+
+			if tt.wantMessages == 0 {
+				return
+			}
+			_, total, err := f.threads.Messages(context.Background(), answer.ThreadID, 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != tt.wantMessages {
+				t.Errorf("recorded messages = %d, want %d", total, tt.wantMessages)
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/cassette"
 	"github.com/TMateusz1/go-3rd-devs/internal/middleware"
+	"github.com/TMateusz1/go-3rd-devs/internal/thread"
 	"github.com/TMateusz1/go-3rd-devs/thread/handler"
 	_ "github.com/joho/godotenv/autoload"
 	"log"
@@ -10,14 +12,49 @@ import (
 )
 
 func main() {
-	as, err := ai.NewOpenaiService()
+	base, err := cassette.FromEnv(func() (ai.Service, error) {
+		return ai.NewServiceFromEnv()
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	as, err := ai.NewServiceBuilder(base).
+		Wrap(func(s ai.Service) (ai.Service, error) {
+			return ai.NewRetryService(s)
+		}).
+		Use(ai.InterceptorsFromEnv()...).
+		Build()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	store, err := thread.NewStoreFromEnv()
 	if err != nil {
 		log.Fatalln(err)
 	}
 
-	th := handler.NewThreadHandler(as)
+	memories, err := thread.NewMemoriesFromEnv(as)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	threads := thread.NewThreads(store)
+	th := handler.NewThreadHandler(as, threads, memories, thread.NewSummarizer(threads, memories))
 	mux := http.NewServeMux()
-	mux.HandleFunc("POST /api/thread", middleware.LogMiddleware(th.Handle))
+	mux.HandleFunc("POST /api/thread", middleware.LogMiddleware(middleware.RequestIDMiddleware(th.Handle)))
+	mux.HandleFunc("GET /api/thread", middleware.LogMiddleware(th.List))
+	mux.HandleFunc("POST /api/thread/new", middleware.LogMiddleware(th.Create))
+	mux.HandleFunc("GET /api/thread/{id}", middleware.LogMiddleware(th.Get))
+	mux.HandleFunc("GET /api/thread/{id}/messages", middleware.LogMiddleware(th.Messages))
+	mux.HandleFunc("DELETE /api/thread/{id}", middleware.LogMiddleware(th.Delete))
+
+	audio, err := ai.NewOpenaiAudioService()
+	if err != nil {
+		log.Printf("voice endpoint disabled: %v", err)
+	} else {
+		vh := handler.NewVoiceHandler(th, audio)
+		mux.HandleFunc("POST /api/thread/voice", middleware.LogMiddleware(middleware.RequestIDMiddleware(vh.Handle)))
+	}
 
 	s := http.Server{
 		Addr:    ":8080",
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/apierror"
+	"github.com/TMateusz1/go-3rd-devs/internal/sse"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+)
+
+const (
+	maxUploadSize = 20 << 20
+	maxImages     = 5
+)
+
+var errUnsupportedImage = errors.New("unsupported image type")
+
+var allowedImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+type answerResponse struct {
+	Answer  string   `json:"answer"`
+	Usage   ai.Usage `json:"usage"`
+	CostUSD float64  `json:"cost_usd"`
+}
+
+func newAnswerResponse(answer string, tracker *ai.UsageTracker) answerResponse {
+	return answerResponse{
+		Answer:  answer,
+		Usage:   tracker.Usage(),
+		CostUSD: tracker.CostUSD(),
+	}
+}
+
+type VisionHandler struct {
+	as ai.Service
+}
+
+func NewVisionHandler(as ai.Service) *VisionHandler {
+	return &VisionHandler{
+		as: as,
+	}
+}
+
+// Handle answers the question form field about uploaded image files (multipart/form-data).
+func (h *VisionHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	err := r.ParseMultipartForm(maxUploadSize)
+	if err != nil {
+		log.Printf("failed to parse upload: %v", err)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			apierror.WriteError(w, apierror.Error{Status: http.StatusRequestEntityTooLarge, Code: apierror.CodeInvalidRequest, Message: fmt.Sprintf("upload exceeds %d MB", maxUploadSize>>20)})
+			return
+		}
+		apierror.BadRequest(w, "multipart form with question and image files is required")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	question := r.FormValue("question")
+	detail := ai.ImageDetail(r.FormValue("detail"))
+	files := r.MultipartForm.File["image"]
+	if question == "" || len(files) == 0 || len(files) > maxImages {
+		apierror.BadRequest(w, fmt.Sprintf("question and 1-%d image files are required", maxImages))
+		return
+	}
+	switch detail {
+	case "", ai.ImageDetailAuto, ai.ImageDetailLow, ai.ImageDetailHigh:
+	default:
+		apierror.BadRequest(w, "detail must be auto, low or high")
+		return
+	}
+
+	parts := []ai.ContentPart{ai.TextPart(question)}
+	for _, file := range files {
+		part, err := readImagePart(file, detail)
+		if errors.Is(err, errUnsupportedImage) {
+			log.Printf("rejected image %s: %v", file.Filename, err)
+			apierror.BadRequest(w, fmt.Sprintf("%s is not png, jpeg, gif or webp image", file.Filename))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, "failed to read image "+file.Filename, err)
+			return
+		}
+		parts = append(parts, part)
+	}
+
+	ctx, tracker := ai.WithUsageTracker(r.Context())
+	r = r.WithContext(ctx)
+
+	questionMessages := []ai.Message{
+		ai.SystemMessage("You are a helpful assistant who answers questions about provided images using as few words as possible."),
+		ai.UserPartsMessage(parts...),
+	}
+
+	if sse.IsRequested(r) {
+		h.handleStream(w, r, tracker, questionMessages)
+		return
+	}
+
+	answer, err := h.as.Chat(r.Context(), questionMessages)
+	if err != nil {
+		apierror.Write(w, "failed to chat with AI", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(newAnswerResponse(answer, tracker))
+	if err != nil {
+		log.Printf("failed to encode response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *VisionHandler) handleStream(w http.ResponseWriter, r *http.Request, tracker *ai.UsageTracker, questionMessages []ai.Message) {
+	stream, err := h.as.ChatStream(r.Context(), questionMessages, "")
+	if err != nil {
+		apierror.Write(w, "failed to chat with AI", err)
+		return
+	}
+
+	sw, err := sse.NewWriter(w)
+	if err != nil {
+		log.Printf("failed to create sse writer: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	answer, err := sw.Forward(stream)
+	if err != nil {
+		log.Printf("failed to stream answer: %v", err)
+		_ = sw.SendError(err)
+		return
+	}
+
+	err = sw.SendDone(newAnswerResponse(answer, tracker))
+	if err != nil {
+		log.Printf("failed to send done event: %v", err)
+	}
+}
+
+// readImagePart detects the media type from content, because browsers often send application/octet-stream.
+func readImagePart(header *multipart.FileHeader, detail ai.ImageDetail) (ai.ContentPart, error) {
+	file, err := header.Open()
+	if err != nil {
+		return ai.ContentPart{}, fmt.Errorf("opening upload: %w", err)
+	}
+	defer file.Close()
+
+	image, err := io.ReadAll(file)
+	if err != nil {
+		return ai.ContentPart{}, fmt.Errorf("reading upload: %w", err)
+	}
+
+	mediaType := http.DetectContentType(image)
+	if !allowedImageTypes[mediaType] {
+		return ai.ContentPart{}, fmt.Errorf("%w %s of %s", errUnsupportedImage, mediaType, header.Filename)
+	}
+	return ai.ImageBase64Part(mediaType, image, detail), nil
+}
@@ -0,0 +1,192 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/apierror"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"github.com/TMateusz1/go-3rd-devs/vision/handler"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var pngImage = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+type visionAnswer struct {
+	Answer string `json:"answer"`
+	Error  string `json:"error"`
+	Code   string `json:"code"`
+}
+
+type upload struct {
+	name    string
+	content []byte
+}
+
+func visionRequest(t *testing.T, fields map[string]string, images []upload) *http.Request {
+	t.Helper()
+	body := bytes.Buffer{}
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := mw.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, image := range images {
+		part, err := mw.CreateFormFile("image", image.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := part.Write(image.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/vision", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestVisionHandler(t *testing.T) {
+	image := upload{name: "cat.png", content: pngImage}
+
+	tests := []struct {
+		name       string
+		req        func(t *testing.T) *http.Request
+		reply      error
+		wantStatus int
+		wantCode   string
+		wantError  string
+	}{
+		{
+			name: "answer",
+			req: func(t *testing.T) *http.Request {
+				return visionRequest(t, map[string]string{"question": "What is it?"}, []upload{image})
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "not multipart",
+			req: func(t *testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/api/vision", strings.NewReader("{}"))
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeInvalidRequest,
+			wantError:  "multipart form",
+		},
+		{
+			name:       "missing question",
+			req:        func(t *testing.T) *http.Request { return visionRequest(t, nil, []upload{image}) },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeInvalidRequest,
+			wantError:  "question and 1-5 image files are required",
+		},
+		{
+			name: "too many images",
+			req: func(t *testing.T) *http.Request {
+				return visionRequest(t, map[string]string{"question": "What is it?"}, []upload{image, image, image, image, image, image})
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeInvalidRequest,
+			wantError:  "question and 1-5 image files are required",
+		},
+		{
+			name: "invalid detail",
+			req: func(t *testing.T) *http.Request {
+				return visionRequest(t, map[string]string{"question": "What is it?", "detail": "ultra"}, []upload{image})
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeInvalidRequest,
+			wantError:  "detail must be auto, low or high",
+		},
+		{
+			name: "unsupported image",
+			req: func(t *testing.T) *http.Request {
+				return visionRequest(t, map[string]string{"question": "What is it?"}, []upload{{name: "notes.txt", content: []byte("plain text")}})
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierror.CodeInvalidRequest,
+			wantError:  "notes.txt is not png, jpeg, gif or webp image",
+		},
+		{
+			name: "rate limited",
+			req: func(t *testing.T) *http.Request {
+				return visionRequest(t, map[string]string{"question": "What is it?"}, []upload{image})
+			},
+			reply:      ai.ErrRateLimited,
+			wantStatus: http.StatusTooManyRequests,
+			wantCode:   apierror.CodeRateLimited,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := testsupport.NewFakeService()
+			if tt.reply != nil {
+				fake.On(ai.System, ".").ReplyError(tt.reply)
+			} else {
+				fake.On(ai.System, "images").Reply("A cat.")
+			}
+			w := httptest.NewRecorder()
+			handler.NewVisionHandler(fake).Handle(w, tt.req(t))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("content type = %q, want JSON", contentType)
+			}
+			var answer visionAnswer
+			if err := json.NewDecoder(w.Body).Decode(&answer); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if answer.Answer != "A cat." {
+					t.Errorf("answer = %q", answer.Answer)
+				}
+				return
+			}
+			if answer.Code != tt.wantCode || !strings.Contains(answer.Error, tt.wantError) {
+				t.Errorf("error = %+v, want code %s with %q", answer, tt.wantCode, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestVisionHandlerStreams(t *testing.T) {
+	fake := testsupport.NewFakeService()
+	fake.On(ai.System, "images").Reply("A black cat.")
+	r := visionRequest(t, map[string]string{"question": "What is it?"}, []upload{{name: "cat.png", content: pngImage}})
+	r.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+
+	handler.NewVisionHandler(fake).Handle(w, r)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status = %d, content type %q, want event stream", w.Code, w.Header().Get("Content-Type"))
+	}
+	events := testsupport.ReadEvents(t, w.Body)
+	if names := testsupport.EventNames(events); !reflect.DeepEqual(names, []string{"delta", "done"}) {
+		t.Fatalf("events = %v, want deltas and done", names)
+	}
+	var deltas strings.Builder
+	for _, event := range events[:len(events)-1] {
+		var delta struct {
+			Content string `json:"content"`
+		}
+		event.Decode(t, &delta)
+		deltas.WriteString(delta.Content)
+	}
+	var done visionAnswer
+	events[len(events)-1].Decode(t, &done)
+	if deltas.String() != "A black cat." || done.Answer != deltas.String() {
+		t.Errorf("deltas = %q, done = %+v, want the whole answer in both", deltas.String(), done)
+	}
+}
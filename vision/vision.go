@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/cassette"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"github.com/TMateusz1/go-3rd-devs/internal/middleware"
+	"github.com/TMateusz1/go-3rd-devs/vision/handler"
+	_ "github.com/joho/godotenv/autoload"
+	"log"
+	"net/http"
+)
+
+func main() {
+	base, err := cassette.FromEnv(func() (ai.Service, error) {
+		return ai.NewServiceFromEnv(option.WithBaseModel(ai.OpenRouterModelGPT4oMini))
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	as, err := ai.NewServiceBuilder(base).
+		Wrap(func(s ai.Service) (ai.Service, error) {
+			return ai.NewRetryService(s)
+		}).
+		Use(ai.InterceptorsFromEnv()...).
+		Build()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	vh := handler.NewVisionHandler(as)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/vision", middleware.LogMiddleware(middleware.RequestIDMiddleware(vh.Handle)))
+
+	s := http.Server{
+		Addr:    ":8080",
+		Handler: mux,
+	}
+
+	log.Println("Starting server on: ", s.Addr)
+	if err := s.ListenAndServe(); err != nil {
+		log.Fatalln(err)
+	}
+}
@@ -1,31 +1,67 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/apierror"
+	"github.com/TMateusz1/go-3rd-devs/internal/sse"
 	"github.com/TMateusz1/go-3rd-devs/internal/websearch"
 	"log"
 	"net/http"
+	"slices"
 	"strings"
 )
 
 var allowedDomains = []websearch.AllowedDomain{
-	{"Wikipedia.org", "https://en.wikipedia.org"},
-	{"OpenAI", "https://openai.com"},
-	{"Go DEV", "https://go.dev"},
-	{"Ardan Labs Golang courses!", "https://www.ardanlabs.com"},
+	{Domain: "Wikipedia.org", Url: "https://en.wikipedia.org"},
+	{Domain: "OpenAI", Url: "https://openai.com"},
+	{Domain: "Go DEV", Url: "https://go.dev"},
+	{Domain: "Ardan Labs Golang courses!", Url: "https://www.ardanlabs.com"},
+}
+
+type answerResponse struct {
+	Answer  string   `json:"answer"`
+	Usage   ai.Usage `json:"usage"`
+	CostUSD float64  `json:"cost_usd"`
+	// Model is set when the answer comes from a fallback chain.
+	Model        string        `json:"model,omitempty"`
+	FailedModels []failedModel `json:"failed_models,omitempty"`
+}
+
+// failedModel exposes only the error code, raw errors of providers are logged by the fallback service.
+type failedModel struct {
+	Model string `json:"model"`
+	Code  string `json:"code"`
+}
+
+func newAnswerResponse(answer string, tracker *ai.UsageTracker, report *ai.FallbackReport) answerResponse {
+	var failed []failedModel
+	for _, f := range report.Failed() {
+		failed = append(failed, failedModel{Model: f.Model, Code: apierror.FromError(f.Err).Code})
+	}
+	return answerResponse{
+		Answer:       answer,
+		Usage:        tracker.Usage(),
+		CostUSD:      tracker.CostUSD(),
+		Model:        report.AnsweredBy(),
+		FailedModels: failed,
+	}
 }
 
 type WebSearchHandler struct {
 	as ai.Service
 	ws websearch.Service
+	// budget keeps scraped pages within the context window of the answering model.
+	budget *ai.Budget
 }
 
-func NewWebSearchHandler(as ai.Service, ws websearch.Service) *WebSearchHandler {
+func NewWebSearchHandler(as ai.Service, ws websearch.Service, budget *ai.Budget) *WebSearchHandler {
 	return &WebSearchHandler{
-		as: as,
-		ws: ws,
+		as:     as,
+		ws:     ws,
+		budget: budget,
 	}
 }
 
@@ -42,61 +78,154 @@ func (h *WebSearchHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+
+	ctx, tracker := ai.WithUsageTracker(r.Context())
+	ctx, report := ai.WithFallbackReport(ctx)
+	r = r.WithContext(ctx)
+
+	if sse.IsRequested(r) {
+		h.handleStream(w, r, tracker, report, req.Message)
+		return
+	}
+
+	answerMessages, err := h.prepare(r.Context(), req.Message, func(string) {})
+	if err != nil {
+		apierror.Write(w, "failed to prepare answer", err)
+		return
+	}
+
+	answer, err := h.as.Chat(r.Context(), answerMessages)
+
+	if err != nil {
+		apierror.Write(w, "failed to answer", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(newAnswerResponse(answer, tracker, report))
+
+	if err != nil {
+		log.Printf("failed to encode response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+}
+
+// handleStream opens the stream before searching, the client gets a progress event for every stage instead of waiting for the whole pipeline.
+// Headers are sent by then, so errors of every stage are sent as error events.
+func (h *WebSearchHandler) handleStream(w http.ResponseWriter, r *http.Request, tracker *ai.UsageTracker, report *ai.FallbackReport, message string) {
+	sw, err := sse.NewWriter(w)
+	if err != nil {
+		log.Printf("failed to create sse writer: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	answerMessages, err := h.prepare(r.Context(), message, func(stage string) {
+		err := sw.Send("progress", progressEvent{Stage: stage})
+		if err != nil {
+			log.Printf("failed to send progress event: %v", err)
+		}
+	})
+	if err != nil {
+		log.Printf("failed to prepare answer: %v", err)
+		_ = sw.SendError(err)
+		return
+	}
+
+	stream, err := h.as.ChatStream(r.Context(), answerMessages, "")
+	if err != nil {
+		log.Printf("failed to stream answer: %v", err)
+		_ = sw.SendError(err)
+		return
+	}
+
+	answer, err := sw.Forward(stream)
+	if err != nil {
+		log.Printf("failed to stream answer: %v", err)
+		_ = sw.SendError(err)
+		return
+	}
+
+	err = sw.SendDone(newAnswerResponse(answer, tracker, report))
+	if err != nil {
+		log.Printf("failed to send done event: %v", err)
+	}
+}
+
+// Stages of the pipeline sent in progress events.
+const (
+	stageClassifying = "classifying"
+	stageSearching   = "searching"
+	stageScoring     = "scoring"
+	stageScraping    = "scraping"
+	stageAnswering   = "answering"
+)
+
+type progressEvent struct {
+	Stage string `json:"stage"`
+}
+
+// prepare searches the web when the message needs it and returns the answer prompt, progress is called when a stage starts.
+func (h *WebSearchHandler) prepare(ctx context.Context, message string, progress func(stage string)) ([]ai.Message, error) {
 	var scrappedWebPage []websearch.ScrappedWebPage
 
-	required := h.ws.IsSearchRequired(r.Context(), req.Message)
-	if required {
-		queries, err := h.ws.GetDomainQueries(r.Context(), req.Message, allowedDomains)
+	progress(stageClassifying)
+	if h.ws.IsSearchRequired(ctx, message) {
+		progress(stageSearching)
+		queries, err := h.ws.GetDomainQueries(ctx, message, allowedDomains)
 		if err != nil {
-			log.Printf("failed to get domain queries: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			return nil, err
 		}
 
-		results, err := h.ws.SearchForSpecificPages(r.Context(), queries)
+		results, err := h.ws.SearchForSpecificPages(ctx, queries)
 		if err != nil {
-			log.Printf("error searching for specific domains: %s", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			return nil, fmt.Errorf("error searching for specific domains: %w", err)
 		}
 
-		scoredResults, err := h.ws.ScoreResults(r.Context(), results, req.Message)
+		progress(stageScoring)
+		scoredResults, err := h.ws.ScoreResults(ctx, results, message)
 		if err != nil {
-			log.Printf("error scoring: %s", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			return nil, fmt.Errorf("error scoring: %w", err)
 		}
 
-		scrappedWebPage, err = h.ws.ScrapWebpages(r.Context(), scoredResults, req.Message)
+		progress(stageScraping)
+		scrappedWebPage, err = h.ws.ScrapWebpages(ctx, scoredResults, message)
 		if err != nil {
-			log.Printf("error scrapping: %s", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			return nil, fmt.Errorf("error scrapping: %w", err)
 		}
 	}
 
-	answer, err := h.as.Chat(r.Context(), []ai.Message{
-		ai.SystemMessage(promptWithResults(scrappedWebPage)),
-		ai.UserMessage(req.Message),
-	})
-
+	progress(stageAnswering)
+	answerMessages, err := h.answerMessages(scrappedWebPage, message)
 	if err != nil {
-		log.Printf("failed to get domain queries: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("failed to fit answer prompt: %w", err)
 	}
+	return answerMessages, nil
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(struct {
-		Answer string `json:"answer"`
-	}{answer})
+// answerMessages truncates scraped contents evenly, so the prompt fits the context window with room for the answer.
+func (h *WebSearchHandler) answerMessages(pages []websearch.ScrappedWebPage, message string) ([]ai.Message, error) {
+	pages = slices.Clone(pages)
+	contents := make([]string, len(pages))
+	for i := range pages {
+		contents[i] = pages[i].Content
+		pages[i].Content = ""
+	}
 
-	if err != nil {
-		log.Printf("failed to encode response: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	skeleton := []ai.Message{
+		ai.SystemMessage(promptWithResults(pages)),
+		ai.UserMessage(message),
+	}
+	for i, content := range h.budget.FitDocuments(skeleton, contents) {
+		pages[i].Content = content
 	}
 
+	return h.budget.FitMessages([]ai.Message{
+		ai.SystemMessage(promptWithResults(pages)),
+		ai.UserMessage(message),
+	})
 }
 
 func promptWithResults(pages []websearch.ScrappedWebPage) string {
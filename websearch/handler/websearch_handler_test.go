@@ -0,0 +1,267 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/cassette"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
+	"github.com/TMateusz1/go-3rd-devs/internal/apierror"
+	"github.com/TMateusz1/go-3rd-devs/internal/testsupport"
+	"github.com/TMateusz1/go-3rd-devs/internal/websearch"
+	"github.com/TMateusz1/go-3rd-devs/websearch/handler"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var goDevPages = []testsupport.FirecrawlPage{
+	{
+		WebPage:  websearch.WebPage{Url: "https://go.dev/doc/effective_go", Title: "Effective Go", Description: "Goroutines, channels and other idioms of Go."},
+		Markdown: "A goroutine is a function executing concurrently with other goroutines in the same address space.",
+	},
+	{
+		WebPage:  websearch.WebPage{Url: "https://go.dev/tour/concurrency/1", Title: "A Tour of Go", Description: "Goroutines are lightweight threads managed by the Go runtime."},
+		Markdown: "A goroutine is a lightweight thread managed by the Go runtime. go f(x) starts a new goroutine.",
+	},
+}
+
+type webSearchAnswer struct {
+	Answer       string `json:"answer"`
+	Error        string `json:"error"`
+	Code         string `json:"code"`
+	Model        string `json:"model"`
+	FailedModels []struct {
+		Model string `json:"model"`
+		Code  string `json:"code"`
+	} `json:"failed_models"`
+}
+
+func newWebSearchHandler(t *testing.T, as ai.Service, firecrawl *testsupport.FirecrawlServer) *handler.WebSearchHandler {
+	t.Helper()
+	ws, err := websearch.NewService(as, firecrawl.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	budget, err := ai.NewBudget(ai.OpenRouterModelGPT4oMini, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return handler.NewWebSearchHandler(as, ws, budget)
+}
+
+func askWebSearch(t *testing.T, h *handler.WebSearchHandler, message string) (int, webSearchAnswer) {
+	t.Helper()
+	body, err := json.Marshal(map[string]string{"message": message})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.Handle(w, httptest.NewRequest(http.MethodPost, "/api/websearch", strings.NewReader(string(body))))
+
+	var answer webSearchAnswer
+	if err := json.NewDecoder(w.Body).Decode(&answer); err != nil {
+		t.Fatalf("decoding response %d: %v", w.Code, err)
+	}
+	return w.Code, answer
+}
+
+func TestWebSearchHandlerReplaysSearch(t *testing.T) {
+	tests := []struct {
+		name        string
+		cassette    string
+		message     string
+		want        string
+		wantScrapes int
+	}{
+		{
+			name:        "search required",
+			cassette:    "testdata/websearch_goroutines.json",
+			message:     "What is a goroutine according to go.dev?",
+			want:        "A lightweight thread managed by the Go runtime.",
+			wantScrapes: 2,
+		},
+		{
+			name:     "answer from knowledge",
+			cassette: "testdata/websearch_no_search.json",
+			message:  "How much is 2 + 2?",
+			want:     "4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			firecrawl := testsupport.NewFirecrawlServer(goDevPages...)
+			defer firecrawl.Close()
+			h := newWebSearchHandler(t, testsupport.CassetteService(t, tt.cassette, option.WithBaseModel(ai.OpenRouterModelGPT4oMini)), firecrawl)
+
+			status, answer := askWebSearch(t, h, tt.message)
+			if status != http.StatusOK {
+				t.Fatalf("status = %d, error %q", status, answer.Error)
+			}
+			if answer.Answer != tt.want {
+				t.Errorf("answer = %q, want %q", answer.Answer, tt.want)
+			}
+			if scrapes := len(firecrawl.Scrapes()); scrapes != tt.wantScrapes {
+				t.Errorf("scrapes = %d, want %d", scrapes, tt.wantScrapes)
+			}
+		})
+	}
+}
+
+func TestWebSearchHandlerFailsOnUnrecordedRequest(t *testing.T) {
+	firecrawl := testsupport.NewFirecrawlServer(goDevPages...)
+	defer firecrawl.Close()
+	replayer, err := cassette.NewReplayer("testdata/websearch_no_search.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newWebSearchHandler(t, replayer, firecrawl)
+
+	status, answer := askWebSearch(t, h, "A question nobody recorded")
+	if status != http.StatusInternalServerError || answer.Error == "" {
+		t.Errorf("status = %d, response = %+v, want internal error", status, answer)
+	}
+}
+
+func TestWebSearchHandlerReportsFailedModelsWithoutDetails(t *testing.T) {
+	firecrawl := testsupport.NewFirecrawlServer(goDevPages...)
+	defer firecrawl.Close()
+	failing := testsupport.NewFakeService()
+	failing.On(ai.User, ".").ReplyError(&ai.StatusError{StatusCode: http.StatusServiceUnavailable, Err: errors.New("upstream 10.0.0.7 overloaded")})
+	answering := testsupport.NewFakeService()
+	answering.Fallback = &ai.Response{Message: ai.AssistantMessage("4"), FinishReason: ai.FinishReasonStop}
+	fs, err := ai.NewFallbackService([]ai.FallbackTarget{{Service: failing, Model: "a"}, {Service: answering, Model: "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newWebSearchHandler(t, fs, firecrawl)
+
+	w := httptest.NewRecorder()
+	h.Handle(w, httptest.NewRequest(http.MethodPost, "/api/websearch", strings.NewReader(`{"message":"How much is 2 + 2?"}`)))
+
+	if strings.Contains(w.Body.String(), "10.0.0.7") {
+		t.Errorf("response has the provider error: %s", w.Body)
+	}
+	var answer webSearchAnswer
+	if err := json.NewDecoder(w.Body).Decode(&answer); err != nil {
+		t.Fatal(err)
+	}
+	if answer.Answer != "4" || answer.Model != "b" || len(answer.FailedModels) == 0 {
+		t.Fatalf("response = %+v, want answer of b after failed a", answer)
+	}
+	for _, failed := range answer.FailedModels {
+		if failed.Model != "a" || failed.Code != apierror.CodeProviderUnavailable {
+			t.Errorf("failed model = %+v, want a with code %s", failed, apierror.CodeProviderUnavailable)
+		}
+	}
+}
+
+func TestWebSearchHandlerRejectsMessageOverContextWindow(t *testing.T) {
+	firecrawl := testsupport.NewFirecrawlServer(goDevPages...)
+	defer firecrawl.Close()
+	fake := testsupport.NewFakeService()
+	fake.Fallback = &ai.Response{Message: ai.AssistantMessage("0"), FinishReason: ai.FinishReasonStop}
+	ws, err := websearch.NewService(fake, firecrawl.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// unknown model has the default window of 8192 tokens, 12 left for the prompt don't fit even the system prompt
+	budget, err := ai.NewBudget("", 8_180)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := handler.NewWebSearchHandler(fake, ws, budget)
+
+	status, answer := askWebSearch(t, h, "What is a goroutine?")
+	if status != http.StatusRequestEntityTooLarge || answer.Code != apierror.CodeContextLengthExceeded {
+		t.Errorf("status = %d, response = %+v, want context length exceeded", status, answer)
+	}
+}
+
+func TestWebSearchHandlerStreamsProgress(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     func(fake *testsupport.FakeService)
+		wantStages []string
+		wantEvents []string
+		wantAnswer string
+		wantCode   string
+	}{
+		{
+			name: "answer",
+			script: func(fake *testsupport.FakeService) {
+				fake.On(ai.System, "Necessity Detector").Reply("0")
+				fake.On(ai.System, "Answer the question").Reply("It is 4.")
+			},
+			wantStages: []string{"classifying", "answering"},
+			wantEvents: []string{"progress", "delta", "done"},
+			wantAnswer: "It is 4.",
+		},
+		{
+			name: "failed search",
+			script: func(fake *testsupport.FakeService) {
+				fake.On(ai.System, "Necessity Detector").Reply("1")
+				fake.On(ai.System, "keyword-based queries").ReplyError(ai.ErrProviderUnavailable)
+			},
+			wantStages: []string{"classifying", "searching"},
+			wantEvents: []string{"progress", "error"},
+			wantCode:   apierror.CodeProviderUnavailable,
+		},
+		{
+			name: "failed answer",
+			script: func(fake *testsupport.FakeService) {
+				fake.On(ai.System, "Necessity Detector").Reply("0")
+				fake.On(ai.System, "Answer the question").ReplyError(ai.ErrRateLimited)
+			},
+			wantStages: []string{"classifying", "answering"},
+			wantEvents: []string{"progress", "error"},
+			wantCode:   apierror.CodeRateLimited,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			firecrawl := testsupport.NewFirecrawlServer(goDevPages...)
+			defer firecrawl.Close()
+			fake := testsupport.NewFakeService()
+			tt.script(fake)
+			h := newWebSearchHandler(t, fake, firecrawl)
+
+			r := httptest.NewRequest(http.MethodPost, "/api/websearch", strings.NewReader(`{"message":"How much is 2 + 2?"}`))
+			r.Header.Set("Accept", "text/event-stream")
+			w := httptest.NewRecorder()
+			h.Handle(w, r)
+
+			if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+				t.Fatalf("status = %d, content type %q, want event stream", w.Code, w.Header().Get("Content-Type"))
+			}
+			events := testsupport.ReadEvents(t, w.Body)
+			if names := testsupport.EventNames(events); !reflect.DeepEqual(names, tt.wantEvents) {
+				t.Fatalf("events = %v, want %v", names, tt.wantEvents)
+			}
+
+			var stages []string
+			for _, event := range events {
+				if event.Name == "progress" {
+					var progress struct {
+						Stage string `json:"stage"`
+					}
+					event.Decode(t, &progress)
+					stages = append(stages, progress.Stage)
+				}
+			}
+			if !reflect.DeepEqual(stages, tt.wantStages) {
+				t.Errorf("stages = %v, want %v", stages, tt.wantStages)
+			}
+
+			var last webSearchAnswer
+			events[len(events)-1].Decode(t, &last)
+			if last.Answer != tt.wantAnswer || last.Code != tt.wantCode {
+				t.Errorf("last event = %+v, want answer %q, code %q", last, tt.wantAnswer, tt.wantCode)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/TMateusz1/go-3rd-devs/internal/ai"
+	"github.com/TMateusz1/go-3rd-devs/internal/ai/cassette"
 	"github.com/TMateusz1/go-3rd-devs/internal/ai/option"
 	"github.com/TMateusz1/go-3rd-devs/internal/middleware"
 	"github.com/TMateusz1/go-3rd-devs/internal/websearch"
@@ -11,8 +12,33 @@ import (
 	"net/http"
 )
 
+// answerOutputTokens is reserved in the context window for the answer.
+const answerOutputTokens = 2048
+
 func main() {
-	as, err := ai.NewOpenaiService(option.WithBaseModel(ai.OpenRouterModelGPT4oMini))
+	base, err := cassette.FromEnv(func() (ai.Service, error) {
+		return ai.NewServiceFromEnv(option.WithBaseModel(ai.OpenRouterModelGPT4oMini))
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	cache, err := ai.NewCacheBackendFromEnv()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	as, err := ai.NewServiceBuilder(base).
+		Wrap(func(s ai.Service) (ai.Service, error) {
+			return ai.NewRetryService(s)
+		}).
+		Wrap(func(s ai.Service) (ai.Service, error) {
+			return ai.NewCacheService(s, cache, option.WithCacheObserver(func(key string, hit bool) {
+				if hit {
+					log.Printf("AI cache hit: %s", key)
+				}
+			}))
+		}).
+		Use(ai.InterceptorsFromEnv()...).
+		Build()
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -21,9 +47,13 @@ func main() {
 	if err != nil {
 		log.Fatalln(err)
 	}
-	wh := handler.NewWebSearchHandler(as, ws)
+	budget, err := ai.NewBudgetForService(as, answerOutputTokens)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	wh := handler.NewWebSearchHandler(as, ws, budget)
 	mux := http.NewServeMux()
-	mux.HandleFunc("POST /api/websearch", middleware.LogMiddleware(wh.Handle))
+	mux.HandleFunc("POST /api/websearch", middleware.LogMiddleware(middleware.RequestIDMiddleware(wh.Handle)))
 
 	s := http.Server{
 		Addr:    ":8080",